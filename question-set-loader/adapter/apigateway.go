@@ -0,0 +1,97 @@
+package adapter
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Converts the API Gateway proxy event, payload version 1.0, to an http.Request.
+// The multi-value headers and query parameters are used when present as they hold
+// every value, the single value ones only hold the last
+func APIGatewayProxyRequestEventToHttpRequest(ctx context.Context, event *events.APIGatewayProxyRequest) (*http.Request, error) {
+	headers := make(http.Header)
+	for key, value := range event.Headers {
+		headers.Set(key, value)
+	}
+	for key, values := range event.MultiValueHeaders {
+		headers.Del(key)
+		for _, value := range values {
+			headers.Add(key, value)
+		}
+	}
+
+	query := make(url.Values)
+	for key, value := range event.QueryStringParameters {
+		query.Set(key, value)
+	}
+	for key, values := range event.MultiValueQueryStringParameters {
+		query[key] = values
+	}
+
+	return newHttpRequest(ctx, event.HTTPMethod, event.Path, query.Encode(), headers, event.Body, event.IsBase64Encoded, event.RequestContext.Identity.SourceIP)
+}
+
+// Converts the API Gateway HTTP API event, payload version 2.0, to an http.Request.
+// Repeated headers arrive comma separated and cookies arrive separately
+func APIGatewayV2HTTPRequestEventToHttpRequest(ctx context.Context, event *events.APIGatewayV2HTTPRequest) (*http.Request, error) {
+	headers := v2Headers(event.Headers, event.Cookies)
+	return newHttpRequest(ctx, event.RequestContext.HTTP.Method, event.RawPath, event.RawQueryString, headers, event.Body, event.IsBase64Encoded, event.RequestContext.HTTP.SourceIP)
+}
+
+// Builds the request headers from a payload version 2.0 event
+func v2Headers(eventHeaders map[string]string, cookies []string) http.Header {
+	headers := make(http.Header)
+	for key, value := range eventHeaders {
+		headers.Set(key, value)
+	}
+	if len(cookies) > 0 {
+		headers.Set("Cookie", strings.Join(cookies, "; "))
+	}
+	return headers
+}
+
+// Creates the API Gateway proxy response, payload version 1.0, from the writer. Only
+// the multi-value headers are set as API Gateway would merge in the single value ones
+func (a *ResponseWriter) AsAPIGatewayProxyResponse() (events.APIGatewayProxyResponse, error) {
+	a.finish()
+	body, isBase64Encoded := a.body()
+	return events.APIGatewayProxyResponse{
+		StatusCode: *a.statusCode,
+		MultiValueHeaders: a.headers,
+		Body: body,
+		IsBase64Encoded: isBase64Encoded,
+	}, nil
+}
+
+// Creates the API Gateway HTTP API response, payload version 2.0, from the writer
+func (a *ResponseWriter) AsAPIGatewayV2HTTPResponse() (events.APIGatewayV2HTTPResponse, error) {
+	a.finish()
+	headers, cookies := a.v2Headers()
+	body, isBase64Encoded := a.body()
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode: *a.statusCode,
+		Headers: headers,
+		Cookies: cookies,
+		Body: body,
+		IsBase64Encoded: isBase64Encoded,
+	}, nil
+}
+
+// Splits the written headers into the comma separated headers and the cookies of a
+// payload version 2.0 response
+func (a *ResponseWriter) v2Headers() (map[string]string, []string) {
+	headers := make(map[string]string, len(a.headers))
+	var cookies []string
+	for key, values := range a.headers {
+		if key == "Set-Cookie" {
+			cookies = append(cookies, values...)
+			continue
+		}
+		headers[key] = strings.Join(values, ", ")
+	}
+	return headers, cookies
+}
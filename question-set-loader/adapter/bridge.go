@@ -0,0 +1,32 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Runs handler on the HTTP request in the Lambda event, so that the same handlers
+// serve the container and the Lambda. The response is in the shape expected by
+// whatever sent the event. Events that don't carry an HTTP request are an error
+// as there's no way to respond to them
+func ServeEvent(ctx context.Context, handler http.Handler, event []byte) (interface{}, error) {
+	eventType, err := DetectEventType(event)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := EventToHttpRequest(ctx, eventType, event)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't adapt %s event to http.Request. Error: %w", eventType, err)
+	}
+
+	writer := NewResponseWriter()
+	handler.ServeHTTP(&writer, request)
+
+	response, err := writer.AsEventResponse(eventType)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't adapt http.ResponseWriter to %s response. Error: %w", eventType, err)
+	}
+	return response, nil
+}
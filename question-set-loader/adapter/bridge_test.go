@@ -0,0 +1,106 @@
+package adapter
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/go-cmp/cmp"
+)
+
+const albEvent = `{
+	"httpMethod": "POST",
+	"path": "/api/echo",
+	"queryStringParameters": {"name": "a%20b", "tag": "y"},
+	"multiValueQueryStringParameters": {"name": ["a%20b"], "tag": ["x", "y"]},
+	"multiValueHeaders": {
+		"host": ["example.elb.amazonaws.com"],
+		"x-forwarded-for": ["198.51.100.7, 203.0.113.1"],
+		"content-type": ["text/plain"]
+	},
+	"requestContext": {"elb": {"targetGroupArn": "arn:aws:elasticloadbalancing:us-east-1:123456780300:targetgroup/example/39031bff5fe010f0"}},
+	"body": "aGVsbG8=",
+	"isBase64Encoded": true
+}`
+
+type contextKey struct{}
+
+// Routes like the container would
+func newTestMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Add("Vary", "Origin")
+		w.Header().Add("Vary", "Accept")
+		fmt.Fprintf(w, "%s %s name=%s tag=%v host=%s remote=%s length=%d body=%s context=%v",
+			r.Method, r.URL.Path, r.URL.Query().Get("name"), r.URL.Query()["tag"], r.Host, r.RemoteAddr,
+			r.ContentLength, body, r.Context().Value(contextKey{}))
+	})
+	mux.HandleFunc("/api/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte{0x89, 'P', 'N', 'G', 0x00, 0xff})
+	})
+	return mux
+}
+
+func TestServeEvent_alb(t *testing.T) {
+	ctx := context.WithValue(context.Background(), contextKey{}, "from lambda")
+
+	response, err := ServeEvent(ctx, newTestMux(), []byte(albEvent))
+	if err != nil {
+		t.Fatalf("Failed to serve event: %v", err)
+	}
+
+	want := events.ALBTargetGroupResponse{
+		StatusCode: http.StatusOK,
+		StatusDescription: "200 OK",
+		Headers: map[string]string{"Content-Type": "text/plain; charset=utf-8", "Vary": "Origin, Accept"},
+		MultiValueHeaders: map[string][]string{"Content-Type": {"text/plain; charset=utf-8"}, "Vary": {"Origin", "Accept"}},
+		Body: "POST /api/echo name=a b tag=[x y] host=example.elb.amazonaws.com remote=203.0.113.1:0 length=5 body=hello context=from lambda",
+	}
+	if diff := cmp.Diff(want, response); diff != "" {
+		t.Errorf("Wrong response: %s", diff)
+	}
+}
+
+func TestServeEvent_binary_response(t *testing.T) {
+	event := `{"version": "2.0", "rawPath": "/api/image", "requestContext": {"http": {"method": "GET"}}}`
+
+	response, err := ServeEvent(context.Background(), newTestMux(), []byte(event))
+	if err != nil {
+		t.Fatalf("Failed to serve event: %v", err)
+	}
+
+	want := events.APIGatewayV2HTTPResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{"Content-Type": "image/png"},
+		Body: base64.StdEncoding.EncodeToString([]byte{0x89, 'P', 'N', 'G', 0x00, 0xff}),
+		IsBase64Encoded: true,
+	}
+	if diff := cmp.Diff(want, response); diff != "" {
+		t.Errorf("Wrong response: %s", diff)
+	}
+}
+
+func TestServeEvent_not_found(t *testing.T) {
+	event := `{"httpMethod": "GET", "path": "/api/missing", "requestContext": {"apiId": "abc123"}}`
+
+	response, err := ServeEvent(context.Background(), newTestMux(), []byte(event))
+	if err != nil {
+		t.Fatalf("Failed to serve event: %v", err)
+	}
+	if diff := cmp.Diff(http.StatusNotFound, response.(events.APIGatewayProxyResponse).StatusCode); diff != "" {
+		t.Errorf("Wrong status code: %s", diff)
+	}
+}
+
+func TestServeEvent_unsupported_event(t *testing.T) {
+	if _, err := ServeEvent(context.Background(), newTestMux(), []byte(`{"Records": []}`)); err == nil {
+		t.Errorf("Failed to detect unsupported event")
+	}
+}
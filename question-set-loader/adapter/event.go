@@ -0,0 +1,101 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// The kinds of Lambda event that carry an HTTP request
+type EventType string
+
+const (
+	EventALB			EventType = "alb"				// ALB target group
+	EventAPIGatewayV1	EventType = "apigateway-v1"		// API Gateway REST API or HTTP API with payload version 1.0
+	EventAPIGatewayV2	EventType = "apigateway-v2"		// API Gateway HTTP API with payload version 2.0
+	EventFunctionURL	EventType = "function-url"		// Lambda Function URL
+)
+
+// The fields that tell the events apart
+type eventShape struct {
+	Version			string	`json:"version"`
+	HTTPMethod		string	`json:"httpMethod"`
+	RequestContext	struct {
+		Elb			json.RawMessage	`json:"elb"`
+		DomainName	string			`json:"domainName"`
+		HTTP		json.RawMessage	`json:"http"`
+	}	`json:"requestContext"`
+}
+
+// Works out which kind of event the raw Lambda event is from its shape
+func DetectEventType(event []byte) (EventType, error) {
+	var shape eventShape
+	if err := json.Unmarshal(event, &shape); err != nil {
+		return "", fmt.Errorf("event isn't a JSON object. Error: %w", err)
+	}
+
+	switch {
+	case len(shape.RequestContext.Elb) > 0:
+		return EventALB, nil
+	case shape.Version == "2.0" || len(shape.RequestContext.HTTP) > 0:
+		// Function URLs have the same shape as HTTP API events but a different domain
+		if strings.Contains(shape.RequestContext.DomainName, ".lambda-url.") {
+			return EventFunctionURL, nil
+		}
+		return EventAPIGatewayV2, nil
+	case shape.HTTPMethod != "":
+		return EventAPIGatewayV1, nil
+	}
+	return "", errors.New("event isn't from an ALB, API Gateway or a Function URL")
+}
+
+// Converts the raw Lambda event of eventType to an http.Request
+func EventToHttpRequest(ctx context.Context, eventType EventType, event []byte) (*http.Request, error) {
+	switch eventType {
+	case EventALB:
+		var request events.ALBTargetGroupRequest
+		if err := json.Unmarshal(event, &request); err != nil {
+			return nil, err
+		}
+		return ALBTargetGroupRequestEventToHttpRequest(ctx, &request)
+	case EventAPIGatewayV1:
+		var request events.APIGatewayProxyRequest
+		if err := json.Unmarshal(event, &request); err != nil {
+			return nil, err
+		}
+		return APIGatewayProxyRequestEventToHttpRequest(ctx, &request)
+	case EventAPIGatewayV2:
+		var request events.APIGatewayV2HTTPRequest
+		if err := json.Unmarshal(event, &request); err != nil {
+			return nil, err
+		}
+		return APIGatewayV2HTTPRequestEventToHttpRequest(ctx, &request)
+	case EventFunctionURL:
+		var request events.LambdaFunctionURLRequest
+		if err := json.Unmarshal(event, &request); err != nil {
+			return nil, err
+		}
+		return LambdaFunctionURLRequestEventToHttpRequest(ctx, &request)
+	}
+	return nil, fmt.Errorf("unsupported event type '%s'", eventType)
+}
+
+// Creates the response to the event of eventType from the writer
+func (a *ResponseWriter) AsEventResponse(eventType EventType) (interface{}, error) {
+	switch eventType {
+	case EventALB:
+		return a.AsALBTargetGroupResponse()
+	case EventAPIGatewayV1:
+		return a.AsAPIGatewayProxyResponse()
+	case EventAPIGatewayV2:
+		return a.AsAPIGatewayV2HTTPResponse()
+	case EventFunctionURL:
+		return a.AsLambdaFunctionURLResponse()
+	}
+	return nil, fmt.Errorf("unsupported event type '%s'", eventType)
+}
@@ -0,0 +1,227 @@
+package adapter
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/go-cmp/cmp"
+)
+
+const apiGatewayV1Event = `{
+	"resource": "/api/upload/quiz",
+	"path": "/api/upload/quiz",
+	"httpMethod": "POST",
+	"headers": {"Content-Type": "text/plain", "Host": "abc123.execute-api.us-east-1.amazonaws.com"},
+	"multiValueHeaders": {"Content-Type": ["text/plain"], "Host": ["abc123.execute-api.us-east-1.amazonaws.com"], "X-Tag": ["a", "b"]},
+	"queryStringParameters": {"tag": "b"},
+	"multiValueQueryStringParameters": {"tag": ["a", "b"]},
+	"requestContext": {"apiId": "abc123", "identity": {"sourceIp": "203.0.113.1"}},
+	"body": "aGVsbG8=",
+	"isBase64Encoded": true
+}`
+
+const apiGatewayV2Event = `{
+	"version": "2.0",
+	"routeKey": "$default",
+	"rawPath": "/api/upload/quiz",
+	"rawQueryString": "tag=a&tag=b",
+	"cookies": ["a=1", "b=2"],
+	"headers": {"content-type": "text/plain", "host": "abc123.execute-api.us-east-1.amazonaws.com", "x-tag": "a,b"},
+	"requestContext": {
+		"apiId": "abc123",
+		"domainName": "abc123.execute-api.us-east-1.amazonaws.com",
+		"http": {"method": "POST", "path": "/api/upload/quiz", "sourceIp": "203.0.113.1"}
+	},
+	"body": "hello",
+	"isBase64Encoded": false
+}`
+
+const functionURLEvent = `{
+	"version": "2.0",
+	"rawPath": "/api/upload/quiz",
+	"rawQueryString": "tag=a&tag=b",
+	"cookies": ["a=1", "b=2"],
+	"headers": {"content-type": "text/plain", "host": "abc123.lambda-url.us-east-1.on.aws", "x-tag": "a,b"},
+	"requestContext": {
+		"apiId": "abc123",
+		"domainName": "abc123.lambda-url.us-east-1.on.aws",
+		"http": {"method": "POST", "path": "/api/upload/quiz", "sourceIp": "203.0.113.1"}
+	},
+	"body": "aGVsbG8=",
+	"isBase64Encoded": true
+}`
+
+func TestDetectEventType(t *testing.T) {
+	albEvent, err := os.ReadFile("../cmd/lambda/sample-event.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		event string
+		want EventType
+	}{
+		"alb": {string(albEvent), EventALB},
+		"api gateway v1": {apiGatewayV1Event, EventAPIGatewayV1},
+		"api gateway v2": {apiGatewayV2Event, EventAPIGatewayV2},
+		"function url": {functionURLEvent, EventFunctionURL},
+	}
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			got, err := DetectEventType([]byte(test.event))
+			if err != nil {
+				t.Fatalf("Failed to detect event type: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Wrong event type: %s", diff)
+			}
+		})
+	}
+
+	for _, event := range []string{`{"source": "aws.events"}`, `[]`} {
+		if _, err := DetectEventType([]byte(event)); err == nil {
+			t.Errorf("Failed to detect unsupported event '%s'", event)
+		}
+	}
+}
+
+func TestEventToHttpRequest(t *testing.T) {
+	tests := map[string]struct {
+		eventType EventType
+		event string
+		wantHost string
+		wantHeaders http.Header
+	}{
+		"api gateway v1": {EventAPIGatewayV1, apiGatewayV1Event, "abc123.execute-api.us-east-1.amazonaws.com", http.Header{"X-Tag": {"a", "b"}}},
+		"api gateway v2": {EventAPIGatewayV2, apiGatewayV2Event, "abc123.execute-api.us-east-1.amazonaws.com", http.Header{"X-Tag": {"a,b"}, "Cookie": {"a=1; b=2"}}},
+		"function url": {EventFunctionURL, functionURLEvent, "abc123.lambda-url.us-east-1.on.aws", http.Header{"X-Tag": {"a,b"}, "Cookie": {"a=1; b=2"}}},
+	}
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			request, err := EventToHttpRequest(context.Background(), test.eventType, []byte(test.event))
+			if err != nil {
+				t.Fatalf("Failed to adapt event: %v", err)
+			}
+
+			if diff := cmp.Diff(http.MethodPost, request.Method); diff != "" {
+				t.Errorf("Wrong method: %s", diff)
+			}
+			if diff := cmp.Diff("/api/upload/quiz", request.URL.Path); diff != "" {
+				t.Errorf("Wrong path: %s", diff)
+			}
+			if diff := cmp.Diff([]string{"a", "b"}, request.URL.Query()["tag"]); diff != "" {
+				t.Errorf("Wrong query: %s", diff)
+			}
+			if diff := cmp.Diff(test.wantHost, request.Host); diff != "" {
+				t.Errorf("Wrong host: %s", diff)
+			}
+			if diff := cmp.Diff("203.0.113.1:0", request.RemoteAddr); diff != "" {
+				t.Errorf("Wrong remote address: %s", diff)
+			}
+			if diff := cmp.Diff("text/plain", request.Header.Get("Content-Type")); diff != "" {
+				t.Errorf("Wrong content type: %s", diff)
+			}
+			for key, values := range test.wantHeaders {
+				if diff := cmp.Diff(values, request.Header.Values(key)); diff != "" {
+					t.Errorf("Wrong '%s' header: %s", key, diff)
+				}
+			}
+			body, err := io.ReadAll(request.Body)
+			if err != nil {
+				t.Fatalf("Failed to read body: %v", err)
+			}
+			if diff := cmp.Diff("hello", string(body)); diff != "" {
+				t.Errorf("Wrong body: %s", diff)
+			}
+		})
+	}
+}
+
+// Tests percent-encoded paths are decoded, keeping the encoding in RawPath
+func TestEventToHttpRequest_escaped_path(t *testing.T) {
+	tests := map[string]struct {
+		rawPath		string
+		wantPath	string
+		wantErr		bool
+	}{
+		"plain": {"/api/upload/library/general", "/api/upload/library/general", false},
+		"space": {"/api/upload/library/general%20knowledge", "/api/upload/library/general knowledge", false},
+		"encoded slash": {"/api/upload/library/..%2Fsecrets", "/api/upload/library/../secrets", false},
+		"invalid encoding": {"/api/upload/library/%zz", "", true},
+	}
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			event := `{"version": "2.0", "rawPath": "` + test.rawPath + `", "requestContext": {"http": {"method": "GET"}}}`
+			request, err := EventToHttpRequest(context.Background(), EventAPIGatewayV2, []byte(event))
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("Failed to detect error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to adapt event: %v", err)
+			}
+			if diff := cmp.Diff(test.wantPath, request.URL.Path); diff != "" {
+				t.Errorf("Wrong path: %s", diff)
+			}
+			if diff := cmp.Diff(test.rawPath, request.URL.EscapedPath()); diff != "" {
+				t.Errorf("Wrong escaped path: %s", diff)
+			}
+			if diff := cmp.Diff(test.rawPath, request.RequestURI); diff != "" {
+				t.Errorf("Wrong request URI: %s", diff)
+			}
+		})
+	}
+}
+
+func TestAsEventResponse(t *testing.T) {
+	writer := NewResponseWriter()
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Add("Vary", "Origin")
+	writer.Header().Add("Vary", "Access-Control-Request-Method")
+	writer.Header().Add("Set-Cookie", "a=1")
+	writer.Header().Add("Set-Cookie", "b=2")
+	writer.WriteHeader(http.StatusCreated)
+	writer.Write([]byte("{}"))
+
+	v1, err := writer.AsEventResponse(EventAPIGatewayV1)
+	if err != nil {
+		t.Fatalf("Failed to adapt response: %v", err)
+	}
+	wantV1 := events.APIGatewayProxyResponse{
+		StatusCode: http.StatusCreated,
+		MultiValueHeaders: map[string][]string{
+			"Content-Type": {"application/json"},
+			"Vary": {"Origin", "Access-Control-Request-Method"},
+			"Set-Cookie": {"a=1", "b=2"},
+		},
+		Body: "{}",
+	}
+	if diff := cmp.Diff(wantV1, v1); diff != "" {
+		t.Errorf("Wrong API Gateway v1 response: %s", diff)
+	}
+
+	wantHeaders := map[string]string{"Content-Type": "application/json", "Vary": "Origin, Access-Control-Request-Method"}
+	v2, err := writer.AsEventResponse(EventAPIGatewayV2)
+	if err != nil {
+		t.Fatalf("Failed to adapt response: %v", err)
+	}
+	wantV2 := events.APIGatewayV2HTTPResponse{StatusCode: http.StatusCreated, Headers: wantHeaders, Cookies: []string{"a=1", "b=2"}, Body: "{}"}
+	if diff := cmp.Diff(wantV2, v2); diff != "" {
+		t.Errorf("Wrong API Gateway v2 response: %s", diff)
+	}
+
+	functionURL, err := writer.AsEventResponse(EventFunctionURL)
+	if err != nil {
+		t.Fatalf("Failed to adapt response: %v", err)
+	}
+	wantFunctionURL := events.LambdaFunctionURLResponse{StatusCode: http.StatusCreated, Headers: wantHeaders, Cookies: []string{"a=1", "b=2"}, Body: "{}"}
+	if diff := cmp.Diff(wantFunctionURL, functionURL); diff != "" {
+		t.Errorf("Wrong Function URL response: %s", diff)
+	}
+}
@@ -0,0 +1,29 @@
+package adapter
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Converts the Lambda Function URL event to an http.Request. Function URL events
+// have the same shape as API Gateway HTTP API payload version 2.0 events
+func LambdaFunctionURLRequestEventToHttpRequest(ctx context.Context, event *events.LambdaFunctionURLRequest) (*http.Request, error) {
+	headers := v2Headers(event.Headers, event.Cookies)
+	return newHttpRequest(ctx, event.RequestContext.HTTP.Method, event.RawPath, event.RawQueryString, headers, event.Body, event.IsBase64Encoded, event.RequestContext.HTTP.SourceIP)
+}
+
+// Creates the Lambda Function URL response from the writer
+func (a *ResponseWriter) AsLambdaFunctionURLResponse() (events.LambdaFunctionURLResponse, error) {
+	a.finish()
+	headers, cookies := a.v2Headers()
+	body, isBase64Encoded := a.body()
+	return events.LambdaFunctionURLResponse{
+		StatusCode: *a.statusCode,
+		Headers: headers,
+		Cookies: cookies,
+		Body: body,
+		IsBase64Encoded: isBase64Encoded,
+	}, nil
+}
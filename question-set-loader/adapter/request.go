@@ -1,41 +1,103 @@
 package adapter
 
 import (
-	"bytes"
+	"context"
 	"encoding/base64"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"sort"
+	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 )
 
-// Converts the AWS Lambda ALBTargetGroupRequest event to an http.Request
-func ALBTargetGroupRequestEventToHttpRequest(event *events.ALBTargetGroupRequest) (*http.Request, error) {
-
-	var body []byte
-	if event.IsBase64Encoded {
-		var err error
-		body, err = base64.StdEncoding.DecodeString(event.Body)
-		if err != nil {
-			return &http.Request{}, fmt.Errorf("failed to decode base64 encoded body: Error: %w", err)
-		}
-	} else {
-		body = []byte(event.Body)
-	}
+// Converts the AWS Lambda ALBTargetGroupRequest event to an http.Request. Base64
+// encoded bodies are decoded as they are read so an invalid body is reported by
+// the handler when it reads it. The multi-value headers and query parameters are
+// used when the target group has them enabled
+func ALBTargetGroupRequestEventToHttpRequest(ctx context.Context, event *events.ALBTargetGroupRequest) (*http.Request, error) {
 
 	httpHeaders := make(http.Header)
+	for key, value := range event.Headers {
+		httpHeaders.Set(key, value)
+	}
 	for key, values := range event.MultiValueHeaders {
+		httpHeaders.Del(key)
 		for _, value := range values {
 			httpHeaders.Add(key, value)
 		}
 	}
 
-	return &http.Request{
-		Method: event.HTTPMethod,
-		URL: &url.URL{Path: event.Path},
-		Header: httpHeaders,
-		Body: io.NopCloser(bytes.NewReader(body)),
-	}, nil
-}
\ No newline at end of file
+	// The ALB passes the query parameters on as they were sent, still URL encoded
+	query := make(map[string][]string)
+	for key, value := range event.QueryStringParameters {
+		query[key] = []string{value}
+	}
+	for key, values := range event.MultiValueQueryStringParameters {
+		query[key] = values
+	}
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	rawQuery := []string{}
+	for _, key := range keys {
+		for _, value := range query[key] {
+			rawQuery = append(rawQuery, key + "=" + value)
+		}
+	}
+
+	// The ALB appends the address of the client it received the request from
+	sourceIp := ""
+	if forwardedFor := strings.Split(httpHeaders.Get("X-Forwarded-For"), ","); len(forwardedFor) > 0 {
+		sourceIp = strings.TrimSpace(forwardedFor[len(forwardedFor)-1])
+	}
+
+	return newHttpRequest(ctx, event.HTTPMethod, event.Path, strings.Join(rawQuery, "&"), httpHeaders, event.Body, event.IsBase64Encoded, sourceIp)
+}
+
+// Builds the http.Request from the parts common to every event. The path is as sent,
+// still percent-encoded, and is decoded like net/http does. Base64 encoded bodies are
+// decoded as they're read
+func newHttpRequest(ctx context.Context, method string, rawPath string, rawQuery string, headers http.Header, eventBody string, isBase64Encoded bool, sourceIp string) (*http.Request, error) {
+	var body io.Reader = strings.NewReader(eventBody)
+	contentLength := int64(len(eventBody))
+	if isBase64Encoded {
+		body = base64.NewDecoder(base64.StdEncoding, body)
+		contentLength = decodedLength(eventBody)
+	}
+
+	path, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path '%s': %w", rawPath, err)
+	}
+	// RawPath keeps encoded characters such as '%2F' that decoding loses
+	requestUrl := &url.URL{Path: path, RawPath: rawPath, RawQuery: rawQuery}
+	request := &http.Request{
+		Method: method,
+		URL: requestUrl,
+		Proto: "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: headers,
+		Body: io.NopCloser(body),
+		ContentLength: contentLength,
+		Host: headers.Get("Host"),
+		RequestURI: requestUrl.RequestURI(),
+	}
+	if sourceIp != "" {
+		// The port isn't in the event
+		request.RemoteAddr = net.JoinHostPort(sourceIp, "0")
+	}
+	return request.WithContext(ctx), nil
+}
+
+// Length of the data encoded in the padded base64 string
+func decodedLength(encoded string) int64 {
+	length := int64(len(encoded) / 4 * 3)
+	return length - int64(len(encoded) - len(strings.TrimRight(encoded, "=")))
+}
@@ -2,26 +2,33 @@ package adapter
 
 import (
 	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
 	"net/http"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/aws/aws-lambda-go/events"
 )
 
-type httpResponseWriterToALBTargetGroupResponse struct {
+// http.ResponseWriter that holds the response so that it can be returned as the
+// response to a Lambda event
+type ResponseWriter struct {
 	headers http.Header
 	bodyWriter bytes.Buffer
 	statusCode *int
 }
 
-func NewHttpResponseWriterToALBTargetGroupResponse() httpResponseWriterToALBTargetGroupResponse {
-	return httpResponseWriterToALBTargetGroupResponse{
+func NewResponseWriter() ResponseWriter {
+	return ResponseWriter{
 		headers: make(http.Header),
 		bodyWriter: bytes.Buffer{},
 	}
 }
 
 // Get a reference to the header map to send in the response
-func (a *httpResponseWriterToALBTargetGroupResponse) Header() http.Header {
+func (a *ResponseWriter) Header() http.Header {
 	return a.headers
 }
 
@@ -32,12 +39,12 @@ func (a *httpResponseWriterToALBTargetGroupResponse) Header() http.Header {
 // does not contain a Content-Type line, Write adds a Content-Type set
 // to the result of passing the initial 512 bytes of written data to
 // DetectContentType.
-func (a *httpResponseWriterToALBTargetGroupResponse) Write(bytes []byte) (int, error) {
+func (a *ResponseWriter) Write(bytes []byte) (int, error) {
 	if a.statusCode == nil {
 		a.WriteHeader(http.StatusOK)
 	}
 
-	a.bodyWriter.Write(bytes)
+	n, err := a.bodyWriter.Write(bytes)
 
 	contentType := "Content-Type"
 	if a.headers.Get(contentType) == "" {
@@ -46,7 +53,7 @@ func (a *httpResponseWriterToALBTargetGroupResponse) Write(bytes []byte) (int, e
 		a.headers[contentType] = []string{contentTypeVal}
 	}
 
-	return a.bodyWriter.Len(), nil
+	return n, err
 }
 
 // WriteHeader sends an HTTP response header with the provided
@@ -55,17 +62,77 @@ func (a *httpResponseWriterToALBTargetGroupResponse) Write(bytes []byte) (int, e
 // If WriteHeader is not called explicitly, the first call to Write
 // will trigger an implicit WriteHeader(http.StatusOK).
 // Thus explicit calls to WriteHeader are mainly used to
-// send error codes.
-func (a *httpResponseWriterToALBTargetGroupResponse) WriteHeader(statusCode int) {
+// send error codes. Later calls are ignored as they are by net/http
+func (a *ResponseWriter) WriteHeader(statusCode int) {
+	if a.statusCode != nil {
+		return
+	}
 	a.statusCode = &statusCode
 }
 
 // Creates the ALBTargetGroupResponse from the writer. Returns non-nill error if failed to create
-func (a *httpResponseWriterToALBTargetGroupResponse) AsALBTargetGroupResponse() (events.ALBTargetGroupResponse, error) {
+// The headers and body are passed through as written e.g. problem+json error documents.
+// Both the single and multi-value headers are set since the ALB only reads the ones
+// that the target group is configured for
+func (a *ResponseWriter) AsALBTargetGroupResponse() (events.ALBTargetGroupResponse, error) {
+	a.finish()
+	body, isBase64Encoded := a.body()
 	return events.ALBTargetGroupResponse{
 		StatusCode: *a.statusCode,
+		StatusDescription: fmt.Sprintf("%d %s", *a.statusCode, http.StatusText(*a.statusCode)),
+		Headers: a.singleValueHeaders(),
 		MultiValueHeaders: a.headers,
-		Body: a.bodyWriter.String(),
-		IsBase64Encoded: false,
+		Body: body,
+		IsBase64Encoded: isBase64Encoded,
 	}, nil
-}
\ No newline at end of file
+}
+
+// Sets the status to 200 OK if the handler never wrote a response, which then has an
+// empty body, as net/http does
+func (a *ResponseWriter) finish() {
+	if a.statusCode == nil {
+		a.WriteHeader(http.StatusOK)
+	}
+}
+
+// Returns the body for the event response. Text is returned as is and anything else
+// is base64 encoded, as event responses can only hold strings
+func (a *ResponseWriter) body() (body string, isBase64Encoded bool) {
+	if isText(a.headers.Get("Content-Type")) && utf8.Valid(a.bodyWriter.Bytes()) {
+		return a.bodyWriter.String(), false
+	}
+	return base64.StdEncoding.EncodeToString(a.bodyWriter.Bytes()), true
+}
+
+// Whether responses with the media type are text
+func isText(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"),
+		mediaType == "application/json",
+		mediaType == "application/xml",
+		mediaType == "application/javascript",
+		mediaType == "application/x-www-form-urlencoded":
+		return true
+	}
+	return false
+}
+
+// Joins repeated headers with commas for responses that only have one value per
+// header. Set-Cookie can't be joined so only the last cookie is kept
+func (a *ResponseWriter) singleValueHeaders() map[string]string {
+	headers := make(map[string]string, len(a.headers))
+	for key, values := range a.headers {
+		if key == "Set-Cookie" {
+			headers[key] = values[len(values)-1]
+			continue
+		}
+		headers[key] = strings.Join(values, ", ")
+	}
+	return headers
+}
@@ -0,0 +1,57 @@
+package adapter
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Tests that a problem+json error response is passed through unchanged
+func TestAsALBTargetGroupResponse_problem(t *testing.T) {
+	body := `{"type":"urn:mc-speedrun:problem:invalid-token","title":"Invalid token","status":401}` + "\n"
+
+	writer := NewResponseWriter()
+	writer.Header().Set("Content-Type", "application/problem+json")
+	writer.WriteHeader(http.StatusUnauthorized)
+	n, err := writer.Write([]byte(body[:10]))
+	if err != nil || n != 10 {
+		t.Fatalf("Wrote %d bytes with error %v, expected 10", n, err)
+	}
+	writer.Write([]byte(body[10:]))
+
+	got, err := writer.AsALBTargetGroupResponse()
+	if err != nil {
+		t.Fatalf("Failed to adapt response: %v", err)
+	}
+	if diff := cmp.Diff(http.StatusUnauthorized, got.StatusCode); diff != "" {
+		t.Errorf("Wrong status code: %s", diff)
+	}
+	if diff := cmp.Diff("401 Unauthorized", got.StatusDescription); diff != "" {
+		t.Errorf("Wrong status description: %s", diff)
+	}
+	if diff := cmp.Diff([]string{"application/problem+json"}, got.MultiValueHeaders["Content-Type"]); diff != "" {
+		t.Errorf("Wrong content type: %s", diff)
+	}
+	if diff := cmp.Diff(body, got.Body); diff != "" {
+		t.Errorf("Wrong body: %s", diff)
+	}
+}
+
+// Tests a handler that never wrote a response gets an empty 200 like it would from net/http
+func TestAsALBTargetGroupResponse_nothing_written(t *testing.T) {
+	writer := NewResponseWriter()
+	got, err := writer.AsALBTargetGroupResponse()
+	if err != nil {
+		t.Fatalf("Failed to adapt response: %v", err)
+	}
+	if diff := cmp.Diff(http.StatusOK, got.StatusCode); diff != "" {
+		t.Errorf("Wrong status code: %s", diff)
+	}
+	if diff := cmp.Diff("200 OK", got.StatusDescription); diff != "" {
+		t.Errorf("Wrong status description: %s", diff)
+	}
+	if got.Body != "" {
+		t.Errorf("Expected an empty body but got '%s'", got.Body)
+	}
+}
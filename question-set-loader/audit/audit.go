@@ -0,0 +1,63 @@
+package audit
+
+import (
+	"time"
+)
+
+// Changes to a question set that are audited
+const (
+	ActionUpload	= "upload"
+	ActionReplace	= "replace"
+	ActionDelete	= "delete"
+)
+
+// Outcome of a request that succeeded. Failed requests have the name of their
+// problem type instead e.g. "precondition-failed"
+const OutcomeSuccess = "success"
+
+// Most entries a query returns, and how many it returns when it doesn't say
+const (
+	MaxQueryLimit		= 1000
+	DefaultQueryLimit	= 100
+)
+
+// Record of a request to change a question set
+type Entry struct {
+	Time		time.Time	`json:"time"`
+	Action		string		`json:"action"`
+	QuizId		string		`json:"quizId"`
+	Subject		string		`json:"subject,omitempty"`		// 'sub' claim of the host's token
+	TokenId		string		`json:"tokenId,omitempty"`		// 'jti' claim of the host's token
+	ClientIP	string		`json:"clientIp"`
+	ContentHash	string		`json:"contentHash,omitempty"`	// Of the question set uploaded, or deleted
+	Size		int64		`json:"size"`					// Bytes in the request body
+	Status		int			`json:"status"`
+	Outcome		string		`json:"outcome"`
+}
+
+// Where entries are written to. Entries are only ever added
+type Sink interface {
+	Write(entry Entry) error
+}
+
+// Implemented by sinks that entries can be read back from
+type Querier interface {
+	// Returns the entries matching filter, newest first
+	Query(filter Filter) ([]Entry, error)
+}
+
+// Entries to return from a query. Empty fields match every entry
+type Filter struct {
+	QuizId	string
+	Action	string
+	Since	time.Time	// Inclusive
+	Until	time.Time	// Exclusive
+	Limit	int			// DefaultQueryLimit when 0
+}
+
+func (f Filter) matches(entry Entry) bool {
+	return (f.QuizId == "" || entry.QuizId == f.QuizId) &&
+		(f.Action == "" || entry.Action == f.Action) &&
+		(f.Since.IsZero() || !entry.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || entry.Time.Before(f.Until))
+}
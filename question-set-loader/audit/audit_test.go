@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatalf("Failed to open sink: %v", err)
+	}
+	defer sink.Close()
+
+	start := time.Date(2022, 1, 27, 8, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Time: start, Action: ActionUpload, QuizId: "quiz1", Subject: "host1", ClientIP: "10.0.0.1", ContentHash: "sha256:ab", Size: 120, Status: 201, Outcome: OutcomeSuccess},
+		{Time: start.Add(time.Minute), Action: ActionUpload, QuizId: "quiz2", ClientIP: "10.0.0.2", Size: 80, Status: 400, Outcome: "invalid-question-set"},
+		{Time: start.Add(2 * time.Minute), Action: ActionReplace, QuizId: "quiz1", ClientIP: "10.0.0.1", ContentHash: "sha256:cd", Size: 130, Status: 200, Outcome: OutcomeSuccess},
+		{Time: start.Add(3 * time.Minute), Action: ActionDelete, QuizId: "quiz1", ClientIP: "10.0.0.1", ContentHash: "sha256:cd", Status: 204, Outcome: OutcomeSuccess},
+	}
+	for _, entry := range entries {
+		if err := sink.Write(entry); err != nil {
+			t.Fatalf("Failed to write entry: %v", err)
+		}
+	}
+
+	tests := map[string]struct {
+		filter	Filter
+		want	[]Entry
+	}{
+		"all": {Filter{}, []Entry{entries[3], entries[2], entries[1], entries[0]}},
+		"quiz": {Filter{QuizId: "quiz1"}, []Entry{entries[3], entries[2], entries[0]}},
+		"action": {Filter{Action: ActionUpload}, []Entry{entries[1], entries[0]}},
+		"time range": {Filter{Since: start.Add(time.Minute), Until: start.Add(3 * time.Minute)}, []Entry{entries[2], entries[1]}},
+		"limited to newest": {Filter{QuizId: "quiz1", Limit: 2}, []Entry{entries[3], entries[2]}},
+		"none": {Filter{QuizId: "quiz3"}, []Entry{}},
+	}
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			got, err := sink.Query(test.filter)
+			if err != nil {
+				t.Fatalf("Failed to query: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Wrong entries: %s", diff)
+			}
+		})
+	}
+}
+
+// Entries are appended to what's already in the file and lines that aren't entries
+// are skipped
+func TestFileSink_appends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := os.WriteFile(path, []byte(`{"action": "upload", "quizId": "quiz1"}`+"\n"+`{"action": "upl`+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatalf("Failed to open sink: %v", err)
+	}
+	defer sink.Close()
+	if err := sink.Write(Entry{Action: ActionDelete, QuizId: "quiz1"}); err != nil {
+		t.Fatalf("Failed to write entry: %v", err)
+	}
+
+	got, err := sink.Query(Filter{})
+	if err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	want := []Entry{{Action: ActionDelete, QuizId: "quiz1"}, {Action: ActionUpload, QuizId: "quiz1"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Wrong entries: %s", diff)
+	}
+}
@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Sink appending each entry to a file as a line of JSON. The file is only appended to,
+// so it can be rotated by moving it aside. Queries only read the current file
+type FileSink struct {
+	path	string
+
+	mu		sync.Mutex
+	file	*os.File
+}
+
+// Opens the file at path for appending, creating it and its parent directories if needed
+func NewFileSink(path string) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create audit file parent directories. Error: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file '%s'. Error: %w", path, err)
+	}
+	return &FileSink{path: path, file: file}, nil
+}
+
+func (s *FileSink) Write(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// A single write so that lines from other processes appending to the file aren't interleaved
+	if _, err := s.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write to audit file '%s'. Error: %w", s.path, err)
+	}
+	return nil
+}
+
+// Reads the whole file, keeping the newest entries that match. Lines that can't be
+// decoded, such as one cut short by a crash, are skipped
+func (s *FileSink) Query(filter Filter) ([]Entry, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultQueryLimit
+	}
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file '%s'. Error: %w", s.path, err)
+	}
+	defer file.Close()
+
+	// Oldest first, dropping the oldest once there are more than limit
+	matched := []Entry{}
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read audit file '%s'. Error: %w", s.path, err)
+		}
+		var entry Entry
+		if json.Unmarshal(line, &entry) == nil && filter.matches(entry) {
+			matched = append(matched, entry)
+			if len(matched) > limit {
+				matched = matched[1:]
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+
+	newestFirst := make([]Entry, len(matched))
+	for i, entry := range matched {
+		newestFirst[len(matched)-1-i] = entry
+	}
+	return newestFirst, nil
+}
+
+// Closes the file
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
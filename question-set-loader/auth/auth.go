@@ -2,22 +2,42 @@ package auth
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/golang-jwt/jwt/v4"
 )
 
 type JwtParams struct {
-	Secret string
+	Secret string // Verifies HMAC signed tokens. HMAC tokens are rejected when empty
 	Issuer string
 	Audience string
+	Keys KeySource // Verifies RSA and ECDSA signed tokens by their 'kid'. Optional
+	Policy ClaimsPolicy // Checks on the 'exp', 'nbf' and 'iat' claims
+	Revocations RevocationList // Rejects revoked tokens and finished quizzes. Optional
+}
+
+// Claims identifying the host from a valid token
+type HostClaims struct {
+	QuizId	string
+	Subject	string // 'sub' claim, empty when the token doesn't have one
+	TokenId	string // 'jti' claim, empty when the token doesn't have one
 }
 
+// Validates the host's token and returns the quizId in it. See ValidateHostJwt
 func ValidateJwt(tokenString string, jwtParams JwtParams) (quizId string, err error) {
-	
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+	claims, err := ValidateHostJwt(tokenString, jwtParams)
+	return claims.QuizId, err
+}
 
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
+// Validates the host's token and returns the claims identifying them. HMAC signed
+// tokens are verified with the shared secret and RS256/ES256 (and the larger variants)
+// with the key in Keys named by the token's 'kid' header. Errors for revoked tokens
+// and finished quizzes wrap ErrTokenRevoked and ErrQuizFinished respectively
+func ValidateHostJwt(tokenString string, jwtParams JwtParams) (HostClaims, error) {
+	
+	// The time claims are checked by the policy which, unlike the parser, allows for clock skew
+	parser := jwt.Parser{SkipClaimsValidation: true}
+	token, err := parser.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 
 		// Validate claims
 		if claims, ok := token.Claims.(jwt.MapClaims); ok {
@@ -30,21 +50,46 @@ func ValidateJwt(tokenString string, jwtParams JwtParams) (quizId string, err er
 			if claims["isHost"] != true {
 				return nil, fmt.Errorf("only the host can upload the quiz")
 			}
-			if quizId := claims["quizId"]; quizId == nil || quizId == "" {
+			if quizId, ok := claims["quizId"].(string); !ok || quizId == "" {
 				return nil, fmt.Errorf("quizId must be non-empty")
 			}
 		} else {
 			return nil, fmt.Errorf("couldn't read claims")
 		}
 
-		return []byte(jwtParams.Secret), nil
+		switch token.Method.(type) {
+		case *jwt.SigningMethodHMAC:
+			if jwtParams.Secret == "" {
+				return nil, fmt.Errorf("HMAC signed tokens aren't accepted")
+			}
+			return []byte(jwtParams.Secret), nil
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+			if jwtParams.Keys == nil {
+				return nil, fmt.Errorf("no keys configured for signing method: %v", token.Header["alg"])
+			}
+			kid, _ := token.Header["kid"].(string)
+			return jwtParams.Keys.Key(kid, token.Method.Alg())
+		default:
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to parse token: Error: %s", err.Error())
+		return HostClaims{}, fmt.Errorf("failed to parse token: Error: %s", err.Error())
 	}
 
 	claims := token.Claims.(jwt.MapClaims)
-	quizId = claims["quizId"].(string)
+	if err := jwtParams.Policy.Validate(claims, time.Now()); err != nil {
+		return HostClaims{}, fmt.Errorf("invalid token: %w", err)
+	}
+	host := HostClaims{QuizId: claims["quizId"].(string)}
+	host.Subject, _ = claims["sub"].(string)
+	host.TokenId, _ = claims["jti"].(string)
+
+	if jwtParams.Revocations != nil {
+		if err := jwtParams.Revocations.Check(host.TokenId, host.QuizId); err != nil {
+			return HostClaims{}, fmt.Errorf("rejected token for quiz '%s': %w", host.QuizId, err)
+		}
+	}
 	
-	return quizId, nil
+	return host, nil
 }
\ No newline at end of file
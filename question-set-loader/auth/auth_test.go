@@ -26,9 +26,9 @@ func TestValidateJwt_valid(t *testing.T) {
 	t.Logf("Token is: %s", token)
 
 	parsedQuizId, err := ValidateJwt(token, JwtParams{
-		secret,
-		issuer,
-		audience,
+		Secret: secret,
+		Issuer: issuer,
+		Audience: audience,
 	})
 	if err != nil {
 		t.Errorf("Returned error response '%v'. Expected nil", err)
@@ -38,6 +38,35 @@ func TestValidateJwt_valid(t *testing.T) {
 	}
 }
 
+func TestValidateHostJwt_claims(t *testing.T) {
+	params := testutils.JwtTestParams{Secret: "secret", Issuer: "http://test.com", Audience: "http://test.com", IsHost: true, QuizId: "quizid1"}
+	jwtParams := JwtParams{Secret: "secret", Issuer: "http://test.com", Audience: "http://test.com"}
+
+	tests := map[string]struct {
+		claims	map[string]interface{}
+		want	HostClaims
+	}{
+		"subject and token id": {map[string]interface{}{"sub": "host@example.com", "jti": "token1"}, HostClaims{QuizId: "quizid1", Subject: "host@example.com", TokenId: "token1"}},
+		"quizId only": {nil, HostClaims{QuizId: "quizid1"}},
+	}
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			params.Claims = test.claims
+			token, err := testutils.BuildJwt(params)
+			if err != nil {
+				t.Fatalf("Failed creating test jwt with: %v", err)
+			}
+			got, err := ValidateHostJwt(token, jwtParams)
+			if err != nil {
+				t.Fatalf("Returned error response '%v'. Expected nil", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Wrong claims: %s", diff)
+			}
+		})
+	}
+}
+
 func TestValidateJwt_invalid(t *testing.T) {
 
 	// constants
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Policy for the time claims of a token. The zero value checks 'exp', 'nbf' and
+// 'iat' when they're present without any leeway and requires none of them
+type ClaimsPolicy struct {
+	RequireExpiry	bool			// Reject tokens without an 'exp' claim
+	MaxAge			time.Duration	// Reject tokens issued longer ago than this. Requires 'iat'. Unlimited when 0
+	Leeway			time.Duration	// Clock skew allowed between the issuer and this service
+}
+
+// Validates the time claims against the policy at now
+func (p ClaimsPolicy) Validate(claims jwt.MapClaims, now time.Time) error {
+	expiresAt, hasExpiry, err := timeClaim(claims, "exp")
+	if err != nil {
+		return err
+	}
+	if !hasExpiry && p.RequireExpiry {
+		return fmt.Errorf("token has no expiry")
+	}
+	if hasExpiry && now.After(expiresAt.Add(p.Leeway)) {
+		return fmt.Errorf("token expired at %s", expiresAt.UTC().Format(time.RFC3339))
+	}
+
+	notBefore, hasNotBefore, err := timeClaim(claims, "nbf")
+	if err != nil {
+		return err
+	}
+	if hasNotBefore && now.Add(p.Leeway).Before(notBefore) {
+		return fmt.Errorf("token isn't valid until %s", notBefore.UTC().Format(time.RFC3339))
+	}
+
+	issuedAt, hasIssuedAt, err := timeClaim(claims, "iat")
+	if err != nil {
+		return err
+	}
+	if hasIssuedAt && now.Add(p.Leeway).Before(issuedAt) {
+		return fmt.Errorf("token was issued in the future at %s", issuedAt.UTC().Format(time.RFC3339))
+	}
+	if p.MaxAge > 0 {
+		if !hasIssuedAt {
+			return fmt.Errorf("token has no issue time")
+		}
+		if now.Sub(issuedAt) > p.MaxAge+p.Leeway {
+			return fmt.Errorf("token issued at %s is older than %s", issuedAt.UTC().Format(time.RFC3339), p.MaxAge)
+		}
+	}
+
+	return nil
+}
+
+// Returns the NumericDate claim with the name as a time and whether it was present
+func timeClaim(claims jwt.MapClaims, name string) (time.Time, bool, error) {
+	var seconds float64
+	switch value := claims[name].(type) {
+	case nil:
+		return time.Time{}, false, nil
+	case float64:
+		seconds = value
+	case json.Number:
+		var err error
+		if seconds, err = value.Float64(); err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid '%s' claim '%s'", name, value)
+		}
+	default:
+		return time.Time{}, false, fmt.Errorf("invalid '%s' claim '%v'", name, value)
+	}
+	whole, fraction := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(fraction*float64(time.Second))), true, nil
+}
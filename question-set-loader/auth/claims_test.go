@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestClaimsPolicy_Validate(t *testing.T) {
+	now := time.Unix(1643273678, 0)
+	at := func(offset time.Duration) float64 {
+		return float64(now.Add(offset).Unix())
+	}
+
+	tests := map[string]struct {
+		policy ClaimsPolicy
+		claims jwt.MapClaims
+		valid bool
+	}{
+		"no time claims": {ClaimsPolicy{}, jwt.MapClaims{}, true},
+		"missing required expiry": {ClaimsPolicy{RequireExpiry: true}, jwt.MapClaims{}, false},
+		"has required expiry": {ClaimsPolicy{RequireExpiry: true}, jwt.MapClaims{"exp": at(time.Minute)}, true},
+		"expired": {ClaimsPolicy{}, jwt.MapClaims{"exp": at(-time.Minute)}, false},
+		"expired within leeway": {ClaimsPolicy{Leeway: 2 * time.Minute}, jwt.MapClaims{"exp": at(-time.Minute)}, true},
+		"not yet valid": {ClaimsPolicy{}, jwt.MapClaims{"nbf": at(time.Minute)}, false},
+		"not yet valid within leeway": {ClaimsPolicy{Leeway: 2 * time.Minute}, jwt.MapClaims{"nbf": at(time.Minute)}, true},
+		"issued in the future": {ClaimsPolicy{}, jwt.MapClaims{"iat": at(time.Minute)}, false},
+		"issued in the future within leeway": {ClaimsPolicy{Leeway: 2 * time.Minute}, jwt.MapClaims{"iat": at(time.Minute)}, true},
+		"max age without iat": {ClaimsPolicy{MaxAge: time.Hour}, jwt.MapClaims{}, false},
+		"younger than max age": {ClaimsPolicy{MaxAge: time.Hour}, jwt.MapClaims{"iat": at(-time.Minute)}, true},
+		"older than max age": {ClaimsPolicy{MaxAge: time.Hour}, jwt.MapClaims{"iat": at(-2 * time.Hour)}, false},
+		"older than max age within leeway": {ClaimsPolicy{MaxAge: time.Hour, Leeway: 2 * time.Hour}, jwt.MapClaims{"iat": at(-2 * time.Hour)}, true},
+		"invalid claim type": {ClaimsPolicy{}, jwt.MapClaims{"exp": "tomorrow"}, false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tc.policy.Validate(tc.claims, now)
+			if tc.valid && err != nil {
+				t.Errorf("Returned error response '%v'. Expected nil", err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("Failed to detect error")
+			}
+		})
+	}
+}
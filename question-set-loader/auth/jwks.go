@@ -0,0 +1,268 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Returned when a token is signed with a key that isn't in the key source
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Provides the public keys for verifying asymmetrically signed tokens
+type KeySource interface {
+	// Returns the key with id kid for verifying a token signed with alg e.g. "RS256".
+	// Returns ErrUnknownKey if there's no key with that id
+	Key(kid string, alg string) (crypto.PublicKey, error)
+}
+
+// Least time between refreshes of a JWKS loaded from a URL when a token has an unknown key id
+const jwksMinRefreshInterval = time.Minute
+
+const jwksFetchTimeout = 10 * time.Second
+
+// A key from a JSON Web Key Set. See https://datatracker.ietf.org/doc/html/rfc7517
+type jsonWebKey struct {
+	Kid	string	`json:"kid"`
+	Kty	string	`json:"kty"`
+	Alg	string	`json:"alg"`
+	Use	string	`json:"use"`
+	// RSA
+	N	string	`json:"n"`
+	E	string	`json:"e"`
+	// EC
+	Crv	string	`json:"crv"`
+	X	string	`json:"x"`
+	Y	string	`json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// Verification key parsed from a jsonWebKey
+type publicKey struct {
+	key	crypto.PublicKey
+	alg	string // Empty if the key doesn't restrict the algorithm
+}
+
+// KeySource backed by a JSON Web Key Set from a local file or an http(s) URL. Every
+// key in the set is active so keys can be rotated by adding the new one before
+// signing with it. Sets loaded from a URL are fetched again when a token has a key
+// id that hasn't been seen, at most once every jwksMinRefreshInterval
+type Jwks struct {
+	location	string
+	client		*http.Client
+
+	mu			sync.RWMutex
+	keys		map[string]publicKey
+	lastLoad	time.Time
+}
+
+// Loads the JSON Web Key Set from location, which is either a file path or an
+// http(s) URL
+func NewJwks(location string) (*Jwks, error) {
+	jwks := &Jwks{
+		location: location,
+		client: &http.Client{Timeout: jwksFetchTimeout},
+	}
+	if err := jwks.load(); err != nil {
+		return nil, err
+	}
+	return jwks, nil
+}
+
+func (j *Jwks) Key(kid string, alg string) (crypto.PublicKey, error) {
+	j.mu.RLock()
+	key, ok := j.keys[kid]
+	lastLoad := j.lastLoad
+	j.mu.RUnlock()
+
+	if !ok && j.isUrl() && time.Since(lastLoad) >= jwksMinRefreshInterval {
+		// The key may have been added since the set was loaded
+		if err := j.load(); err != nil {
+			return nil, err
+		}
+		j.mu.RLock()
+		key, ok = j.keys[kid]
+		j.mu.RUnlock()
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w '%s'", ErrUnknownKey, kid)
+	}
+
+	if key.alg != "" && key.alg != alg {
+		return nil, fmt.Errorf("key '%s' is for '%s' not '%s'", kid, key.alg, alg)
+	}
+	if err := checkKeyType(key.key, alg); err != nil {
+		return nil, fmt.Errorf("key '%s' can't verify '%s': %w", kid, alg, err)
+	}
+	return key.key, nil
+}
+
+func (j *Jwks) isUrl() bool {
+	return strings.HasPrefix(j.location, "http://") || strings.HasPrefix(j.location, "https://")
+}
+
+// Reads the key set from the location and replaces the current keys
+func (j *Jwks) load() error {
+	var data []byte
+	var err error
+	if j.isUrl() {
+		data, err = j.fetch()
+	} else {
+		data, err = ioutil.ReadFile(j.location)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.lastLoad = time.Now()
+	if err != nil {
+		return fmt.Errorf("failed to read JWKS from '%s': %w", j.location, err)
+	}
+
+	keys, err := parseJwks(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse JWKS from '%s': %w", j.location, err)
+	}
+	j.keys = keys
+	return nil
+}
+
+func (j *Jwks) fetch() ([]byte, error) {
+	response, err := j.client.Get(j.location)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status '%s'", response.Status)
+	}
+	return ioutil.ReadAll(response.Body)
+}
+
+// Parses the RSA and EC signing keys from a JSON Web Key Set, keyed by key id.
+// Keys for encryption and of other types are skipped
+func parseJwks(data []byte) (map[string]publicKey, error) {
+	var keySet jsonWebKeySet
+	if err := json.Unmarshal(data, &keySet); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]publicKey)
+	for i, jwk := range keySet.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		var key crypto.PublicKey
+		var err error
+		switch jwk.Kty {
+		case "RSA":
+			key, err = jwk.rsaPublicKey()
+		case "EC":
+			key, err = jwk.ecdsaPublicKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("keys[%d]: %w", i, err)
+		}
+
+		if _, ok := keys[jwk.Kid]; ok {
+			return nil, fmt.Errorf("keys[%d]: duplicate key id '%s'", i, jwk.Kid)
+		}
+		keys[jwk.Kid] = publicKey{key, jwk.Alg}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no RSA or EC signing keys")
+	}
+	return keys, nil
+}
+
+func (jwk jsonWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'n': %w", err)
+	}
+	e, err := decodeBigInt(jwk.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'e': %w", err)
+	}
+	if !e.IsInt64() || e.Int64() > 1<<31-1 || e.Int64() < 2 {
+		return nil, errors.New("invalid 'e': out of range")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (jwk jsonWebKey) ecdsaPublicKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve '%s'", jwk.Crv)
+	}
+	x, err := decodeBigInt(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'x': %w", err)
+	}
+	y, err := decodeBigInt(jwk.Y)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'y': %w", err)
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("point isn't on the curve")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// Decodes the unpadded base64url big-endian integers used in JWKs
+func decodeBigInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("missing")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// Checks that key is the right type for verifying a token signed with alg
+func checkKeyType(key crypto.PublicKey, alg string) error {
+	switch alg {
+	case "RS256", "RS384", "RS512":
+		if _, ok := key.(*rsa.PublicKey); !ok {
+			return errors.New("not an RSA key")
+		}
+	case "ES256", "ES384", "ES512":
+		ecdsaKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("not an EC key")
+		}
+		curves := map[string]string{"ES256": "P-256", "ES384": "P-384", "ES512": "P-521"}
+		if name := ecdsaKey.Curve.Params().Name; name != curves[alg] {
+			return fmt.Errorf("wrong curve '%s'", name)
+		}
+	default:
+		return fmt.Errorf("unsupported algorithm")
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/internal/testutils"
+	"github.com/google/go-cmp/cmp"
+)
+
+const testIssuer = "http://test.com"
+const testAudience = "http://test.com"
+
+// Test signing keys, generated once as RSA key generation is slow
+var (
+	testKeysOnce	sync.Once
+	testRsaKey		*rsa.PrivateKey
+	testRsaKey2		*rsa.PrivateKey
+	testEcdsaKey	*ecdsa.PrivateKey
+)
+
+func testKeys(t *testing.T) (*rsa.PrivateKey, *rsa.PrivateKey, *ecdsa.PrivateKey) {
+	testKeysOnce.Do(func() {
+		testRsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+		testRsaKey2, _ = rsa.GenerateKey(rand.Reader, 2048)
+		testEcdsaKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	})
+	if testRsaKey == nil || testRsaKey2 == nil || testEcdsaKey == nil {
+		t.Fatalf("Failed to generate test keys")
+	}
+	return testRsaKey, testRsaKey2, testEcdsaKey
+}
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func rsaJwk(kid string, key *rsa.PrivateKey) jsonWebKey {
+	return jsonWebKey{Kid: kid, Kty: "RSA", Use: "sig", N: encodeBigInt(key.N), E: encodeBigInt(big.NewInt(int64(key.E)))}
+}
+
+func ecdsaJwk(kid string, key *ecdsa.PrivateKey) jsonWebKey {
+	return jsonWebKey{Kid: kid, Kty: "EC", Crv: "P-256", X: encodeBigInt(key.X), Y: encodeBigInt(key.Y)}
+}
+
+// Writes the keys to a JWKS file and returns its path
+func writeJwksFile(t *testing.T, keys ...jsonWebKey) string {
+	data, err := json.Marshal(jsonWebKeySet{keys})
+	if err != nil {
+		t.Fatalf("Failed to serialize JWKS: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write JWKS: %v", err)
+	}
+	return path
+}
+
+func buildSignedJwt(t *testing.T, kid string, signingKey interface{}) string {
+	token, err := testutils.BuildJwt(testutils.JwtTestParams{
+		Issuer: testIssuer,
+		Audience: testAudience,
+		IsHost: true,
+		QuizId: "quizid1",
+		Kid: kid,
+		SigningKey: signingKey,
+	})
+	if err != nil {
+		t.Fatalf("Failed to build token: %v", err)
+	}
+	return token
+}
+
+func TestValidateJwt_jwks_valid(t *testing.T) {
+	rsaKey, rsaKey2, ecdsaKey := testKeys(t)
+	jwks, err := NewJwks(writeJwksFile(t, rsaJwk("rsa1", rsaKey), rsaJwk("rsa2", rsaKey2), ecdsaJwk("ec1", ecdsaKey)))
+	if err != nil {
+		t.Fatalf("Failed to load JWKS: %v", err)
+	}
+	jwtParams := JwtParams{Issuer: testIssuer, Audience: testAudience, Keys: jwks}
+
+	tests := map[string]string{
+		"RS256": buildSignedJwt(t, "rsa1", rsaKey),
+		"RS256 rotated key": buildSignedJwt(t, "rsa2", rsaKey2),
+		"ES256": buildSignedJwt(t, "ec1", ecdsaKey),
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			quizId, err := ValidateJwt(token, jwtParams)
+			if err != nil {
+				t.Fatalf("Returned error response '%v'. Expected nil", err)
+			}
+			if diff := cmp.Diff("quizid1", quizId); diff != "" {
+				t.Errorf("Wrong parsed quizid: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateJwt_jwks_invalid(t *testing.T) {
+	rsaKey, rsaKey2, ecdsaKey := testKeys(t)
+	jwks, err := NewJwks(writeJwksFile(t, rsaJwk("rsa1", rsaKey), ecdsaJwk("ec1", ecdsaKey)))
+	if err != nil {
+		t.Fatalf("Failed to load JWKS: %v", err)
+	}
+
+	tests := map[string]struct {
+		token string
+		jwtParams JwtParams
+	}{
+		"unknown kid": {buildSignedJwt(t, "rsa2", rsaKey2), JwtParams{Issuer: testIssuer, Audience: testAudience, Keys: jwks}},
+		"no kid": {buildSignedJwt(t, "", rsaKey), JwtParams{Issuer: testIssuer, Audience: testAudience, Keys: jwks}},
+		"wrong key": {buildSignedJwt(t, "rsa1", rsaKey2), JwtParams{Issuer: testIssuer, Audience: testAudience, Keys: jwks}},
+		"key type mismatch": {buildSignedJwt(t, "ec1", rsaKey), JwtParams{Issuer: testIssuer, Audience: testAudience, Keys: jwks}},
+		"no keys configured": {buildSignedJwt(t, "rsa1", rsaKey), JwtParams{Secret: "secret", Issuer: testIssuer, Audience: testAudience}},
+		"hmac without secret": {buildSignedJwt(t, "", nil), JwtParams{Issuer: testIssuer, Audience: testAudience, Keys: jwks}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ValidateJwt(tc.token, tc.jwtParams); err == nil {
+				t.Errorf("Failed to detect error")
+			}
+		})
+	}
+}
+
+// HMAC tokens keep working alongside the JWKS for existing deployments
+func TestValidateJwt_jwks_hmac_fallback(t *testing.T) {
+	rsaKey, _, _ := testKeys(t)
+	jwks, err := NewJwks(writeJwksFile(t, rsaJwk("rsa1", rsaKey)))
+	if err != nil {
+		t.Fatalf("Failed to load JWKS: %v", err)
+	}
+
+	token, err := testutils.BuildJwt(testutils.JwtTestParams{
+		Secret: "secret",
+		Issuer: testIssuer,
+		Audience: testAudience,
+		IsHost: true,
+		QuizId: "quizid1",
+	})
+	if err != nil {
+		t.Fatalf("Failed to build token: %v", err)
+	}
+
+	if _, err := ValidateJwt(token, JwtParams{Secret: "secret", Issuer: testIssuer, Audience: testAudience, Keys: jwks}); err != nil {
+		t.Errorf("Returned error response '%v'. Expected nil", err)
+	}
+}
+
+func TestJwks_url_refresh(t *testing.T) {
+	rsaKey, rsaKey2, _ := testKeys(t)
+
+	var mu sync.Mutex
+	keySet := jsonWebKeySet{[]jsonWebKey{rsaJwk("rsa1", rsaKey)}}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		json.NewEncoder(w).Encode(keySet)
+	}))
+	defer server.Close()
+
+	jwks, err := NewJwks(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to load JWKS: %v", err)
+	}
+
+	// Rotate in a new key
+	mu.Lock()
+	keySet.Keys = append(keySet.Keys, rsaJwk("rsa2", rsaKey2))
+	mu.Unlock()
+
+	// Not refetched until the refresh interval has passed
+	if _, err := jwks.Key("rsa2", "RS256"); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Expected ErrUnknownKey, got: %v", err)
+	}
+
+	jwks.lastLoad = time.Now().Add(-jwksMinRefreshInterval)
+	if _, err := jwks.Key("rsa2", "RS256"); err != nil {
+		t.Fatalf("Failed to get rotated key: %v", err)
+	}
+	if diff := cmp.Diff(2, requests); diff != "" {
+		t.Errorf("Wrong number of fetches: %s", diff)
+	}
+}
+
+func TestParseJwks_invalid(t *testing.T) {
+	rsaKey, _, _ := testKeys(t)
+	badRsa := rsaJwk("rsa1", rsaKey)
+	badRsa.N = "not base64!"
+	offCurve := jsonWebKey{Kid: "ec1", Kty: "EC", Crv: "P-256", X: encodeBigInt(big.NewInt(1)), Y: encodeBigInt(big.NewInt(1))}
+
+	tests := map[string]jsonWebKeySet{
+		"no keys": {[]jsonWebKey{}},
+		"only encryption keys": {[]jsonWebKey{{Kid: "enc", Kty: "RSA", Use: "enc", N: badRsa.E, E: badRsa.E}}},
+		"invalid modulus": {[]jsonWebKey{badRsa}},
+		"point off curve": {[]jsonWebKey{offCurve}},
+		"duplicate kid": {[]jsonWebKey{rsaJwk("rsa1", rsaKey), rsaJwk("rsa1", rsaKey)}},
+	}
+	for name, keySet := range tests {
+		t.Run(name, func(t *testing.T) {
+			data, _ := json.Marshal(keySet)
+			if _, err := parseJwks(data); err == nil {
+				t.Errorf("Failed to detect error")
+			}
+		})
+	}
+}
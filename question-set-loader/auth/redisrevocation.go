@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type RedisRevocationOptions struct {
+	Addr     string
+	Password string
+}
+
+// RevocationList backed by the Redis instance the quiz runs on. A token is revoked
+// when 'revoked:token:<jti>' exists. A quiz has finished when 'revoked:quiz:<quizId>'
+// exists or the speed-run service has set '<quizId>:stopTime'
+type RedisRevocationList struct {
+	rdb *redis.Client
+}
+
+func NewRedisRevocationList(o RedisRevocationOptions) *RedisRevocationList {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     o.Addr,
+		Password: o.Password,
+	})
+
+	return &RedisRevocationList{
+		rdb: rdb,
+	}
+}
+
+func (r *RedisRevocationList) Check(tokenId string, quizId string) error {
+
+	// Give the check 1 second to complete
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	if tokenId != "" {
+		revoked, err := r.rdb.Exists(ctx, "revoked:token:" + tokenId).Result()
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrRevocationCheckFailed, err)
+		}
+		if revoked > 0 {
+			return ErrTokenRevoked
+		}
+	}
+
+	finished, err := r.rdb.Exists(ctx, "revoked:quiz:" + quizId, quizId + ":stopTime").Result()
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrRevocationCheckFailed, err)
+	}
+	if finished > 0 {
+		return ErrQuizFinished
+	}
+	return nil
+}
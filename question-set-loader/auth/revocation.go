@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+const RevocationStoreFile = "file"
+const RevocationStoreRedis = "redis"
+
+// Returned when the token's 'jti' has been revoked
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// Returned when the token is for a quiz that has finished or been revoked
+var ErrQuizFinished = errors.New("quiz has finished")
+
+// Returned when it couldn't be determined whether the token has been revoked
+var ErrRevocationCheckFailed = errors.New("failed to check token revocation")
+
+// Checks whether tokens can still be used
+type RevocationList interface {
+	// Returns ErrTokenRevoked if the token with tokenId has been revoked or ErrQuizFinished
+	// if quizId has finished, else nil. tokenId is empty if the token doesn't have one
+	Check(tokenId string, quizId string) error
+}
+
+// Contents of the revocation list file
+type revocationFile struct {
+	TokenIds	[]string	`json:"tokenIds"`
+	QuizIds		[]string	`json:"quizIds"`
+}
+
+// RevocationList read from a JSON file of the form {"tokenIds": [...], "quizIds": [...]}.
+// The file is read again whenever its modification time changes. A missing file
+// revokes nothing
+type FileRevocationList struct {
+	path	string
+
+	mu			sync.Mutex
+	modTime		time.Time
+	tokenIds	map[string]bool
+	quizIds		map[string]bool
+}
+
+func NewFileRevocationList(path string) *FileRevocationList {
+	return &FileRevocationList{path: path}
+}
+
+func (f *FileRevocationList) Check(tokenId string, quizId string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.reload(); err != nil {
+		return fmt.Errorf("%w: %s", ErrRevocationCheckFailed, err)
+	}
+	if tokenId != "" && f.tokenIds[tokenId] {
+		return ErrTokenRevoked
+	}
+	if f.quizIds[quizId] {
+		return ErrQuizFinished
+	}
+	return nil
+}
+
+// Reads the file if it's changed since it was last read
+func (f *FileRevocationList) reload() error {
+	info, err := os.Stat(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		f.modTime, f.tokenIds, f.quizIds = time.Time{}, nil, nil
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if f.tokenIds != nil && info.ModTime().Equal(f.modTime) {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	var contents revocationFile
+	if err := json.Unmarshal(data, &contents); err != nil {
+		return fmt.Errorf("invalid revocation file '%s': %w", f.path, err)
+	}
+
+	f.tokenIds = make(map[string]bool, len(contents.TokenIds))
+	for _, tokenId := range contents.TokenIds {
+		f.tokenIds[tokenId] = true
+	}
+	f.quizIds = make(map[string]bool, len(contents.QuizIds))
+	for _, quizId := range contents.QuizIds {
+		f.quizIds[quizId] = true
+	}
+	f.modTime = info.ModTime()
+	return nil
+}
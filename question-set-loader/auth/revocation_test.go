@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/internal/testutils"
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestFileRevocationList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "revoked.json")
+	revocations := NewFileRevocationList(path)
+
+	// Nothing is revoked until the file exists
+	if err := revocations.Check("token1", "quiz1"); err != nil {
+		t.Fatalf("Returned error response '%v'. Expected nil", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"tokenIds": ["token1"], "quizIds": ["quiz1"]}`), 0644); err != nil {
+		t.Fatalf("Failed to write revocation file: %v", err)
+	}
+	tests := map[string]struct {
+		tokenId string
+		quizId string
+		want error
+	}{
+		"revoked token": {"token1", "quiz2", ErrTokenRevoked},
+		"finished quiz": {"token2", "quiz1", ErrQuizFinished},
+		"finished quiz without token id": {"", "quiz1", ErrQuizFinished},
+		"valid": {"token2", "quiz2", nil},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := revocations.Check(tc.tokenId, tc.quizId); !errors.Is(err, tc.want) {
+				t.Errorf("Expected '%v', got '%v'", tc.want, err)
+			}
+		})
+	}
+
+	// Changes to the file are picked up
+	if err := os.WriteFile(path, []byte(`{"quizIds": ["quiz2"]}`), 0644); err != nil {
+		t.Fatalf("Failed to write revocation file: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	if err := revocations.Check("token1", "quiz2"); !errors.Is(err, ErrQuizFinished) {
+		t.Errorf("Expected '%v', got '%v'", ErrQuizFinished, err)
+	}
+
+	// A corrupt file fails closed
+	if err := os.WriteFile(path, []byte(`not json`), 0644); err != nil {
+		t.Fatalf("Failed to write revocation file: %v", err)
+	}
+	later = later.Add(time.Minute)
+	os.Chtimes(path, later, later)
+	if err := revocations.Check("token2", "quiz3"); !errors.Is(err, ErrRevocationCheckFailed) {
+		t.Errorf("Expected '%v', got '%v'", ErrRevocationCheckFailed, err)
+	}
+}
+
+func TestRedisRevocationList(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	mr.Set("revoked:token:token1", "1")
+	mr.Set("revoked:quiz:quiz1", "1")
+	mr.Set("quiz2:stopTime", "1643273706")
+	revocations := NewRedisRevocationList(RedisRevocationOptions{Addr: mr.Addr()})
+
+	tests := map[string]struct {
+		tokenId string
+		quizId string
+		want error
+	}{
+		"revoked token": {"token1", "quiz3", ErrTokenRevoked},
+		"revoked quiz": {"token2", "quiz1", ErrQuizFinished},
+		"stopped quiz": {"", "quiz2", ErrQuizFinished},
+		"valid": {"token2", "quiz3", nil},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := revocations.Check(tc.tokenId, tc.quizId); !errors.Is(err, tc.want) {
+				t.Errorf("Expected '%v', got '%v'", tc.want, err)
+			}
+		})
+	}
+
+	mr.Close()
+	if err := revocations.Check("token2", "quiz3"); !errors.Is(err, ErrRevocationCheckFailed) {
+		t.Errorf("Expected '%v', got '%v'", ErrRevocationCheckFailed, err)
+	}
+}
+
+func TestValidateJwt_revoked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "revoked.json")
+	if err := os.WriteFile(path, []byte(`{"tokenIds": ["token1"], "quizIds": ["quiz1"]}`), 0644); err != nil {
+		t.Fatalf("Failed to write revocation file: %v", err)
+	}
+	jwtParams := JwtParams{Secret: "secret", Issuer: testIssuer, Audience: testAudience, Revocations: NewFileRevocationList(path)}
+
+	tests := map[string]struct {
+		params testutils.JwtTestParams
+		want error
+	}{
+		"revoked token": {testutils.JwtTestParams{QuizId: "quiz2", Claims: map[string]interface{}{"jti": "token1"}}, ErrTokenRevoked},
+		"finished quiz": {testutils.JwtTestParams{QuizId: "quiz1"}, ErrQuizFinished},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tc.params.Secret, tc.params.Issuer, tc.params.Audience, tc.params.IsHost = "secret", testIssuer, testAudience, true
+			token, err := testutils.BuildJwt(tc.params)
+			if err != nil {
+				t.Fatalf("Failed to build token: %v", err)
+			}
+			if _, err := ValidateJwt(token, jwtParams); !errors.Is(err, tc.want) {
+				t.Errorf("Expected '%v', got '%v'", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestValidateJwt_policy(t *testing.T) {
+	jwtParams := JwtParams{Secret: "secret", Issuer: testIssuer, Audience: testAudience, Policy: ClaimsPolicy{Leeway: time.Minute}}
+
+	tests := map[string]struct {
+		exp time.Duration
+		valid bool
+	}{
+		"expired": {-2 * time.Minute, false},
+		"expired within leeway": {-30 * time.Second, true},
+		"not expired": {time.Hour, true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			token, err := testutils.BuildJwt(testutils.JwtTestParams{
+				Secret: "secret",
+				Issuer: testIssuer,
+				Audience: testAudience,
+				IsHost: true,
+				QuizId: "quizid1",
+				Claims: map[string]interface{}{"exp": time.Now().Add(tc.exp).Unix()},
+			})
+			if err != nil {
+				t.Fatalf("Failed to build token: %v", err)
+			}
+			_, err = ValidateJwt(token, jwtParams)
+			if tc.valid && err != nil {
+				t.Errorf("Returned error response '%v'. Expected nil", err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("Failed to detect error")
+			}
+		})
+	}
+}
@@ -1,17 +1,63 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/audit"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/auth"
 	"github.com/Ryangwaite/mc-speedrun/question-set-loader/config"
 	"github.com/Ryangwaite/mc-speedrun/question-set-loader/handler"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/idempotency"
 	"github.com/Ryangwaite/mc-speedrun/question-set-loader/logfmt"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/metrics"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/publish"
 	"github.com/Ryangwaite/mc-speedrun/question-set-loader/quiz"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/ratelimit"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/secret"
 	log "github.com/sirupsen/logrus"
 )
 
+// Builds the QuizStore selected in the config
+func buildQuizStore(config config.Config) (quiz.QuizStore, error) {
+	if config.Loader.Store == quiz.StoreS3 {
+		return quiz.NewQuizS3Writer(context.TODO(), quiz.S3Options{
+			Endpoint: config.S3.Endpoint,
+			Region: config.S3.Region,
+			Bucket: config.S3.Bucket,
+			Prefix: config.S3.Prefix,
+			AccessKeyID: config.S3.AccessKeyID,
+			SecretAccessKey: config.S3.SecretAccessKey,
+		})
+	}
+	return quiz.QuizJsonFileWriter{SaveDirectory: config.Loader.DestinationDirectory}, nil
+}
+
+// Builds the Publisher selected in the config, or nil when events aren't published
+func buildPublisher(config config.Config) (publish.Publisher, error) {
+	switch config.Publish.Broker {
+	case publish.BrokerRabbitMq:
+		return publish.NewRabbitMqPublisher(publish.RabbitMqOptions{
+			Host: config.RabbitMQ.Host,
+			Port: config.RabbitMQ.Port,
+			Username: config.RabbitMQ.Username,
+			Password: config.RabbitMQ.Password,
+			QueueName: config.Publish.Channel,
+		})
+	case publish.BrokerRedis:
+		return publish.NewRedisPublisher(publish.RedisOptions{
+			Addr: fmt.Sprintf("%s:%d", config.Redis.Host, config.Redis.Port),
+			Channel: config.Publish.Channel,
+		}), nil
+	}
+	return nil, nil
+}
+
 func main() {
 
 	logger := log.New()
@@ -19,31 +65,155 @@ func main() {
 	logger.SetOutput(os.Stdout)
 	logger.SetFormatter(logfmt.NewUtcLogFormatter())
 
-	// FIXME: The config file is required else it will panic even if all config
-	// properties are provided via environment variables. Note that viper still
-	// requires the config file to be present though.
-	// See https://github.com/spf13/viper/issues/584
-	config, err := config.Load("./config.ini")
+	configPath := flag.String("config", "", "Path to an .ini, .yaml or .toml config file. Without one, the config comes from environment variables alone")
+	flag.Parse()
+
+	config, err := config.Load(*configPath)
 	if err != nil {
 		logger.Panic("Failed to load config. Error: " + err.Error())
 	}
 
-	// NOTE: Probably should mask out sensitive config
-	logger.Info("Loaded config: " + fmt.Sprintf("%#v", config))
+	logger.Info("Loaded config: " + config.String())
+
+	// Resolved after logging so that the references to secrets are shown rather than redacted
+	if err := config.ResolveSecrets(context.TODO(), secret.NewResolver(secret.NewSecretsManagerSource())); err != nil {
+		logger.Panic("Failed to resolve secrets. Error: " + err.Error())
+	}
+
+	quizStore, err := buildQuizStore(config)
+	if err != nil {
+		logger.Panic("Failed to build quiz store. Error: " + err.Error())
+	}
+	readinessChecks := map[string]func() error{}
+	if checker, ok := quizStore.(quiz.WritableChecker); ok {
+		readinessChecks["store"] = checker.CheckWritable
+	}
+	m := metrics.New()
+	quizStore = m.InstrumentQuizStore(quizStore)
+
+	jwtParams := auth.JwtParams{
+		Secret: config.Jwt.Secret,
+		Issuer: config.Jwt.Issuer,
+		Audience: config.Jwt.Audience,
+		Policy: auth.ClaimsPolicy{
+			RequireExpiry: config.Jwt.RequireExpiry,
+			MaxAge: config.Jwt.MaxAge,
+			Leeway: config.Jwt.Leeway,
+		},
+	}
+	if config.Jwt.Jwks != "" {
+		if jwtParams.Keys, err = auth.NewJwks(config.Jwt.Jwks); err != nil {
+			logger.Panic("Failed to load JWKS. Error: " + err.Error())
+		}
+	}
+
+	switch {
+	case config.Revocation.Store == auth.RevocationStoreRedis:
+		jwtParams.Revocations = auth.NewRedisRevocationList(auth.RedisRevocationOptions{
+			Addr: fmt.Sprintf("%s:%d", config.Redis.Host, config.Redis.Port),
+		})
+	case config.Revocation.File != "":
+		jwtParams.Revocations = auth.NewFileRevocationList(config.Revocation.File)
+	}
+
+	rateLimits := handler.RateLimits{
+		Client: ratelimit.PerMinute(config.RateLimit.ClientPerMinute, config.RateLimit.ClientBurst),
+		Quiz: ratelimit.PerMinute(config.RateLimit.QuizPerMinute, config.RateLimit.QuizBurst),
+		TrustForwardedFor: config.RateLimit.TrustForwardedFor,
+	}
+	if config.RateLimit.Store == ratelimit.StoreRedis {
+		rateLimits.Limiter = ratelimit.NewRedisLimiter(ratelimit.RedisOptions{
+			Addr: fmt.Sprintf("%s:%d", config.Redis.Host, config.Redis.Port),
+		})
+	} else {
+		rateLimits.Limiter = ratelimit.NewMemoryLimiter()
+	}
+
+	idempotencyOptions := handler.Idempotency{TTL: config.Idempotency.TTL}
+	if config.Idempotency.Store == idempotency.StoreRedis {
+		idempotencyOptions.Store = idempotency.NewRedisStore(idempotency.RedisOptions{
+			Addr: fmt.Sprintf("%s:%d", config.Redis.Host, config.Redis.Port),
+		})
+	} else {
+		idempotencyOptions.Store = idempotency.NewMemoryStore()
+	}
+
+	corsOptions := handler.CorsOptions{
+		AllowedOrigins: config.Cors.AllowedOrigins,
+		AllowedMethods: config.Cors.AllowedMethods,
+		AllowedHeaders: config.Cors.AllowedHeaders,
+		AllowCredentials: config.Cors.AllowCredentials,
+		MaxAge: config.Cors.MaxAge,
+	}
+	if config.Server.Development && len(corsOptions.AllowedOrigins) == 0 {
+		// Let the UI dev server on another port through
+		corsOptions.AllowedOrigins = []string{"*"}
+	}
+
+	publisher, err := buildPublisher(config)
+	if err != nil {
+		logger.Panic("Failed to build publisher. Error: " + err.Error())
+	}
+	if publisher != nil {
+		defer publisher.Close()
+	}
 
 	upload := handler.Upload {
-		DevelopmentMode: config.Server.Development,
-		QuizWriter: quiz.QuizJsonFileWriter{SaveDirectory: config.Loader.DestinationDirectory},
-		JwtParams: config.Jwt,
+		QuizStore: quizStore,
+		JwtParams: jwtParams,
+		RateLimits: rateLimits,
+		Idempotency: idempotencyOptions,
+		QuizLibrary: quiz.QuizLibrary{Directory: config.Loader.LibraryDirectory},
+		Publisher: publisher,
 		Logger: logger,
 	}
 
-	http.HandleFunc("/api/upload/quiz", handler.LoggerMiddleware(logger, upload.Quiz))
+	if config.Audit.File != "" {
+		auditSink, err := audit.NewFileSink(config.Audit.File)
+		if err != nil {
+			logger.Panic("Failed to open audit log. Error: " + err.Error())
+		}
+		defer auditSink.Close()
+		upload.Audit = auditSink
+		// Outside of '/api/' so that it isn't exposed through the proxy in front of the API
+		if config.Audit.OperatorToken != "" {
+			http.HandleFunc(handler.AuditPath, handler.AuditLog(auditSink, config.Audit.OperatorToken))
+		}
+	}
+
+	apiMux := handler.NewServeMux(&upload, corsOptions, logger)
+	http.HandleFunc("/api/", m.Middleware(apiMux.ServeHTTP))
+	http.Handle("/metrics", m.Handler())
+	http.HandleFunc("/healthz", handler.Healthz)
+	http.HandleFunc("/readyz", handler.Readyz(readinessChecks))
+
+	// Stop on SIGTERM from the orchestrator or Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
 
 	port := config.Server.Port
+	server := &http.Server{Addr: fmt.Sprintf(":%d", port)}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
 	logger.Info(fmt.Sprintf("Listening on port %d", port))
-	err = http.ListenAndServe(fmt.Sprintf(":%d", port), nil)
-	if err != nil {
+
+	select {
+	case err := <-serverErr:
 		logger.Panic(fmt.Sprintf("Failed to start server. Error: %s", err.Error()))
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Stop accepting connections and give in-flight uploads time to finish
+	logger.Info(fmt.Sprintf("Shutting down. Waiting up to %s for in-flight requests", config.Server.ShutdownTimeout))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error(fmt.Sprintf("Failed to finish in-flight requests. Error: %s", err.Error()))
+		server.Close()
+		return
 	}
+	logger.Info("Shut down")
 }
@@ -9,104 +9,187 @@ import (
 
 	"github.com/Ryangwaite/mc-speedrun/question-set-loader/adapter"
 	"github.com/Ryangwaite/mc-speedrun/question-set-loader/auth"
+	appconfig "github.com/Ryangwaite/mc-speedrun/question-set-loader/config"
 	"github.com/Ryangwaite/mc-speedrun/question-set-loader/handler"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/idempotency"
 	"github.com/Ryangwaite/mc-speedrun/question-set-loader/logfmt"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/publish"
 	"github.com/Ryangwaite/mc-speedrun/question-set-loader/quiz"
-	"github.com/aws/aws-lambda-go/events"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/ratelimit"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/secret"
 	"github.com/aws/aws-lambda-go/lambda"
 	log "github.com/sirupsen/logrus"
 )
 
-type lambdaConfig struct {
-	quizFileDirectory string
-	jwt auth.JwtParams
+// Logs every invocation in the execution environment
+var logger = newLogger()
 
-}
-
-const ENV_VAR_PREFIX = "MC_SPEEDRUN_"
+// API built on the cold start, reused while the execution environment is warm so the
+// config is loaded, secrets resolved and connections made once
+var apiCache http.Handler
 
-// Loads the lambda config from the environment variables
-func loadLambdaConfig() (lambdaConfig, error) {
-	config := lambdaConfig{}
+func newLogger() *log.Logger {
+	logger := log.New()
+	logger.SetLevel(log.InfoLevel)
+	logger.SetOutput(os.Stdout)
+	logger.SetFormatter(logfmt.NewUtcLogFormatter())
+	return logger
+}
 
-	loaderDstDirKey := ENV_VAR_PREFIX + "LOADER_DST_DIR"
-	if config.quizFileDirectory = os.Getenv(loaderDstDirKey); config.quizFileDirectory == "" {
-		return config, fmt.Errorf("required env var '%s' was missing", loaderDstDirKey) // TODO: Use custom error type
+// Builds the QuizStore selected in the config
+func buildQuizStore(ctx context.Context, config appconfig.Config) (quiz.QuizStore, error) {
+	if config.Loader.Store == quiz.StoreS3 {
+		return quiz.NewQuizS3Writer(ctx, quiz.S3Options{
+			Endpoint: config.S3.Endpoint,
+			Region: config.S3.Region,
+			Bucket: config.S3.Bucket,
+			Prefix: config.S3.Prefix,
+			AccessKeyID: config.S3.AccessKeyID,
+			SecretAccessKey: config.S3.SecretAccessKey,
+		})
 	}
+	return quiz.QuizJsonFileWriter{SaveDirectory: config.Loader.DestinationDirectory}, nil
+}
 
-	// NOTE: This is an unsecure way of doing this. Ideally these should be ARNS's to
-	// secrets in AWS secrets manager which the lambda should query at runtime using it's
-	// execution role that has permissions to read from secrets manager.
-	jwtSecretKey := ENV_VAR_PREFIX + "JWT_SECRET"
-	if config.jwt.Secret = os.Getenv(jwtSecretKey); config.jwt.Secret == "" {
-		return config, fmt.Errorf("required env var '%s' was missing", jwtSecretKey)
-	}
-	jwtAudienceKey := ENV_VAR_PREFIX + "JWT_AUDIENCE"
-	if config.jwt.Audience = os.Getenv(jwtAudienceKey); config.jwt.Audience == "" {
-		return config, fmt.Errorf("required env var '%s' was missing", jwtAudienceKey)
+// Builds the Publisher selected in the config, or nil when events aren't published
+func buildPublisher(config appconfig.Config) (publish.Publisher, error) {
+	switch config.Publish.Broker {
+	case publish.BrokerRabbitMq:
+		return publish.NewRabbitMqPublisher(publish.RabbitMqOptions{
+			Host: config.RabbitMQ.Host,
+			Port: config.RabbitMQ.Port,
+			Username: config.RabbitMQ.Username,
+			Password: config.RabbitMQ.Password,
+			QueueName: config.Publish.Channel,
+		})
+	case publish.BrokerRedis:
+		return publish.NewRedisPublisher(publish.RedisOptions{
+			Addr: fmt.Sprintf("%s:%d", config.Redis.Host, config.Redis.Port),
+			Channel: config.Publish.Channel,
+		}), nil
 	}
-	jwtIssuerKey := ENV_VAR_PREFIX + "JWT_ISSUER"
-	if config.jwt.Issuer = os.Getenv(jwtIssuerKey); config.jwt.Issuer == "" {
-		return config, fmt.Errorf("required env var '%s' was missing", jwtIssuerKey)
-	}
-
-	return config, nil
+	return nil, nil
 }
 
-// Builds an error response for returning in the lambda response.
-func buildErrorResponse(statusCode int, errMsg string) events.ALBTargetGroupResponse {
-	return events.ALBTargetGroupResponse{
-		StatusCode: statusCode,
-		Headers: map[string]string{
-			"Content-Type": "text/plain",
-		},
-		Body: errMsg,
-		IsBase64Encoded: false,
+// Handles requests from an ALB, API Gateway REST or HTTP API, or a Function URL with
+// the same routes as the container. The response is in the shape expected by
+// whichever sent the event
+func HandleRequest(ctx context.Context, event json.RawMessage) (interface{}, error) {
+
+	logger.Debugf("Event as json: %s", string(event))
+
+	if apiCache == nil {
+		api, err := buildHandler(ctx)
+		if err != nil {
+			// Not cached so that the next invocation tries again
+			logger.Error(err.Error())
+			return adapter.ServeEvent(ctx, errorHandler(err.Error()), event)
+		}
+		apiCache = api
 	}
+	return adapter.ServeEvent(ctx, apiCache, event)
 }
 
-func HandleRequest(ctx context.Context, event events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
-
-	logger := log.New()
-	logger.SetLevel(log.InfoLevel)
-	logger.SetOutput(os.Stdout)
-	logger.SetFormatter(logfmt.NewUtcLogFormatter())
+// Responds to every request with an internal error
+func errorHandler(errMsg string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.WriteProblem(w, handler.NewProblem(handler.ProblemInternalError, errMsg))
+	})
+}
 
-	jsonEvent, _ := json.Marshal(event)
-	logger.Debugf("Event as json: %s", string(jsonEvent))
+// Builds the API from the config in the environment variables
+func buildHandler(ctx context.Context) (http.Handler, error) {
 
-	config, err := loadLambdaConfig()
+	config, err := appconfig.LoadLambda()
 	if err != nil {
-		return buildErrorResponse(http.StatusInternalServerError, fmt.Sprintf("Couldn't load config. Error: %s", err.Error())), nil
+		return nil, fmt.Errorf("Couldn't load config. Error: %w", err)
 	}
-	logger.Debugf("Loaded config: %+v", config)
+	logger.Info("Loaded config: " + config.String())
 
-	// Adapt the lambda event to an http.Request
-	httpRequest, err := adapter.ALBTargetGroupRequestEventToHttpRequest(&event)
+	// Requires secretsmanager:GetSecretValue on the execution role when any are ARNs
+	if err := config.ResolveSecrets(ctx, secret.NewResolver(secret.NewSecretsManagerSource())); err != nil {
+		return nil, fmt.Errorf("Couldn't resolve secrets. Error: %w", err)
+	}
+
+	quizStore, err := buildQuizStore(ctx, config)
 	if err != nil {
-		return buildErrorResponse(http.StatusInternalServerError, fmt.Sprintf("Couldn't adapt ALB request to http request. Error: %s", err.Error())), nil
+		return nil, fmt.Errorf("Couldn't build quiz store. Error: %w", err)
 	}
-	logger.Debugf("Adapted ALBTargetGroupRequest: %+v\nTo http.Request: %+v", event, httpRequest)
 
-	httpResponseWriter := adapter.NewHttpResponseWriterToALBTargetGroupResponse()
+	jwtParams := auth.JwtParams{
+		Secret: config.Jwt.Secret,
+		Issuer: config.Jwt.Issuer,
+		Audience: config.Jwt.Audience,
+		Policy: auth.ClaimsPolicy{
+			RequireExpiry: config.Jwt.RequireExpiry,
+			MaxAge: config.Jwt.MaxAge,
+			Leeway: config.Jwt.Leeway,
+		},
+	}
+	if config.Jwt.Jwks != "" {
+		if jwtParams.Keys, err = auth.NewJwks(config.Jwt.Jwks); err != nil {
+			return nil, fmt.Errorf("Couldn't load JWKS. Error: %w", err)
+		}
+	}
 
-	upload := handler.Upload {
-		DevelopmentMode: false,
-		QuizWriter: quiz.QuizJsonFileWriter{SaveDirectory: config.quizFileDirectory},
-		JwtParams: config.jwt,
-		Logger: logger,
+	switch {
+	case config.Revocation.Store == auth.RevocationStoreRedis:
+		jwtParams.Revocations = auth.NewRedisRevocationList(auth.RedisRevocationOptions{
+			Addr: fmt.Sprintf("%s:%d", config.Redis.Host, config.Redis.Port),
+		})
+	case config.Revocation.File != "":
+		jwtParams.Revocations = auth.NewFileRevocationList(config.Revocation.File)
 	}
 
-	// Process the request
-	upload.Quiz(&httpResponseWriter, httpRequest)
+	// The buckets are always in redis since each execution environment would otherwise
+	// only count the requests it handled
+	rateLimits := handler.RateLimits{
+		Client: ratelimit.PerMinute(config.RateLimit.ClientPerMinute, config.RateLimit.ClientBurst),
+		Quiz: ratelimit.PerMinute(config.RateLimit.QuizPerMinute, config.RateLimit.QuizBurst),
+		TrustForwardedFor: config.RateLimit.TrustForwardedFor,
+	}
+	if rateLimits.Client.Enabled() || rateLimits.Quiz.Enabled() {
+		rateLimits.Limiter = ratelimit.NewRedisLimiter(ratelimit.RedisOptions{
+			Addr: fmt.Sprintf("%s:%d", config.Redis.Host, config.Redis.Port),
+		})
+	} else {
+		logger.Warn("No rate limits are set so requests aren't throttled")
+	}
 
-	// Adapt the http.ResponseWriter to ALBTargetGroupResponse
-	albTargetGroupResponse, err := httpResponseWriter.AsALBTargetGroupResponse()
+	// In memory, keys are only recognised by requests to the same warm execution environment
+	idempotencyOptions := handler.Idempotency{TTL: config.Idempotency.TTL}
+	if config.Idempotency.Store == idempotency.StoreRedis {
+		idempotencyOptions.Store = idempotency.NewRedisStore(idempotency.RedisOptions{
+			Addr: fmt.Sprintf("%s:%d", config.Redis.Host, config.Redis.Port),
+		})
+	} else {
+		idempotencyOptions.Store = idempotency.NewMemoryStore()
+	}
+
+	publisher, err := buildPublisher(config)
 	if err != nil {
-		return buildErrorResponse(http.StatusInternalServerError, fmt.Sprintf("Couldn't adapt http.ResponseWriter to ALBTargetGroupResponse. Error: %s", err.Error())), fmt.Errorf("why doint i see this????")
+		return nil, fmt.Errorf("Couldn't build publisher. Error: %w", err)
+	}
+
+	corsOptions := handler.CorsOptions{
+		AllowedOrigins: config.Cors.AllowedOrigins,
+		AllowedMethods: config.Cors.AllowedMethods,
+		AllowedHeaders: config.Cors.AllowedHeaders,
+		AllowCredentials: config.Cors.AllowCredentials,
+		MaxAge: config.Cors.MaxAge,
+	}
+
+	upload := handler.Upload {
+		QuizStore: quizStore,
+		JwtParams: jwtParams,
+		RateLimits: rateLimits,
+		Idempotency: idempotencyOptions,
+		QuizLibrary: quiz.QuizLibrary{Directory: config.Loader.LibraryDirectory},
+		Publisher: publisher,
+		Logger: logger,
 	}
 
-	return albTargetGroupResponse, nil
+	return handler.NewServeMux(&upload, corsOptions, logger), nil
 }
 
 func main() {
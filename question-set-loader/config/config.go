@@ -1,93 +1,384 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"math"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/auth"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/idempotency"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/publish"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/quiz"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/ratelimit"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/secret"
 	"github.com/spf13/viper"
 )
 
+// Methods and headers the CORS policy allows on the quiz endpoint by default
+var DefaultCorsMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}
+var DefaultCorsHeaders = []string{"Authorization", "Content-Type", "If-Match", "If-None-Match", "Idempotency-Key"}
+
 type Config struct {
 	Server struct {
 		Port int
 		Development bool
+		ShutdownTimeout time.Duration
+	}
+	Cors struct {
+		AllowedOrigins		[]string
+		AllowedMethods		[]string
+		AllowedHeaders		[]string
+		AllowCredentials	bool
+		MaxAge				time.Duration
 	}
 	Jwt struct {
 		Secret	string
 		Issuer	string
 		Audience string
+		Jwks	string
+		RequireExpiry	bool
+		MaxAge	time.Duration
+		Leeway	time.Duration
+	}
+	Revocation struct {
+		Store	string
+		File	string
+	}
+	RateLimit struct {
+		Store				string
+		ClientPerMinute		int
+		ClientBurst			int
+		QuizPerMinute		int
+		QuizBurst			int
+		TrustForwardedFor	bool
+	}
+	Idempotency struct {
+		Store	string
+		TTL		time.Duration
+	}
+	Redis struct {
+		Host	string
+		Port	int
+	}
+	Publish struct {
+		Broker	string
+		Channel	string
+	}
+	RabbitMQ struct {
+		Host		string
+		Port		int
+		Username	string
+		Password	string
+	}
+	Audit struct {
+		File			string
+		OperatorToken	string
 	}
 	Loader struct {
+		Store string
 		DestinationDirectory string
+		LibraryDirectory string
+	}
+	S3 struct {
+		Endpoint			string
+		Region				string
+		Bucket				string
+		Prefix				string
+		AccessKeyID			string
+		SecretAccessKey		string
 	}
 }
 
-type missing string
-
-type missingConfigError struct {
-	key string
-}
-
-func (e *missingConfigError) Error() string {
-	return fmt.Sprintf("config item '%s' was not set", e.key)
+// Config file formats by the file's extension
+var formats = map[string]string{
+	".ini": "ini",
+	".yaml": "yaml",
+	".yml": "yaml",
+	".toml": "toml",
 }
 
-// Loads the config from the path specified and applys any overrides
-// from environment variables. Absent settings return an error
+// Loads the config from the file at path, if there is one, and applys any overrides
+// from environment variables. When path is empty the config comes from environment
+// variables alone. Every absent or invalid setting is returned in ValidationErrors
 func Load(path string) (Config, error) {
+	if path == "" {
+		return loadFromReader(nil, "")
+	}
+	format, ok := formats[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return Config{}, fmt.Errorf("config file '%s' must be .ini, .yaml, .yml or .toml", path)
+	}
 	file, err := os.Open(path)
 	if err != nil {
 		return Config{}, fmt.Errorf("failed to open config file '%s': %v", path, err)
 	}
-	return loadFromReader(file)
+	defer file.Close()
+	return loadFromReader(file, format)
+}
+
+// Environment variables paired with the fields in config
+var envVars = map[string]string{
+	"server.port": "PORT",
+	"server.development": "DEVELOPMENT_MODE",
+	"server.shutdown_timeout": "SHUTDOWN_TIMEOUT",
+	"cors.allowed_origins": "CORS_ALLOWED_ORIGINS",
+	"cors.allowed_methods": "CORS_ALLOWED_METHODS",
+	"cors.allowed_headers": "CORS_ALLOWED_HEADERS",
+	"cors.allow_credentials": "CORS_ALLOW_CREDENTIALS",
+	"cors.max_age": "CORS_MAX_AGE",
+	"jwt.secret": "JWT_SECRET",
+	"jwt.issuer": "JWT_ISSUER",
+	"jwt.audience": "JWT_AUDIENCE",
+	"jwt.jwks": "JWT_JWKS",
+	"jwt.require_expiry": "JWT_REQUIRE_EXPIRY",
+	"jwt.max_age": "JWT_MAX_AGE",
+	"jwt.leeway": "JWT_LEEWAY",
+	"revocation.store": "REVOCATION_STORE",
+	"revocation.file": "REVOCATION_FILE",
+	"ratelimit.store": "RATELIMIT_STORE",
+	"ratelimit.client_per_minute": "RATELIMIT_CLIENT_PER_MINUTE",
+	"ratelimit.client_burst": "RATELIMIT_CLIENT_BURST",
+	"ratelimit.quiz_per_minute": "RATELIMIT_QUIZ_PER_MINUTE",
+	"ratelimit.quiz_burst": "RATELIMIT_QUIZ_BURST",
+	"ratelimit.trust_forwarded_for": "RATELIMIT_TRUST_FORWARDED_FOR",
+	"idempotency.store": "IDEMPOTENCY_STORE",
+	"idempotency.ttl": "IDEMPOTENCY_TTL",
+	"redis.host": "REDIS_HOST",
+	"redis.port": "REDIS_PORT",
+	"publish.broker": "PUBLISH_BROKER",
+	"publish.channel": "PUBLISH_CHANNEL",
+	"rabbit-mq.host": "RABBITMQ_HOST",
+	"rabbit-mq.port": "RABBITMQ_PORT",
+	"rabbit-mq.username": "RABBITMQ_USERNAME",
+	"rabbit-mq.password": "RABBITMQ_PASSWORD",
+	"audit.file": "AUDIT_FILE",
+	"audit.operator_token": "AUDIT_OPERATOR_TOKEN",
+	"loader.store": "LOADER_STORE",
+	"loader.destination_directory": "LOADER_DST_DIR",
+	"loader.library_directory": "LOADER_LIBRARY_DIR",
+	"s3.endpoint": "S3_ENDPOINT",
+	"s3.region": "S3_REGION",
+	"s3.bucket": "S3_BUCKET",
+	"s3.prefix": "S3_PREFIX",
+	"s3.access_key_id": "S3_ACCESS_KEY_ID",
+	"s3.secret_access_key": "S3_SECRET_ACCESS_KEY",
+}
+
+// Prefix of the Lambda's environment variables e.g. MC_SPEEDRUN_JWT_SECRET
+const LambdaEnvPrefix = "MC_SPEEDRUN_"
+
+// Loads the Lambda's config from environment variables named like the container's
+// with LambdaEnvPrefix. It has no [server] settings, its rate limits are always kept
+// in redis and the audit log is refused. Every absent or invalid setting is returned
+// in ValidationErrors, named by its environment variable
+func LoadLambda() (Config, error) {
+	return load(nil, "", true)
+}
+
+// Loads the container's config from reader in format, or from environment variables
+// alone when reader is nil
+func loadFromReader(reader io.Reader, format string) (Config, error) {
+	return load(reader, format, false)
 }
 
-func loadFromReader(reader io.Reader) (Config, error) {
-	viper.SetConfigType("ini")
+// Loads the config for the container, or for the Lambda when lambda is set
+func load(reader io.Reader, format string, lambda bool) (Config, error) {
+	v := viper.New()
 
 	// Pair up env vars with the fields in config
-	viper.BindEnv("server.port", "PORT")
-	viper.BindEnv("server.development", "DEVELOPMENT_MODE")
-	viper.BindEnv("jwt.secret", "JWT_SECRET")
-	viper.BindEnv("jwt.issuer", "JWT_ISSUER")
-	viper.BindEnv("jwt.audience", "JWT_AUDIENCE")
-	viper.BindEnv("loader.destination_directory", "LOADER_DST_DIR")
+	envPrefix := ""
+	if lambda {
+		envPrefix = LambdaEnvPrefix
+	}
+	for key, envVar := range envVars {
+		v.BindEnv(key, envPrefix + envVar)
+	}
+
 
 	// Set add fields to be required
 	var missingFlag missing
-	viper.SetDefault("server.port", missingFlag)
-	viper.SetDefault("server.development", missingFlag)
-	viper.SetDefault("jwt.secret", missingFlag)
-	viper.SetDefault("jwt.issuer", missingFlag)
-	viper.SetDefault("jwt.audience", missingFlag)
-	viper.SetDefault("loader.destination_directory", missingFlag)
+	v.SetDefault("server.port", missingFlag)
+	v.SetDefault("server.development", missingFlag)
+	v.SetDefault("jwt.secret", missingFlag)
+	v.SetDefault("jwt.issuer", missingFlag)
+	v.SetDefault("jwt.audience", missingFlag)
+	v.SetDefault("loader.destination_directory", missingFlag)
 
-	loadedConfig := Config{}
-	
-	if err := viper.ReadConfig(reader); err != nil {
-		return loadedConfig, err
+	// Optional fields
+	v.SetDefault("server.shutdown_timeout", "30s")
+	v.SetDefault("cors.allowed_origins", "")
+	v.SetDefault("cors.allowed_methods", strings.Join(DefaultCorsMethods, ","))
+	v.SetDefault("cors.allowed_headers", strings.Join(DefaultCorsHeaders, ","))
+	v.SetDefault("cors.allow_credentials", false)
+	v.SetDefault("cors.max_age", 0)
+	v.SetDefault("jwt.jwks", "")
+	v.SetDefault("jwt.require_expiry", false)
+	v.SetDefault("jwt.max_age", 0)
+	v.SetDefault("jwt.leeway", 0)
+	v.SetDefault("revocation.store", auth.RevocationStoreFile)
+	v.SetDefault("revocation.file", "")
+	// Each execution environment would only count the requests it handled
+	if lambda {
+		v.SetDefault("ratelimit.store", ratelimit.StoreRedis)
+	} else {
+		v.SetDefault("ratelimit.store", ratelimit.StoreMemory)
 	}
+	v.SetDefault("ratelimit.client_per_minute", 0)
+	v.SetDefault("ratelimit.client_burst", 0)
+	v.SetDefault("ratelimit.quiz_per_minute", 0)
+	v.SetDefault("ratelimit.quiz_burst", 0)
+	v.SetDefault("ratelimit.trust_forwarded_for", false)
+	v.SetDefault("idempotency.store", idempotency.StoreMemory)
+	v.SetDefault("idempotency.ttl", idempotency.DefaultTTL.String())
+	v.SetDefault("redis.host", missingFlag)
+	v.SetDefault("redis.port", missingFlag)
+	v.SetDefault("publish.broker", publish.BrokerNone)
+	v.SetDefault("publish.channel", publish.DefaultChannel)
+	v.SetDefault("rabbit-mq.host", missingFlag)
+	v.SetDefault("rabbit-mq.port", missingFlag)
+	v.SetDefault("rabbit-mq.username", missingFlag)
+	v.SetDefault("rabbit-mq.password", missingFlag)
+	v.SetDefault("audit.file", "")
+	v.SetDefault("audit.operator_token", "")
+	v.SetDefault("loader.store", quiz.StoreFile)
+	v.SetDefault("loader.library_directory", "")
+	v.SetDefault("s3.endpoint", "")
+	v.SetDefault("s3.region", "")
+	v.SetDefault("s3.bucket", "")
+	v.SetDefault("s3.prefix", "")
+	v.SetDefault("s3.access_key_id", "")
+	v.SetDefault("s3.secret_access_key", "")
 
-	// Validate that there were no missing required keys
-	keys := []string {
-		"server.port", "server.development",
-		"jwt.secret", "jwt.issuer", "jwt.audience",
-		"loader.destination_directory",
-	}
-	for _, key := range keys {
-		if _, ok := viper.Get(key).(missing); ok {
-			return loadedConfig, &missingConfigError{key}
+	loadedConfig := Config{}
+
+	if reader != nil {
+		v.SetConfigType(format)
+		if err := v.ReadConfig(reader); err != nil {
+			return loadedConfig, fmt.Errorf("failed to read config: %w", err)
 		}
 	}
 
-	// Everythings present and been validated - pull it out
-	loadedConfig.Server.Port					= viper.GetInt("server.port")
-	loadedConfig.Server.Development				= viper.GetBool("server.development")
-	loadedConfig.Jwt.Secret						= viper.GetString("jwt.secret")
-	loadedConfig.Jwt.Issuer						= viper.GetString("jwt.issuer")
-	loadedConfig.Jwt.Audience					= viper.GetString("jwt.audience")
-	loadedConfig.Loader.DestinationDirectory 	= viper.GetString("loader.destination_directory")
+	// Pull out every setting, checking its type and range as it goes
+	r := &valueReader{v: v}
+	if lambda {
+		r.envVars = make(map[string]string, len(envVars))
+		for key, envVar := range envVars {
+			r.envVars[key] = envPrefix + envVar
+		}
+	} else {
+		// The Lambda is invoked rather than listening so it has no server settings
+		loadedConfig.Server.Port				= r.port("server.port")
+		loadedConfig.Server.Development			= r.bool("server.development")
+		loadedConfig.Server.ShutdownTimeout		= r.duration("server.shutdown_timeout")
+	}
+	loadedConfig.Cors.AllowedOrigins			= r.list("cors.allowed_origins")
+	loadedConfig.Cors.AllowedMethods			= r.list("cors.allowed_methods")
+	loadedConfig.Cors.AllowedHeaders			= r.list("cors.allowed_headers")
+	loadedConfig.Cors.AllowCredentials			= r.bool("cors.allow_credentials")
+	if loadedConfig.Cors.AllowCredentials && AllowsAnyOrigin(loadedConfig.Cors.AllowedOrigins) {
+		r.invalid("cors.allowed_origins", "can't contain '*' when '%s' is true", r.name("cors.allow_credentials"))
+	}
+	loadedConfig.Cors.MaxAge					= r.duration("cors.max_age")
+	loadedConfig.Jwt.Jwks						= r.string("jwt.jwks")
+	// The secret is only needed for HMAC tokens when there's no JWKS
+	if loadedConfig.Jwt.Jwks == "" {
+		loadedConfig.Jwt.Secret					= r.nonEmptyString("jwt.secret")
+	} else if secret, ok := v.Get("jwt.secret").(string); ok {
+		loadedConfig.Jwt.Secret					= secret
+	}
+	loadedConfig.Jwt.Issuer						= r.nonEmptyString("jwt.issuer")
+	loadedConfig.Jwt.Audience					= r.nonEmptyString("jwt.audience")
+	loadedConfig.Jwt.RequireExpiry				= r.bool("jwt.require_expiry")
+	loadedConfig.Jwt.MaxAge						= r.duration("jwt.max_age")
+	loadedConfig.Jwt.Leeway						= r.duration("jwt.leeway")
+	loadedConfig.Revocation.Store				= r.oneOf("revocation.store", auth.RevocationStoreFile, auth.RevocationStoreRedis)
+	loadedConfig.Revocation.File				= r.string("revocation.file")
+	loadedConfig.Loader.Store					= r.oneOf("loader.store", quiz.StoreFile, quiz.StoreS3)
+	// The destination directory is only needed when writing to the filesystem
+	if loadedConfig.Loader.Store == quiz.StoreFile {
+		loadedConfig.Loader.DestinationDirectory = r.nonEmptyString("loader.destination_directory")
+	}
+	loadedConfig.Loader.LibraryDirectory		= r.string("loader.library_directory")
+	loadedConfig.S3.Endpoint					= r.string("s3.endpoint")
+	loadedConfig.S3.Region						= r.string("s3.region")
+	if loadedConfig.Loader.Store == quiz.StoreS3 {
+		loadedConfig.S3.Bucket					= r.nonEmptyString("s3.bucket")
+	}
+	loadedConfig.S3.Prefix						= r.string("s3.prefix")
+	loadedConfig.S3.AccessKeyID					= r.string("s3.access_key_id")
+	loadedConfig.S3.SecretAccessKey				= r.string("s3.secret_access_key")
+	if lambda {
+		loadedConfig.RateLimit.Store			= r.oneOf("ratelimit.store", ratelimit.StoreRedis)
+	} else {
+		loadedConfig.RateLimit.Store			= r.oneOf("ratelimit.store", ratelimit.StoreMemory, ratelimit.StoreRedis)
+	}
+	loadedConfig.RateLimit.ClientPerMinute		= r.int("ratelimit.client_per_minute", 0, math.MaxInt32)
+	loadedConfig.RateLimit.ClientBurst			= r.int("ratelimit.client_burst", 0, math.MaxInt32)
+	loadedConfig.RateLimit.QuizPerMinute		= r.int("ratelimit.quiz_per_minute", 0, math.MaxInt32)
+	loadedConfig.RateLimit.QuizBurst			= r.int("ratelimit.quiz_burst", 0, math.MaxInt32)
+	loadedConfig.RateLimit.TrustForwardedFor	= r.bool("ratelimit.trust_forwarded_for")
+	loadedConfig.Idempotency.Store				= r.oneOf("idempotency.store", idempotency.StoreMemory, idempotency.StoreRedis)
+	loadedConfig.Idempotency.TTL				= r.duration("idempotency.ttl")
+	loadedConfig.Publish.Broker					= r.oneOf("publish.broker", publish.BrokerNone, publish.BrokerRabbitMq, publish.BrokerRedis)
+	if loadedConfig.Publish.Broker != publish.BrokerNone {
+		loadedConfig.Publish.Channel			= r.nonEmptyString("publish.channel")
+	}
+	// The Lambda's rate limits are only kept in redis when there are some
+	rateLimitsInRedis := loadedConfig.RateLimit.Store == ratelimit.StoreRedis
+	if lambda {
+		rateLimitsInRedis = loadedConfig.RateLimit.ClientPerMinute > 0 || loadedConfig.RateLimit.QuizPerMinute > 0
+	}
+	// Redis is only needed when it holds the revocations, rate limits or idempotency keys, or events are published to it
+	if loadedConfig.Revocation.Store == auth.RevocationStoreRedis || rateLimitsInRedis ||
+			loadedConfig.Idempotency.Store == idempotency.StoreRedis || loadedConfig.Publish.Broker == publish.BrokerRedis {
+		loadedConfig.Redis.Host					= r.nonEmptyString("redis.host")
+		loadedConfig.Redis.Port					= r.port("redis.port")
+	}
+	if loadedConfig.Publish.Broker == publish.BrokerRabbitMq {
+		loadedConfig.RabbitMQ.Host				= r.nonEmptyString("rabbit-mq.host")
+		loadedConfig.RabbitMQ.Port				= r.port("rabbit-mq.port")
+		loadedConfig.RabbitMQ.Username			= r.nonEmptyString("rabbit-mq.username")
+		loadedConfig.RabbitMQ.Password			= r.nonEmptyString("rabbit-mq.password")
+	}
+	loadedConfig.Audit.File						= r.string("audit.file")
+	// A file in a Lambda execution environment wouldn't outlive it or be seen by the
+	// others, so it's refused rather than silently losing entries
+	if lambda && loadedConfig.Audit.File != "" {
+		r.invalid("audit.file", "isn't supported by the Lambda. The audit log is only kept by the container")
+	}
+	loadedConfig.Audit.OperatorToken			= r.string("audit.operator_token")
 
+	if len(r.errs) > 0 {
+		return Config{}, r.errs
+	}
 	return loadedConfig, nil
-}
\ No newline at end of file
+}
+
+// Returns a copy of the config with the secrets hidden. References to secrets, such as
+// "file:///run/secrets/jwt_secret", are left as they're safe to show
+func (c Config) Redacted() Config {
+	c.Jwt.Secret = Redact(c.Jwt.Secret)
+	c.S3.AccessKeyID = Redact(c.S3.AccessKeyID)
+	c.S3.SecretAccessKey = Redact(c.S3.SecretAccessKey)
+	c.RabbitMQ.Password = Redact(c.RabbitMQ.Password)
+	c.Audit.OperatorToken = Redact(c.Audit.OperatorToken)
+	return c
+}
+
+// Formats the effective config with the secrets redacted so that it can be logged
+func (c Config) String() string {
+	type plain Config // Without this method so that formatting doesn't recurse
+	return fmt.Sprintf("%+v", plain(c.Redacted()))
+}
+
+// Replaces the credentials that reference a secret e.g. "file:///run/secrets/jwt_secret"
+// with the secret
+func (c *Config) ResolveSecrets(ctx context.Context, resolver *secret.Resolver) error {
+	return resolver.ResolveAll(ctx, &c.Jwt.Secret, &c.S3.AccessKeyID, &c.S3.SecretAccessKey, &c.RabbitMQ.Username, &c.RabbitMQ.Password, &c.Audit.OperatorToken)
+}
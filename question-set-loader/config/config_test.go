@@ -2,11 +2,22 @@ package config
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/auth"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/idempotency"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/publish"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/quiz"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/ratelimit"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/secret"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -164,26 +175,28 @@ func TestLoadFromReader_env_vars_override(t *testing.T) {
 	})
 
 	// Act
-	got, err := loadFromReader(reader)
+	got, err := loadFromReader(reader, "ini")
 	if err != nil {
 		t.Fatalf("Failed to load config with: %v", err)
 	}
 
 	// Assert
-	want := Config{
-		Server: struct{Port int; Development bool}{
-			Port: serverPort,
-			Development: serverDevelopment,
-		},
-		Jwt: struct{Secret string; Issuer string; Audience string}{
-			Secret: jwtSecret,
-			Issuer: jwtIssuer,
-			Audience: jwtAudience,
-		},
-		Loader: struct{DestinationDirectory string}{
-			DestinationDirectory: loaderDestinationDirectory,
-		},
-	}
+	want := Config{}
+	want.Cors.AllowedMethods = DefaultCorsMethods
+	want.Cors.AllowedHeaders = DefaultCorsHeaders
+	want.Server.Port = serverPort
+	want.Server.Development = serverDevelopment
+	want.Server.ShutdownTimeout = 30 * time.Second
+	want.Jwt.Secret = jwtSecret
+	want.Jwt.Issuer = jwtIssuer
+	want.Jwt.Audience = jwtAudience
+	want.Revocation.Store = auth.RevocationStoreFile
+	want.Publish.Broker = publish.BrokerNone
+	want.RateLimit.Store = ratelimit.StoreMemory
+	want.Idempotency.Store = idempotency.StoreMemory
+	want.Idempotency.TTL = idempotency.DefaultTTL
+	want.Loader.Store = quiz.StoreFile
+	want.Loader.DestinationDirectory = loaderDestinationDirectory
 	if diff := cmp.Diff(want, got); diff != "" {
 		t.Fatal("Wrong config loaded: ", diff)
 	}
@@ -216,26 +229,28 @@ func TestLoadFromReader_no_env_vars(t *testing.T) {
 	})
 
 	// Act
-	got, err := loadFromReader(reader)
+	got, err := loadFromReader(reader, "ini")
 	if err != nil {
 		t.Fatalf("Failed to load config with: %v", err)
 	}
 
 	// Assert
-	want := Config{
-		Server: struct{Port int; Development bool}{
-			Port: serverPort,
-			Development: serverDevelopment,
-		},
-		Jwt: struct{Secret string; Issuer string; Audience string}{
-			Secret: jwtSecret,
-			Issuer: jwtIssuer,
-			Audience: jwtAudience,
-		},
-		Loader: struct{DestinationDirectory string}{
-			DestinationDirectory: loaderDestinationDirectory,
-		},
-	}
+	want := Config{}
+	want.Cors.AllowedMethods = DefaultCorsMethods
+	want.Cors.AllowedHeaders = DefaultCorsHeaders
+	want.Server.Port = serverPort
+	want.Server.Development = serverDevelopment
+	want.Server.ShutdownTimeout = 30 * time.Second
+	want.Jwt.Secret = jwtSecret
+	want.Jwt.Issuer = jwtIssuer
+	want.Jwt.Audience = jwtAudience
+	want.Revocation.Store = auth.RevocationStoreFile
+	want.Publish.Broker = publish.BrokerNone
+	want.RateLimit.Store = ratelimit.StoreMemory
+	want.Idempotency.Store = idempotency.StoreMemory
+	want.Idempotency.TTL = idempotency.DefaultTTL
+	want.Loader.Store = quiz.StoreFile
+	want.Loader.DestinationDirectory = loaderDestinationDirectory
 	if diff := cmp.Diff(want, got); diff != "" {
 		t.Fatal("Wrong config loaded: ", diff)
 	}
@@ -270,7 +285,7 @@ func TestLoadFromReader_absent_required_keys(t *testing.T) {
 			reader := buildConfigReader(config)
 			
 			// Act
-			_, got := loadFromReader(reader)
+			_, got := loadFromReader(reader, "ini")
 			
 			// Assert
 			if got == nil {
@@ -284,3 +299,785 @@ func TestLoadFromReader_absent_required_keys(t *testing.T) {
 		})
 	}
 }
+
+// Tests loading with the S3 store where the destination directory isn't needed
+func TestLoadFromReader_s3_store(t *testing.T) {
+	reader := strings.NewReader(`
+[server]
+port = 8082
+development = false
+
+[jwt]
+secret = secret
+issuer = issuer
+audience = audience
+
+[loader]
+store = s3
+
+[s3]
+endpoint = http://localhost:9000
+region = us-east-2
+bucket = question-sets
+prefix = uploads
+access_key_id = minio
+secret_access_key = minio123
+`)
+
+	got, err := loadFromReader(reader, "ini")
+	if err != nil {
+		t.Fatalf("Failed to load config with: %v", err)
+	}
+
+	want := Config{}
+	want.Cors.AllowedMethods = DefaultCorsMethods
+	want.Cors.AllowedHeaders = DefaultCorsHeaders
+	want.Server.Port = 8082
+	want.Server.ShutdownTimeout = 30 * time.Second
+	want.Jwt.Secret = "secret"
+	want.Jwt.Issuer = "issuer"
+	want.Jwt.Audience = "audience"
+	want.Revocation.Store = auth.RevocationStoreFile
+	want.Publish.Broker = publish.BrokerNone
+	want.RateLimit.Store = ratelimit.StoreMemory
+	want.Idempotency.Store = idempotency.StoreMemory
+	want.Idempotency.TTL = idempotency.DefaultTTL
+	want.Loader.Store = quiz.StoreS3
+	want.S3.Endpoint = "http://localhost:9000"
+	want.S3.Region = "us-east-2"
+	want.S3.Bucket = "question-sets"
+	want.S3.Prefix = "uploads"
+	want.S3.AccessKeyID = "minio"
+	want.S3.SecretAccessKey = "minio123"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal("Wrong config loaded: ", diff)
+	}
+}
+
+// Tests loading with the S3 store and no bucket
+func TestLoadFromReader_s3_store_absent_bucket(t *testing.T) {
+	reader := strings.NewReader(`
+[server]
+port = 8082
+development = false
+
+[jwt]
+secret = secret
+issuer = issuer
+audience = audience
+
+[loader]
+store = s3
+`)
+
+	_, got := loadFromReader(reader, "ini")
+	if got == nil {
+		t.Fatalf("Failed to detect error")
+	}
+
+	want := (&missingConfigError{key: "s3.bucket"}).Error()
+	if diff := cmp.Diff(want, got.Error()); diff != "" {
+		t.Error("Wrong error received", diff)
+	}
+}
+
+// Tests that the secret isn't required when tokens are verified with a JWKS
+func TestLoadFromReader_jwks_without_secret(t *testing.T) {
+	reader := strings.NewReader(`
+[server]
+port = 8082
+development = false
+
+[jwt]
+issuer = issuer
+audience = audience
+jwks = https://sign-on.example/.well-known/jwks.json
+
+[loader]
+destination_directory = /tmp/question-set-loader/
+`)
+
+	got, err := loadFromReader(reader, "ini")
+	if err != nil {
+		t.Fatalf("Failed to load config with: %v", err)
+	}
+
+	want := Config{}
+	want.Cors.AllowedMethods = DefaultCorsMethods
+	want.Cors.AllowedHeaders = DefaultCorsHeaders
+	want.Server.Port = 8082
+	want.Server.ShutdownTimeout = 30 * time.Second
+	want.Jwt.Issuer = "issuer"
+	want.Jwt.Audience = "audience"
+	want.Jwt.Jwks = "https://sign-on.example/.well-known/jwks.json"
+	want.Revocation.Store = auth.RevocationStoreFile
+	want.Publish.Broker = publish.BrokerNone
+	want.RateLimit.Store = ratelimit.StoreMemory
+	want.Idempotency.Store = idempotency.StoreMemory
+	want.Idempotency.TTL = idempotency.DefaultTTL
+	want.Loader.Store = quiz.StoreFile
+	want.Loader.DestinationDirectory = "/tmp/question-set-loader/"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal("Wrong config loaded: ", diff)
+	}
+}
+
+// Tests loading the token policy and the redis revocation store
+func TestLoadFromReader_token_policy_and_redis_revocation(t *testing.T) {
+	reader := strings.NewReader(`
+[server]
+port = 8082
+development = false
+
+[jwt]
+secret = secret
+issuer = issuer
+audience = audience
+require_expiry = true
+max_age = 12h
+leeway = 30s
+
+[revocation]
+store = redis
+
+[redis]
+host = localhost
+port = 6379
+
+[loader]
+destination_directory = /tmp/question-set-loader/
+`)
+
+	got, err := loadFromReader(reader, "ini")
+	if err != nil {
+		t.Fatalf("Failed to load config with: %v", err)
+	}
+
+	want := Config{}
+	want.Cors.AllowedMethods = DefaultCorsMethods
+	want.Cors.AllowedHeaders = DefaultCorsHeaders
+	want.Server.Port = 8082
+	want.Server.ShutdownTimeout = 30 * time.Second
+	want.Jwt.Secret = "secret"
+	want.Jwt.Issuer = "issuer"
+	want.Jwt.Audience = "audience"
+	want.Jwt.RequireExpiry = true
+	want.Jwt.MaxAge = 12 * time.Hour
+	want.Jwt.Leeway = 30 * time.Second
+	want.Revocation.Store = auth.RevocationStoreRedis
+	want.Publish.Broker = publish.BrokerNone
+	want.RateLimit.Store = ratelimit.StoreMemory
+	want.Idempotency.Store = idempotency.StoreMemory
+	want.Idempotency.TTL = idempotency.DefaultTTL
+	want.Redis.Host = "localhost"
+	want.Redis.Port = 6379
+	want.Loader.Store = quiz.StoreFile
+	want.Loader.DestinationDirectory = "/tmp/question-set-loader/"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal("Wrong config loaded: ", diff)
+	}
+}
+
+// Tests loading with the redis revocation store and no redis
+func TestLoadFromReader_redis_revocation_absent_redis(t *testing.T) {
+	reader := strings.NewReader(`
+[server]
+port = 8082
+development = false
+
+[jwt]
+secret = secret
+issuer = issuer
+audience = audience
+
+[revocation]
+store = redis
+
+[loader]
+destination_directory = /tmp/question-set-loader/
+`)
+
+	_, got := loadFromReader(reader, "ini")
+	if got == nil {
+		t.Fatalf("Failed to detect error")
+	}
+
+	want := ValidationErrors{&missingConfigError{key: "redis.host"}, &missingConfigError{key: "redis.port"}}.Error()
+	if diff := cmp.Diff(want, got.Error()); diff != "" {
+		t.Error("Wrong error received", diff)
+	}
+}
+
+// Tests loading the CORS policy
+func TestLoadFromReader_cors(t *testing.T) {
+	reader := strings.NewReader(`
+[server]
+port = 8082
+development = false
+
+[cors]
+allowed_origins = https://quiz.example.com, https://*.staging.example.com
+allowed_methods = GET, POST
+allowed_headers = Authorization
+allow_credentials = true
+max_age = 10m
+
+[jwt]
+secret = secret
+issuer = issuer
+audience = audience
+
+[loader]
+destination_directory = /tmp/question-set-loader/
+`)
+
+	got, err := loadFromReader(reader, "ini")
+	if err != nil {
+		t.Fatalf("Failed to load config with: %v", err)
+	}
+
+	want := Config{}
+	want.Server.Port = 8082
+	want.Server.ShutdownTimeout = 30 * time.Second
+	want.Cors.AllowedOrigins = []string{"https://quiz.example.com", "https://*.staging.example.com"}
+	want.Cors.AllowedMethods = []string{"GET", "POST"}
+	want.Cors.AllowedHeaders = []string{"Authorization"}
+	want.Cors.AllowCredentials = true
+	want.Cors.MaxAge = 10 * time.Minute
+	want.Jwt.Secret = "secret"
+	want.Jwt.Issuer = "issuer"
+	want.Jwt.Audience = "audience"
+	want.Revocation.Store = auth.RevocationStoreFile
+	want.Publish.Broker = publish.BrokerNone
+	want.RateLimit.Store = ratelimit.StoreMemory
+	want.Idempotency.Store = idempotency.StoreMemory
+	want.Idempotency.TTL = idempotency.DefaultTTL
+	want.Loader.Store = quiz.StoreFile
+	want.Loader.DestinationDirectory = "/tmp/question-set-loader/"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal("Wrong config loaded: ", diff)
+	}
+}
+
+// Tests loading the rate limits with the redis store
+func TestLoadFromReader_rate_limits(t *testing.T) {
+	reader := strings.NewReader(`
+[server]
+port = 8082
+development = false
+
+[jwt]
+secret = secret
+issuer = issuer
+audience = audience
+
+[ratelimit]
+store = redis
+client_per_minute = 60
+client_burst = 20
+quiz_per_minute = 30
+quiz_burst = 10
+trust_forwarded_for = true
+
+[redis]
+host = localhost
+port = 6379
+
+[loader]
+destination_directory = /tmp/question-set-loader/
+`)
+
+	got, err := loadFromReader(reader, "ini")
+	if err != nil {
+		t.Fatalf("Failed to load config with: %v", err)
+	}
+
+	want := Config{}
+	want.Cors.AllowedMethods = DefaultCorsMethods
+	want.Cors.AllowedHeaders = DefaultCorsHeaders
+	want.Server.Port = 8082
+	want.Server.ShutdownTimeout = 30 * time.Second
+	want.Jwt.Secret = "secret"
+	want.Jwt.Issuer = "issuer"
+	want.Jwt.Audience = "audience"
+	want.Revocation.Store = auth.RevocationStoreFile
+	want.Publish.Broker = publish.BrokerNone
+	want.RateLimit.Store = ratelimit.StoreRedis
+	want.Idempotency.Store = idempotency.StoreMemory
+	want.Idempotency.TTL = idempotency.DefaultTTL
+	want.RateLimit.ClientPerMinute = 60
+	want.RateLimit.ClientBurst = 20
+	want.RateLimit.QuizPerMinute = 30
+	want.RateLimit.QuizBurst = 10
+	want.RateLimit.TrustForwardedFor = true
+	want.Redis.Host = "localhost"
+	want.Redis.Port = 6379
+	want.Loader.Store = quiz.StoreFile
+	want.Loader.DestinationDirectory = "/tmp/question-set-loader/"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal("Wrong config loaded: ", diff)
+	}
+}
+
+// Tests loading the store for idempotency keys, which needs redis when it's in redis
+func TestLoadFromReader_idempotency(t *testing.T) {
+	reader := strings.NewReader(`
+[server]
+port = 8082
+development = false
+
+[jwt]
+secret = secret
+issuer = issuer
+audience = audience
+
+[idempotency]
+store = redis
+ttl = 1h
+
+[loader]
+destination_directory = /tmp/question-set-loader/
+`)
+
+	_, got := loadFromReader(reader, "ini")
+	want := ValidationErrors{
+		&missingConfigError{"redis.host"},
+		&missingConfigError{"redis.port"},
+	}
+	if got == nil {
+		t.Fatalf("Failed to detect error")
+	}
+	if diff := cmp.Diff(want.Error(), got.Error()); diff != "" {
+		t.Error("Wrong error received", diff)
+	}
+
+	t.Setenv("REDIS_HOST", "localhost")
+	t.Setenv("REDIS_PORT", "6379")
+	reader.Seek(0, io.SeekStart)
+	config, err := loadFromReader(reader, "ini")
+	if err != nil {
+		t.Fatalf("Failed to load config with: %v", err)
+	}
+	if config.Idempotency.Store != idempotency.StoreRedis || config.Idempotency.TTL != time.Hour {
+		t.Errorf("Wrong idempotency config loaded: %+v", config.Idempotency)
+	}
+}
+
+// Tests loading the audit log, which is off unless there's a file for it
+func TestLoadFromReader_audit(t *testing.T) {
+	reader := strings.NewReader(`
+[server]
+port = 8082
+development = false
+
+[jwt]
+secret = secret
+issuer = issuer
+audience = audience
+
+[audit]
+file = /var/log/question-set-loader/audit.jsonl
+
+[loader]
+destination_directory = /tmp/question-set-loader/
+`)
+
+	t.Setenv("AUDIT_OPERATOR_TOKEN", "env://OPERATOR_TOKEN")
+	config, err := loadFromReader(reader, "ini")
+	if err != nil {
+		t.Fatalf("Failed to load config with: %v", err)
+	}
+	if config.Audit.File != "/var/log/question-set-loader/audit.jsonl" || config.Audit.OperatorToken != "env://OPERATOR_TOKEN" {
+		t.Errorf("Wrong audit config loaded: %+v", config.Audit)
+	}
+}
+
+// Tests loading the brokers the question set ready events are published to
+func TestLoadFromReader_publish(t *testing.T) {
+	base := `
+[server]
+port = 8082
+development = false
+
+[jwt]
+secret = secret
+issuer = issuer
+audience = audience
+
+[loader]
+destination_directory = /tmp/question-set-loader/
+`
+
+	tests := map[string]struct {
+		config		string
+		wantErr		string
+		want		func(c *Config)
+	}{
+		"rabbitmq": {
+			config: "[publish]\nbroker = rabbitmq\n\n[rabbit-mq]\nhost = localhost\nport = 5672\nusername = admin\npassword = passwd\n",
+			want: func(c *Config) {
+				c.Publish.Broker = publish.BrokerRabbitMq
+				c.Publish.Channel = publish.DefaultChannel
+				c.RabbitMQ.Host = "localhost"
+				c.RabbitMQ.Port = 5672
+				c.RabbitMQ.Username = "admin"
+				c.RabbitMQ.Password = "passwd"
+			},
+		},
+		"redis": {
+			config: "[publish]\nbroker = redis\nchannel = ready\n\n[redis]\nhost = localhost\nport = 6379\n",
+			want: func(c *Config) {
+				c.Publish.Broker = publish.BrokerRedis
+				c.Publish.Channel = "ready"
+				c.Redis.Host = "localhost"
+				c.Redis.Port = 6379
+			},
+		},
+		"rabbitmq absent": {
+			config: "[publish]\nbroker = rabbitmq\n",
+			wantErr: ValidationErrors{
+				&missingConfigError{"rabbit-mq.host"},
+				&missingConfigError{"rabbit-mq.port"},
+				&missingConfigError{"rabbit-mq.username"},
+				&missingConfigError{"rabbit-mq.password"},
+			}.Error(),
+		},
+		"redis absent": {
+			config: "[publish]\nbroker = redis\n",
+			wantErr: ValidationErrors{&missingConfigError{"redis.host"}, &missingConfigError{"redis.port"}}.Error(),
+		},
+		"unsupported broker": {
+			config: "[publish]\nbroker = kafka\n",
+			wantErr: (&invalidConfigError{"publish.broker", "has unsupported value 'kafka'"}).Error(),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := loadFromReader(strings.NewReader(base + test.config), "ini")
+			if test.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Failed to detect error")
+				}
+				if diff := cmp.Diff(test.wantErr, err.Error()); diff != "" {
+					t.Fatal("Wrong error received", diff)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to load config with: %v", err)
+			}
+
+			want := Config{}
+			want.Cors.AllowedMethods = DefaultCorsMethods
+			want.Cors.AllowedHeaders = DefaultCorsHeaders
+			want.Server.Port = 8082
+			want.Server.ShutdownTimeout = 30 * time.Second
+			want.Jwt.Secret = "secret"
+			want.Jwt.Issuer = "issuer"
+			want.Jwt.Audience = "audience"
+			want.Revocation.Store = auth.RevocationStoreFile
+			want.RateLimit.Store = ratelimit.StoreMemory
+			want.Idempotency.Store = idempotency.StoreMemory
+			want.Idempotency.TTL = idempotency.DefaultTTL
+			want.Loader.Store = quiz.StoreFile
+			want.Loader.DestinationDirectory = "/tmp/question-set-loader/"
+			test.want(&want)
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Fatal("Wrong config loaded: ", diff)
+			}
+		})
+	}
+}
+
+// Tests that every invalid and absent setting is reported at once
+func TestLoadFromReader_invalid_values(t *testing.T) {
+	reader := strings.NewReader(`
+[server]
+port = 70000
+development = maybe
+shutdown_timeout = soon
+
+[cors]
+allowed_origins = https://quiz.example.com, *
+allow_credentials = true
+max_age = -10m
+
+[jwt]
+secret = secret
+audience = audience
+
+[revocation]
+store = memcached
+
+[ratelimit]
+client_burst = -1
+
+[loader]
+destination_directory = /tmp/question-set-loader/
+`)
+
+	_, got := loadFromReader(reader, "ini")
+	if got == nil {
+		t.Fatalf("Failed to detect error")
+	}
+
+	want := ValidationErrors{
+		&invalidConfigError{"server.port", "must be between 1 and 65535, not 70000"},
+		&invalidConfigError{"server.development", "must be true or false, not 'maybe'"},
+		&invalidConfigError{"server.shutdown_timeout", "must be a duration such as '30s', not 'soon'"},
+		&invalidConfigError{"cors.allowed_origins", "can't contain '*' when 'cors.allow_credentials' is true"},
+		&invalidConfigError{"cors.max_age", "must not be negative"},
+		&missingConfigError{"jwt.issuer"},
+		&invalidConfigError{"revocation.store", "has unsupported value 'memcached'"},
+		&invalidConfigError{"ratelimit.client_burst", "must be between 0 and 2147483647, not -1"},
+	}
+	if diff := cmp.Diff(want.Error(), got.Error()); diff != "" {
+		t.Error("Wrong error received", diff)
+	}
+}
+
+// Tests loading from environment variables alone
+func TestLoad_env_vars_only(t *testing.T) {
+	t.Setenv("PORT", "8082")
+	t.Setenv("DEVELOPMENT_MODE", "true")
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("JWT_ISSUER", "issuer")
+	t.Setenv("JWT_AUDIENCE", "audience")
+	t.Setenv("LOADER_DST_DIR", "/tmp/question-set-loader/")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://quiz.example.com")
+
+	got, err := Load("")
+	if err != nil {
+		t.Fatalf("Failed to load config with: %v", err)
+	}
+
+	want := Config{}
+	want.Cors.AllowedOrigins = []string{"https://quiz.example.com"}
+	want.Cors.AllowedMethods = DefaultCorsMethods
+	want.Cors.AllowedHeaders = DefaultCorsHeaders
+	want.Server.Port = 8082
+	want.Server.Development = true
+	want.Server.ShutdownTimeout = 30 * time.Second
+	want.Jwt.Secret = "secret"
+	want.Jwt.Issuer = "issuer"
+	want.Jwt.Audience = "audience"
+	want.Revocation.Store = auth.RevocationStoreFile
+	want.Publish.Broker = publish.BrokerNone
+	want.RateLimit.Store = ratelimit.StoreMemory
+	want.Idempotency.Store = idempotency.StoreMemory
+	want.Idempotency.TTL = idempotency.DefaultTTL
+	want.Loader.Store = quiz.StoreFile
+	want.Loader.DestinationDirectory = "/tmp/question-set-loader/"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal("Wrong config loaded: ", diff)
+	}
+}
+
+// Tests loading the Lambda's config from its prefixed environment variables, which
+// ignore the container's
+func TestLoadLambda(t *testing.T) {
+	t.Setenv("JWT_SECRET", "container secret")
+	t.Setenv("MC_SPEEDRUN_JWT_SECRET", "secret")
+	t.Setenv("MC_SPEEDRUN_JWT_ISSUER", "issuer")
+	t.Setenv("MC_SPEEDRUN_JWT_AUDIENCE", "audience")
+	t.Setenv("MC_SPEEDRUN_LOADER_STORE", "s3")
+	t.Setenv("MC_SPEEDRUN_S3_BUCKET", "question-sets")
+	t.Setenv("MC_SPEEDRUN_RATELIMIT_CLIENT_PER_MINUTE", "60")
+	t.Setenv("MC_SPEEDRUN_REDIS_HOST", "localhost")
+	t.Setenv("MC_SPEEDRUN_REDIS_PORT", "6379")
+
+	got, err := LoadLambda()
+	if err != nil {
+		t.Fatalf("Failed to load config with: %v", err)
+	}
+
+	want := Config{}
+	want.Cors.AllowedMethods = DefaultCorsMethods
+	want.Cors.AllowedHeaders = DefaultCorsHeaders
+	want.Jwt.Secret = "secret"
+	want.Jwt.Issuer = "issuer"
+	want.Jwt.Audience = "audience"
+	want.Revocation.Store = auth.RevocationStoreFile
+	want.Publish.Broker = publish.BrokerNone
+	want.RateLimit.Store = ratelimit.StoreRedis
+	want.RateLimit.ClientPerMinute = 60
+	want.Idempotency.Store = idempotency.StoreMemory
+	want.Idempotency.TTL = idempotency.DefaultTTL
+	want.Redis.Host = "localhost"
+	want.Redis.Port = 6379
+	want.Loader.Store = quiz.StoreS3
+	want.S3.Bucket = "question-sets"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal("Wrong config loaded: ", diff)
+	}
+}
+
+// Tests that every problem with the Lambda's config is reported at once, named by
+// its environment variable
+func TestLoadLambda_invalid_values(t *testing.T) {
+	t.Setenv("MC_SPEEDRUN_JWT_ISSUER", "issuer")
+	t.Setenv("MC_SPEEDRUN_CORS_ALLOWED_ORIGINS", "*")
+	t.Setenv("MC_SPEEDRUN_CORS_ALLOW_CREDENTIALS", "true")
+	t.Setenv("MC_SPEEDRUN_RATELIMIT_STORE", "memory")
+	t.Setenv("MC_SPEEDRUN_RATELIMIT_QUIZ_PER_MINUTE", "30")
+	t.Setenv("MC_SPEEDRUN_AUDIT_FILE", "/tmp/audit.jsonl")
+
+	_, got := LoadLambda()
+	want := ValidationErrors{
+		&invalidConfigError{"MC_SPEEDRUN_CORS_ALLOWED_ORIGINS", "can't contain '*' when 'MC_SPEEDRUN_CORS_ALLOW_CREDENTIALS' is true"},
+		&missingConfigError{"MC_SPEEDRUN_JWT_SECRET"},
+		&missingConfigError{"MC_SPEEDRUN_JWT_AUDIENCE"},
+		&missingConfigError{"MC_SPEEDRUN_LOADER_DST_DIR"},
+		&invalidConfigError{"MC_SPEEDRUN_RATELIMIT_STORE", "has unsupported value 'memory'"},
+		&missingConfigError{"MC_SPEEDRUN_REDIS_HOST"},
+		&missingConfigError{"MC_SPEEDRUN_REDIS_PORT"},
+		&invalidConfigError{"MC_SPEEDRUN_AUDIT_FILE", "isn't supported by the Lambda. The audit log is only kept by the container"},
+	}
+	if got == nil {
+		t.Fatalf("Failed to detect error")
+	}
+	if diff := cmp.Diff(want.Error(), got.Error()); diff != "" {
+		t.Error("Wrong error received", diff)
+	}
+}
+
+// Tests that YAML and TOML files load the same config as ini files
+func TestLoad_formats(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": `
+server:
+  port: 8082
+  development: false
+cors:
+  allowed_origins: [https://quiz.example.com, "https://*.staging.example.com"]
+  max_age: 10m
+jwt:
+  secret: secret
+  issuer: issuer
+  audience: audience
+loader:
+  destination_directory: /tmp/question-set-loader/
+`,
+		"config.toml": `
+[server]
+port = 8082
+development = false
+
+[cors]
+allowed_origins = "https://quiz.example.com, https://*.staging.example.com"
+max_age = "10m"
+
+[jwt]
+secret = "secret"
+issuer = "issuer"
+audience = "audience"
+
+[loader]
+destination_directory = "/tmp/question-set-loader/"
+`,
+		"config.ini": `
+[server]
+port = 8082
+development = false
+
+[cors]
+allowed_origins = https://quiz.example.com, https://*.staging.example.com
+max_age = 10m
+
+[jwt]
+secret = secret
+issuer = issuer
+audience = audience
+
+[loader]
+destination_directory = /tmp/question-set-loader/
+`,
+	}
+
+	want := Config{}
+	want.Cors.AllowedOrigins = []string{"https://quiz.example.com", "https://*.staging.example.com"}
+	want.Cors.AllowedMethods = DefaultCorsMethods
+	want.Cors.AllowedHeaders = DefaultCorsHeaders
+	want.Cors.MaxAge = 10 * time.Minute
+	want.Server.Port = 8082
+	want.Server.ShutdownTimeout = 30 * time.Second
+	want.Jwt.Secret = "secret"
+	want.Jwt.Issuer = "issuer"
+	want.Jwt.Audience = "audience"
+	want.Revocation.Store = auth.RevocationStoreFile
+	want.Publish.Broker = publish.BrokerNone
+	want.RateLimit.Store = ratelimit.StoreMemory
+	want.Idempotency.Store = idempotency.StoreMemory
+	want.Idempotency.TTL = idempotency.DefaultTTL
+	want.Loader.Store = quiz.StoreFile
+	want.Loader.DestinationDirectory = "/tmp/question-set-loader/"
+
+	for name, contents := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Load(path)
+			if err != nil {
+				t.Fatalf("Failed to load config with: %v", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Fatal("Wrong config loaded: ", diff)
+			}
+		})
+	}
+}
+
+func TestLoad_unsupported_format(t *testing.T) {
+	if _, err := Load("config.json"); err == nil {
+		t.Errorf("Failed to detect unsupported format")
+	}
+}
+
+func TestString_redacts_secrets(t *testing.T) {
+	config := Config{}
+	config.Jwt.Secret = "hunter2"
+	config.Jwt.Issuer = "issuer"
+	config.S3.AccessKeyID = "minio"
+	config.S3.SecretAccessKey = "env://S3_SECRET"
+	config.RabbitMQ.Username = "admin"
+	config.RabbitMQ.Password = "passwd"
+	config.Audit.OperatorToken = "letmein"
+
+	got := config.String()
+	for _, want := range []string{"Secret:REDACTED", "Issuer:issuer", "AccessKeyID:REDACTED", "SecretAccessKey:env://S3_SECRET", "Username:admin", "Password:REDACTED", "OperatorToken:REDACTED"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected '%s' in %s", want, got)
+		}
+	}
+	if strings.Contains(got, "hunter2") || strings.Contains(got, "minio") || strings.Contains(got, "passwd") || strings.Contains(got, "letmein") {
+		t.Errorf("Secret was not redacted in %s", got)
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	arn := "arn:aws:secretsmanager:us-east-2:123456789012:secret:s3-AbCdEf"
+	t.Setenv("TEST_JWT_SECRET", "jwt-secret")
+	resolver := secret.NewResolver(secret.MapSource{arn: `{"accessKeyId": "key-id", "secretAccessKey": "access-key"}`})
+
+	config := Config{}
+	config.Jwt.Secret = "env://TEST_JWT_SECRET"
+	config.Jwt.Issuer = "issuer"
+	config.S3.AccessKeyID = arn + "#accessKeyId"
+	config.S3.SecretAccessKey = arn + "#secretAccessKey"
+	if err := config.ResolveSecrets(context.Background(), resolver); err != nil {
+		t.Fatalf("Failed to resolve secrets: %v", err)
+	}
+
+	want := Config{}
+	want.Jwt.Secret = "jwt-secret"
+	want.Jwt.Issuer = "issuer"
+	want.S3.AccessKeyID = "key-id"
+	want.S3.SecretAccessKey = "access-key"
+	if diff := cmp.Diff(want, config); diff != "" {
+		t.Fatal("Wrong secrets resolved: ", diff)
+	}
+}
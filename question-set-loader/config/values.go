@@ -0,0 +1,199 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/secret"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+type missing string
+
+type missingConfigError struct {
+	key string
+}
+
+func (e *missingConfigError) Error() string {
+	return fmt.Sprintf("config item '%s' was not set", e.key)
+}
+
+type invalidConfigError struct {
+	key		string
+	reason	string
+}
+
+func (e *invalidConfigError) Error() string {
+	return fmt.Sprintf("config item '%s' %s", e.key, e.reason)
+}
+
+// Every problem found with the config so that they can all be fixed at once
+type ValidationErrors []error
+
+func (e ValidationErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	problems := make([]string, len(e))
+	for i, err := range e {
+		problems[i] = err.Error()
+	}
+	return fmt.Sprintf("%d problems with the config: %s", len(e), strings.Join(problems, "; "))
+}
+
+// Reads typed values out of the config, recording a problem for each value that's
+// missing or invalid rather than stopping at the first
+type valueReader struct {
+	v		*viper.Viper
+	errs	ValidationErrors
+	// Environment variable each key is reported as when the config only comes from them
+	envVars	map[string]string
+}
+
+// How key is named in problems with it
+func (r *valueReader) name(key string) string {
+	if envVar, ok := r.envVars[key]; ok {
+		return envVar
+	}
+	return key
+}
+
+func (r *valueReader) isMissing(key string) bool {
+	if _, ok := r.v.Get(key).(missing); ok {
+		r.errs = append(r.errs, &missingConfigError{r.name(key)})
+		return true
+	}
+	return false
+}
+
+func (r *valueReader) invalid(key string, reason string, args ...interface{}) {
+	r.errs = append(r.errs, &invalidConfigError{r.name(key), fmt.Sprintf(reason, args...)})
+}
+
+func (r *valueReader) string(key string) string {
+	if r.isMissing(key) {
+		return ""
+	}
+	return r.v.GetString(key)
+}
+
+// Strings that are required can't be empty either
+func (r *valueReader) nonEmptyString(key string) string {
+	if r.isMissing(key) {
+		return ""
+	}
+	value := r.v.GetString(key)
+	if value == "" {
+		r.errs = append(r.errs, &missingConfigError{r.name(key)})
+	}
+	return value
+}
+
+// Returns the value when it's one of allowed
+func (r *valueReader) oneOf(key string, allowed ...string) string {
+	value := r.string(key)
+	for _, a := range allowed {
+		if value == a {
+			return value
+		}
+	}
+	r.invalid(key, "has unsupported value '%s'", value)
+	return value
+}
+
+func (r *valueReader) int(key string, min int, max int) int {
+	if r.isMissing(key) {
+		return 0
+	}
+	value, err := cast.ToIntE(r.v.Get(key))
+	if err != nil {
+		r.invalid(key, "must be a whole number, not '%v'", r.v.Get(key))
+		return 0
+	}
+	if value < min || value > max {
+		r.invalid(key, "must be between %d and %d, not %d", min, max, value)
+	}
+	return value
+}
+
+func (r *valueReader) port(key string) int {
+	return r.int(key, 1, 65535)
+}
+
+func (r *valueReader) bool(key string) bool {
+	if r.isMissing(key) {
+		return false
+	}
+	value, err := cast.ToBoolE(r.v.Get(key))
+	if err != nil {
+		r.invalid(key, "must be true or false, not '%v'", r.v.Get(key))
+	}
+	return value
+}
+
+// Durations are written like "30s" or "1h30m" and can't be negative
+func (r *valueReader) duration(key string) time.Duration {
+	if r.isMissing(key) {
+		return 0
+	}
+	value, err := cast.ToDurationE(r.v.Get(key))
+	if err != nil {
+		r.invalid(key, "must be a duration such as '30s', not '%v'", r.v.Get(key))
+		return 0
+	}
+	if value < 0 {
+		r.invalid(key, "must not be negative")
+	}
+	return value
+}
+
+// Lists are comma separated strings, or lists in formats that have them e.g. YAML
+func (r *valueReader) list(key string) []string {
+	if r.isMissing(key) {
+		return nil
+	}
+	switch value := r.v.Get(key).(type) {
+	case []interface{}, []string:
+		items, err := cast.ToStringSliceE(value)
+		if err != nil {
+			r.invalid(key, "must be a list of strings")
+		}
+		return SplitList(strings.Join(items, ","))
+	default:
+		return SplitList(r.v.GetString(key))
+	}
+}
+
+const redacted = "REDACTED"
+
+// Hides a secret unless it's empty or a reference to where the secret is kept
+func Redact(value string) string {
+	if value == "" || secret.IsReference(value) {
+		return value
+	}
+	return redacted
+}
+
+// Splits a comma separated config value into its trimmed, non-empty items
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Whether the CORS origins allow any origin with "*". That can't be combined with
+// allowing credentials
+func AllowsAnyOrigin(origins []string) bool {
+	for _, origin := range origins {
+		if origin == "*" {
+			return true
+		}
+	}
+	return false
+}
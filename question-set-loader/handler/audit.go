@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/audit"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/auth"
+)
+
+// Path operators query the audit log on. It's only served by the container, outside
+// of the API paths that are exposed to hosts
+const AuditPath = "/audit"
+
+// Records the status and problem of the response to an audited request
+type auditResponseWriter struct {
+	http.ResponseWriter
+	statusCode	int
+	problem		bytes.Buffer	// Body of error responses
+	requestBody	*countingReader
+}
+
+func (aw *auditResponseWriter) WriteHeader(statusCode int) {
+	aw.statusCode = statusCode
+	aw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (aw *auditResponseWriter) Write(data []byte) (int, error) {
+	if aw.statusCode == 0 {
+		aw.statusCode = http.StatusOK
+	}
+	if aw.statusCode >= 400 {
+		aw.problem.Write(data)
+	}
+	return aw.ResponseWriter.Write(data)
+}
+
+// Outcome of the response for the audit log, the name of its problem type when it failed
+func (aw *auditResponseWriter) outcome() string {
+	if aw.statusCode < 400 {
+		return audit.OutcomeSuccess
+	}
+	var problem Problem
+	if err := json.Unmarshal(aw.problem.Bytes(), &problem); err != nil || problem.Type == "" {
+		return strconv.Itoa(aw.statusCode)
+	}
+	typ := string(problem.Type)
+	return typ[strings.LastIndex(typ, ":")+1:]
+}
+
+// Counts the bytes read from the request body
+type countingReader struct {
+	r		io.ReadCloser
+	count	int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.count += int64(n)
+	return n, err
+}
+
+func (cr *countingReader) Close() error {
+	return cr.r.Close()
+}
+
+// Starts the audit entry for a request to change the host's question set. The request
+// must be handled with the returned writer and then passed to finishAudit
+func (u *Upload) startAudit(w http.ResponseWriter, r *http.Request, host auth.HostClaims) (*audit.Entry, *auditResponseWriter) {
+	action := audit.ActionUpload
+	switch r.Method {
+	case http.MethodPut:
+		action = audit.ActionReplace
+	case http.MethodDelete:
+		action = audit.ActionDelete
+	}
+
+	body := &countingReader{r: r.Body}
+	r.Body = body
+	entry := &audit.Entry{
+		Time: time.Now().UTC(),
+		Action: action,
+		QuizId: host.QuizId,
+		Subject: host.Subject,
+		TokenId: host.TokenId,
+		ClientIP: clientIP(r, u.RateLimits.TrustForwardedFor),
+	}
+	return entry, &auditResponseWriter{ResponseWriter: w, requestBody: body}
+}
+
+// Writes the entry with the outcome of the response. A failure to write it is logged
+// rather than failing the request, which has already been handled
+func (u *Upload) finishAudit(entry *audit.Entry, aw *auditResponseWriter) {
+	if u.Audit == nil {
+		return
+	}
+	entry.Size = aw.requestBody.count
+	entry.Status = aw.statusCode
+	entry.Outcome = aw.outcome()
+	if err := u.Audit.Write(*entry); err != nil {
+		u.Logger.Error(fmt.Sprintf("Failed to write audit entry for %s of quiz '%s'. Error: %s", entry.Action, entry.QuizId, err))
+	}
+}
+
+// Returns the audit log entries matching the query parameters, newest first. Requests
+// must have the operator token as their bearer token. The parameters are 'quizId',
+// 'action', 'since' and 'until' as RFC 3339 timestamps and 'limit'
+func AuditLog(querier audit.Querier, operatorToken string) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			WriteProblem(w, NewProblem(ProblemMethodNotAllowed, fmt.Sprintf("Unexpected HTTP method '%s'", r.Method)))
+			return
+		}
+
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			WriteProblem(w, NewProblem(ProblemMissingAuthorization, "Absent 'Authorization' header"))
+			return
+		}
+		token := strings.TrimPrefix(authHeader, "Bearer ")
+		if token == authHeader || subtle.ConstantTimeCompare([]byte(token), []byte(operatorToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			WriteProblem(w, NewProblem(ProblemInvalidAuthorization, "Authorization must be the operator's bearer token"))
+			return
+		}
+
+		filter, err := parseAuditFilter(r)
+		if err != nil {
+			WriteProblem(w, NewProblem(ProblemInvalidQuery, err.Error()))
+			return
+		}
+		entries, err := querier.Query(filter)
+		if err != nil {
+			WriteProblem(w, NewProblem(ProblemInternalError, fmt.Sprintf("Couldn't query the audit log: %s", err)))
+			return
+		}
+		if entries == nil {
+			entries = []audit.Entry{} // An empty array rather than null
+		}
+		writeJson(w, http.StatusOK, entries)
+	}
+}
+
+func parseAuditFilter(r *http.Request) (audit.Filter, error) {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		QuizId: query.Get("quizId"),
+		Action: query.Get("action"),
+	}
+
+	switch filter.Action {
+	case "", audit.ActionUpload, audit.ActionReplace, audit.ActionDelete:
+	default:
+		return filter, fmt.Errorf("'action' must be '%s', '%s' or '%s'", audit.ActionUpload, audit.ActionReplace, audit.ActionDelete)
+	}
+
+	for _, bound := range []struct {
+		name	string
+		t		*time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		value := query.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("'%s' must be an RFC 3339 timestamp", bound.name)
+		}
+		*bound.t = parsed
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > audit.MaxQueryLimit {
+			return filter, fmt.Errorf("'limit' must be between 1 and %d", audit.MaxQueryLimit)
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/audit"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/auth"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/internal/testutils"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/quiz"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+)
+
+type mockAuditSink struct {
+	entries	[]audit.Entry
+}
+
+func (m *mockAuditSink) Write(entry audit.Entry) error {
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+// Tests an audit entry is written for each change to the question set
+func TestUploadQuizHandler_audited(t *testing.T) {
+	token, err := testutils.BuildJwt(testutils.JwtTestParams{
+		Secret: "testsecret",
+		Issuer: "go.test",
+		Audience: "go.test",
+		IsHost: true,
+		QuizId: "quizId",
+		Claims: map[string]interface{}{"sub": "host-1", "jti": "token-1"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create auth token: %v", err)
+	}
+	hashOf := func(qAndA quiz.QuestionAndAnswers) string {
+		return strings.Trim(mustETag(t, qAndA), `"`)
+	}
+
+	tests := map[string]struct {
+		stored		quiz.QuestionAndAnswers
+		method		string
+		upload		quiz.QuestionAndAnswers
+		headers		map[string]string
+		want		*audit.Entry
+	}{
+		"upload": {nil, http.MethodPost, qAndA, nil,
+			&audit.Entry{Action: audit.ActionUpload, ContentHash: hashOf(qAndA), Status: http.StatusCreated, Outcome: audit.OutcomeSuccess}},
+		"replace": {qAndA, http.MethodPut, replacement, nil,
+			&audit.Entry{Action: audit.ActionReplace, ContentHash: hashOf(replacement), Status: http.StatusOK, Outcome: audit.OutcomeSuccess}},
+		"delete": {qAndA, http.MethodDelete, nil, nil,
+			&audit.Entry{Action: audit.ActionDelete, ContentHash: hashOf(qAndA), Status: http.StatusNoContent, Outcome: audit.OutcomeSuccess}},
+		"delete nothing uploaded": {nil, http.MethodDelete, nil, nil,
+			&audit.Entry{Action: audit.ActionDelete, Status: http.StatusNotFound, Outcome: "question-set-not-found"}},
+		"precondition failed": {qAndA, http.MethodPut, replacement, map[string]string{"If-Match": `"sha256:other"`},
+			&audit.Entry{Action: audit.ActionReplace, ContentHash: hashOf(replacement), Status: http.StatusPreconditionFailed, Outcome: "precondition-failed"}},
+		"read isn't audited": {qAndA, http.MethodGet, nil, nil, nil},
+	}
+
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			mockQuizStore := NewMockQuizStore(nil)
+			if test.stored != nil {
+				mockQuizStore.stored["quizId"] = test.stored
+			}
+			sink := &mockAuditSink{}
+			uploadServer := Upload {
+				QuizStore: mockQuizStore,
+				JwtParams: auth.JwtParams{Secret: "testsecret", Issuer: "go.test", Audience: "go.test"},
+				Audit: sink,
+				Logger: logrus.StandardLogger(),
+			}
+			req := buildConditionalRequest(t, test.method, test.upload, test.headers)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+			before := time.Now()
+			uploadServer.Quiz(httptest.NewRecorder(), req)
+
+			if test.want == nil {
+				if len(sink.entries) != 0 {
+					t.Fatalf("Expected no audit entries but got %+v", sink.entries)
+				}
+				return
+			}
+			if len(sink.entries) != 1 {
+				t.Fatalf("Expected 1 audit entry but got %d", len(sink.entries))
+			}
+			got := sink.entries[0]
+			if got.Time.Before(before.Add(-time.Second)) || got.Time.After(time.Now()) {
+				t.Errorf("Expected the entry time to be now but got %s", got.Time)
+			}
+			if (test.upload != nil) != (got.Size > 0) {
+				t.Errorf("Wrong size %d for the request body", got.Size)
+			}
+			want := *test.want
+			want.QuizId = "quizId"
+			want.Subject = "host-1"
+			want.TokenId = "token-1"
+			want.ClientIP = "192.0.2.1"
+			got.Time, got.Size = time.Time{}, 0
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("Wrong audit entry: %s", diff)
+			}
+		})
+	}
+}
+
+// Tests the operator querying the audit log
+func TestAuditLog(t *testing.T) {
+	sink, err := audit.NewFileSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	defer sink.Close()
+	start := time.Date(2022, 1, 27, 8, 0, 0, 0, time.UTC)
+	entries := []audit.Entry{
+		{Time: start, Action: audit.ActionUpload, QuizId: "quiz1", Status: http.StatusCreated, Outcome: audit.OutcomeSuccess},
+		{Time: start.Add(time.Minute), Action: audit.ActionUpload, QuizId: "quiz2", Status: http.StatusCreated, Outcome: audit.OutcomeSuccess},
+		{Time: start.Add(2 * time.Minute), Action: audit.ActionDelete, QuizId: "quiz1", Status: http.StatusNoContent, Outcome: audit.OutcomeSuccess},
+	}
+	for _, entry := range entries {
+		if err := sink.Write(entry); err != nil {
+			t.Fatalf("Failed to write entry: %v", err)
+		}
+	}
+
+	tests := map[string]struct {
+		method			string
+		authorization	string
+		query			string
+		wantStatus		int
+		want			[]audit.Entry
+	}{
+		"everything": {http.MethodGet, "Bearer operator", "", http.StatusOK, []audit.Entry{entries[2], entries[1], entries[0]}},
+		"by quiz": {http.MethodGet, "Bearer operator", "?quizId=quiz1", http.StatusOK, []audit.Entry{entries[2], entries[0]}},
+		"by action": {http.MethodGet, "Bearer operator", "?action=upload&limit=1", http.StatusOK, []audit.Entry{entries[1]}},
+		"by time": {http.MethodGet, "Bearer operator", "?since=2022-01-27T08:01:00Z&until=2022-01-27T08:02:00Z", http.StatusOK, []audit.Entry{entries[1]}},
+		"none match": {http.MethodGet, "Bearer operator", "?quizId=quiz3", http.StatusOK, []audit.Entry{}},
+		"absent authorization": {http.MethodGet, "", "", http.StatusUnauthorized, nil},
+		"wrong token": {http.MethodGet, "Bearer operator2", "", http.StatusUnauthorized, nil},
+		"not a bearer token": {http.MethodGet, "operator", "", http.StatusUnauthorized, nil},
+		"invalid action": {http.MethodGet, "Bearer operator", "?action=read", http.StatusBadRequest, nil},
+		"invalid time": {http.MethodGet, "Bearer operator", "?since=yesterday", http.StatusBadRequest, nil},
+		"invalid limit": {http.MethodGet, "Bearer operator", "?limit=1001", http.StatusBadRequest, nil},
+		"wrong method": {http.MethodDelete, "Bearer operator", "", http.StatusMethodNotAllowed, nil},
+	}
+
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, AuditPath + test.query, nil)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+
+			recorder := httptest.NewRecorder()
+			AuditLog(sink, "operator")(recorder, req)
+
+			if diff := cmp.Diff(test.wantStatus, recorder.Code); diff != "" {
+				t.Fatalf("Wrong status code: %s", diff)
+			}
+			if test.want == nil {
+				if contentType := recorder.Header().Get("Content-Type"); contentType != ProblemContentType {
+					t.Errorf("Expected a problem but got '%s'", contentType)
+				}
+				return
+			}
+			var got []audit.Entry
+			if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Wrong entries: %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Path of the endpoint listing the categories in the uploaded question set
+const CategoriesPath = QuizPath + "/categories"
+
+// Lists the categories in the question set uploaded for the quiz in the hosts token,
+// with the number of questions in each, so the host can pick from them. Has the same
+// 'ETag' as the question set and honours 'If-None-Match'
+func (u *Upload) Categories(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		WriteProblem(w, NewProblem(ProblemMethodNotAllowed, fmt.Sprintf("Unexpected HTTP method '%s'", r.Method)))
+		return
+	}
+
+	host, ok := u.admit(w, r)
+	if !ok {
+		return
+	}
+	quizId := host.QuizId
+
+	qAndA, etag, ok := u.currentQuiz(w, quizId)
+	if !ok {
+		return
+	}
+	if etag == "" {
+		WriteProblem(w, NewProblem(ProblemQuestionSetNotFound, fmt.Sprintf("No question set uploaded for quiz '%s'", quizId)))
+		return
+	}
+	if !checkPreconditions(w, r, quizId, etag) {
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	writeJson(w, http.StatusOK, qAndA.CategoryCounts())
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/auth"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/quiz"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+)
+
+// Tests listing the categories in the uploaded question set
+func TestCategoriesHandler(t *testing.T) {
+	jwtParams := auth.JwtParams{
+		Secret: "testsecret",
+		Issuer: "go.test",
+		Audience: "go.test",
+	}
+	stored := quiz.QuestionAndAnswers{
+		{Question: "q1", Category: "tech", Options: []string{"a", "b"}, Answers: []int{0}},
+		{Question: "q2", Category: "food", Options: []string{"a", "b"}, Answers: []int{0}},
+		{Question: "q3", Category: "tech", Options: []string{"a", "b"}, Answers: []int{1}},
+	}
+	etag := mustETag(t, stored)
+
+	tests := map[string]struct {
+		stored		quiz.QuestionAndAnswers
+		method		string
+		headers		map[string]string
+		wantStatus	int
+		want		[]quiz.CategoryCount
+	}{
+		"listed": {stored, http.MethodGet, nil, http.StatusOK, []quiz.CategoryCount{{Name: "food", QuestionCount: 1}, {Name: "tech", QuestionCount: 2}}},
+		"not modified": {stored, http.MethodGet, map[string]string{"If-None-Match": etag}, http.StatusNotModified, nil},
+		"nothing uploaded": {nil, http.MethodGet, nil, http.StatusNotFound, nil},
+		"wrong method": {stored, http.MethodPost, nil, http.StatusMethodNotAllowed, nil},
+	}
+
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			mockQuizStore := NewMockQuizStore(nil)
+			if test.stored != nil {
+				mockQuizStore.stored["quizId"] = test.stored
+			}
+			uploadServer := Upload {
+				QuizStore: mockQuizStore,
+				JwtParams: jwtParams,
+				Logger: logrus.StandardLogger(),
+			}
+			req := buildAuthorizedRequest(t, test.method, nil, jwtParams, "quizId")
+			req.URL.Path = CategoriesPath
+			for name, value := range test.headers {
+				req.Header.Set(name, value)
+			}
+
+			recorder := httptest.NewRecorder()
+			NewServeMux(&uploadServer, CorsOptions{}, logrus.StandardLogger()).ServeHTTP(recorder, req)
+
+			if diff := cmp.Diff(test.wantStatus, recorder.Code); diff != "" {
+				t.Fatalf("Wrong status code: %s", diff)
+			}
+			if test.want == nil {
+				return
+			}
+			if diff := cmp.Diff(etag, recorder.Header().Get("ETag")); diff != "" {
+				t.Errorf("Wrong ETag: %s", diff)
+			}
+			var got []quiz.CategoryCount
+			if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Wrong categories: %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/idempotency"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/quiz"
+)
+
+// Retries of requests with an 'Idempotency-Key' header get the original result. Keys
+// aren't remembered without a Store
+type Idempotency struct {
+	Store	idempotency.Store
+	TTL		time.Duration	// How long results are remembered for
+}
+
+// Longest 'Idempotency-Key' accepted
+const maxIdempotencyKeyLength = 255
+
+// Returns the strong entity tag of the question set. It's the hash of the normalised
+// question set so it's the same whatever format the question set was uploaded in
+func etagOf(qAndA quiz.QuestionAndAnswers) (string, error) {
+	contentHash, err := qAndA.ContentHash()
+	if err != nil {
+		return "", err
+	}
+	return `"` + contentHash + `"`, nil
+}
+
+// Whether the entity tags listed in the header match etag, which is empty when there's
+// no question set. "*" matches any question set. Weak tags only match with weak
+// comparison. See https://datatracker.ietf.org/doc/html/rfc7232#section-2.3.2
+func etagListMatches(header string, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+	for _, tag := range splitHeaderList(header) {
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks the 'If-Match' and 'If-None-Match' headers against the entity tag of the stored
+// question set, empty when there isn't one. When a precondition fails the response is
+// written and ok is false. GET gets '304 Not Modified' when 'If-None-Match' matches,
+// other methods get '412 Precondition Failed'
+func checkPreconditions(w http.ResponseWriter, r *http.Request, quizId string, currentETag string) (ok bool) {
+	if ifMatch := strings.Join(r.Header.Values("If-Match"), ","); ifMatch != "" && !etagListMatches(ifMatch, currentETag, false) {
+		WriteProblem(w, NewProblem(ProblemPreconditionFailed, fmt.Sprintf("Question set for quiz '%s' doesn't match 'If-Match'", quizId)))
+		return false
+	}
+	if ifNoneMatch := strings.Join(r.Header.Values("If-None-Match"), ","); ifNoneMatch != "" && etagListMatches(ifNoneMatch, currentETag, true) {
+		if r.Method == http.MethodGet {
+			w.Header().Set("ETag", currentETag)
+			w.WriteHeader(http.StatusNotModified)
+			return false
+		}
+		WriteProblem(w, NewProblem(ProblemPreconditionFailed, fmt.Sprintf("Question set for quiz '%s' matches 'If-None-Match'", quizId)))
+		return false
+	}
+	return true
+}
+
+// Whether the request has a precondition to check
+func hasPreconditions(r *http.Request) bool {
+	return r.Header.Get("If-Match") != "" || r.Header.Get("If-None-Match") != ""
+}
+
+// Returns the 'Idempotency-Key' header, which is empty when it isn't sent. When it's
+// invalid the response is written and ok is false
+func idempotencyKey(w http.ResponseWriter, r *http.Request) (key string, ok bool) {
+	key = r.Header.Get("Idempotency-Key")
+	if len(key) > maxIdempotencyKeyLength {
+		WriteProblem(w, NewProblem(ProblemInvalidIdempotencyKey, fmt.Sprintf("'Idempotency-Key' is longer than %d characters", maxIdempotencyKeyLength)))
+		return "", false
+	}
+	for _, c := range key {
+		if c < ' ' || c > '~' {
+			WriteProblem(w, NewProblem(ProblemInvalidIdempotencyKey, "'Idempotency-Key' must be printable ASCII"))
+			return "", false
+		}
+	}
+	return key, true
+}
+
+// Idempotency key reserved by a request while it's in progress. Its methods do nothing
+// on a nil reservation, which is what requests without a key get
+type reservation struct {
+	u		*Upload
+	key		string	// '<quizId>:<Idempotency-Key>'
+	result	idempotency.Result
+	done	bool
+}
+
+// Reserves the key for the request, or responds with the recorded result when the
+// request retries one made with the same key. Returns whether the response was
+// written. The key is reserved in one step with checking it's free so that concurrent
+// retries aren't both handled. A key reused for a different request is rejected, as
+// is a retry while the original is still in progress. Requests are handled as new
+// ones if the store fails so that uploads don't depend on it
+func (u *Upload) reserveIdempotent(w http.ResponseWriter, r *http.Request, quizId string, key string, etag string) (res *reservation, written bool) {
+	if key == "" || u.Idempotency.Store == nil {
+		return nil, false
+	}
+	storeKey := quizId + ":" + key
+	pending := idempotency.Result{Method: r.Method, ETag: etag}
+	ttl := idempotency.PendingTTL
+	if u.Idempotency.TTL > 0 && u.Idempotency.TTL < ttl {
+		ttl = u.Idempotency.TTL
+	}
+	reserved, err := u.Idempotency.Store.PutIfAbsent(storeKey, pending, ttl)
+	if err != nil {
+		u.Logger.Error(err.Error())
+		return nil, false
+	}
+	if reserved {
+		return &reservation{u: u, key: storeKey, result: pending}, false
+	}
+
+	result, ok, err := u.Idempotency.Store.Get(storeKey)
+	if err != nil {
+		u.Logger.Error(err.Error())
+		return nil, false
+	}
+	if !ok {
+		return nil, false // Released or expired since
+	}
+	if result.Method != r.Method || result.ETag != etag {
+		WriteProblem(w, NewProblem(ProblemIdempotencyKeyReused, fmt.Sprintf("'Idempotency-Key' '%s' was already used for a different request", key)))
+		return nil, true
+	}
+	if result.Pending() {
+		w.Header().Set("Retry-After", "1")
+		WriteProblem(w, NewProblem(ProblemIdempotencyKeyInUse, fmt.Sprintf("A request with 'Idempotency-Key' '%s' is still in progress", key)))
+		return nil, true
+	}
+
+	u.Logger.Info(fmt.Sprintf("Replayed result of idempotency key '%s' for quiz '%s'", key, quizId))
+	w.Header().Set("ETag", result.ETag)
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(result.Status)
+	return nil, true
+}
+
+// Records the result of the request so that retries with the same key get it
+func (res *reservation) record(status int) {
+	if res == nil {
+		return
+	}
+	res.done = true
+	ttl := res.u.Idempotency.TTL
+	if ttl <= 0 {
+		ttl = idempotency.DefaultTTL
+	}
+	res.result.Status = status
+	if err := res.u.Idempotency.Store.Put(res.key, res.result, ttl); err != nil {
+		res.u.Logger.Error(err.Error())
+	}
+}
+
+// Frees the key unless a result was recorded. Only successful results are kept so that
+// a failed request can be retried with the same key
+func (res *reservation) release() {
+	if res == nil || res.done {
+		return
+	}
+	if err := res.u.Idempotency.Store.Delete(res.key); err != nil {
+		res.u.Logger.Error(err.Error())
+	}
+}
@@ -0,0 +1,358 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/auth"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/idempotency"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/quiz"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+)
+
+type failingIdempotencyStore struct{}
+
+func (failingIdempotencyStore) Get(key string) (idempotency.Result, bool, error) {
+	return idempotency.Result{}, false, errors.New("connection refused")
+}
+
+func (failingIdempotencyStore) Put(key string, result idempotency.Result, ttl time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func (failingIdempotencyStore) PutIfAbsent(key string, result idempotency.Result, ttl time.Duration) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func (failingIdempotencyStore) Delete(key string) error {
+	return errors.New("connection refused")
+}
+
+var replacement = quiz.QuestionAndAnswers{
+	{Question: "question 2", Category: "tech", Options: []string{"i", "ii"}, Answers: []int{0}},
+}
+
+func mustETag(t *testing.T, qAndA quiz.QuestionAndAnswers) string {
+	etag, err := etagOf(qAndA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return etag
+}
+
+// Returns an upload of qAndA, or a GET or DELETE when qAndA is nil, with the headers set
+func buildConditionalRequest(t *testing.T, method string, qAndA quiz.QuestionAndAnswers, headers map[string]string) *http.Request {
+	jwtParams := auth.JwtParams{Secret: "testsecret", Issuer: "go.test", Audience: "go.test"}
+	var req *http.Request
+	if qAndA == nil {
+		req = buildAuthorizedRequest(t, method, nil, jwtParams, "quizId")
+	} else {
+		body, contentTypeValue, err := buildReqBodyWithQAndA("file", &qAndA)
+		if err != nil {
+			t.Fatalf("failed to create request body: %v", err)
+		}
+		req = buildAuthorizedRequest(t, method, body, jwtParams, "quizId")
+		req.Header.Add("Content-Type", contentTypeValue)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	return req
+}
+
+func TestEtagListMatches(t *testing.T) {
+	etag := `"sha256:ab"`
+	tests := map[string]struct {
+		header	string
+		etag	string
+		weak	bool
+		want	bool
+	}{
+		"same": {`"sha256:ab"`, etag, false, true},
+		"in list": {`"sha256:cd", "sha256:ab"`, etag, false, true},
+		"different": {`"sha256:cd"`, etag, false, false},
+		"any": {"*", etag, false, true},
+		"any without question set": {"*", "", false, false},
+		"weak with strong comparison": {`W/"sha256:ab"`, etag, false, false},
+		"weak with weak comparison": {`W/"sha256:ab"`, etag, true, true},
+	}
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			if got := etagListMatches(test.header, test.etag, test.weak); got != test.want {
+				t.Errorf("Expected %t but got %t", test.want, got)
+			}
+		})
+	}
+}
+
+// Every response for a stored question set has its ETag and the preconditions are
+// checked against it
+func TestUploadQuizHandler_conditional_requests(t *testing.T) {
+	storedETag := mustETag(t, qAndA)
+	replacementETag := mustETag(t, replacement)
+
+	tests := map[string]struct {
+		stored		quiz.QuestionAndAnswers
+		method		string
+		upload		quiz.QuestionAndAnswers
+		headers		map[string]string
+		wantStatus	int
+		wantETag	string
+		wantStored	quiz.QuestionAndAnswers
+	}{
+		"get": {qAndA, http.MethodGet, nil, nil, http.StatusOK, storedETag, qAndA},
+		"get not modified": {qAndA, http.MethodGet, nil, map[string]string{"If-None-Match": storedETag}, http.StatusNotModified, storedETag, qAndA},
+		"get modified": {qAndA, http.MethodGet, nil, map[string]string{"If-None-Match": replacementETag}, http.StatusOK, storedETag, qAndA},
+		"create": {nil, http.MethodPost, qAndA, nil, http.StatusCreated, storedETag, qAndA},
+		"create only": {nil, http.MethodPost, qAndA, map[string]string{"If-None-Match": "*"}, http.StatusCreated, storedETag, qAndA},
+		"create only when stored": {qAndA, http.MethodPost, replacement, map[string]string{"If-None-Match": "*"}, http.StatusPreconditionFailed, "", qAndA},
+		"replace": {qAndA, http.MethodPut, replacement, map[string]string{"If-Match": storedETag}, http.StatusOK, replacementETag, replacement},
+		"replace changed": {qAndA, http.MethodPut, replacement, map[string]string{"If-Match": replacementETag}, http.StatusPreconditionFailed, "", qAndA},
+		"replace weak": {qAndA, http.MethodPut, replacement, map[string]string{"If-Match": "W/" + storedETag}, http.StatusPreconditionFailed, "", qAndA},
+		"post if match without question set": {nil, http.MethodPost, qAndA, map[string]string{"If-Match": "*"}, http.StatusPreconditionFailed, "", nil},
+		"delete": {qAndA, http.MethodDelete, nil, map[string]string{"If-Match": storedETag}, http.StatusNoContent, "", nil},
+		"delete changed": {qAndA, http.MethodDelete, nil, map[string]string{"If-Match": replacementETag}, http.StatusPreconditionFailed, "", qAndA},
+	}
+
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			mockQuizStore := NewMockQuizStore(nil)
+			if test.stored != nil {
+				mockQuizStore.stored["quizId"] = test.stored
+			}
+			uploadServer := Upload {
+				QuizStore: mockQuizStore,
+				JwtParams: auth.JwtParams{Secret: "testsecret", Issuer: "go.test", Audience: "go.test"},
+				Logger: logrus.StandardLogger(),
+			}
+
+			recorder := httptest.NewRecorder()
+			uploadServer.Quiz(recorder, buildConditionalRequest(t, test.method, test.upload, test.headers))
+
+			if diff := cmp.Diff(test.wantStatus, recorder.Code); diff != "" {
+				t.Errorf("Wrong status code: %s", diff)
+			}
+			if diff := cmp.Diff(test.wantETag, recorder.Header().Get("ETag")); diff != "" {
+				t.Errorf("Wrong ETag: %s", diff)
+			}
+			if diff := cmp.Diff(test.wantStored, mockQuizStore.stored["quizId"]); diff != "" {
+				t.Errorf("Wrong question set stored: %s", diff)
+			}
+		})
+	}
+}
+
+// Uploading the question set that's already stored, in any format, doesn't rewrite it.
+// Nothing is created so a POST gets a 200 like a PUT
+func TestUploadQuizHandler_unchanged_not_rewritten(t *testing.T) {
+	tests := map[string]struct {
+		method string
+	}{
+		"post": {method: http.MethodPost},
+		"put": {method: http.MethodPut},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			writes := 0
+			writeImpl := func(quizId string, qAndA *quiz.QuestionAndAnswers) error {
+				writes++
+				return nil
+			}
+			mockQuizStore := NewMockQuizStore(&writeImpl)
+			mockQuizStore.stored["quizId"] = qAndA
+			publisher := &mockPublisher{}
+			uploadServer := Upload {
+				QuizStore: mockQuizStore,
+				JwtParams: auth.JwtParams{Secret: "testsecret", Issuer: "go.test", Audience: "go.test"},
+				Publisher: publisher,
+				Logger: logrus.StandardLogger(),
+			}
+
+			csvFile := []byte("question,category,option,option,option,option,answers,points\nquestion 1,food,a,b,c,d,2;1,1\n")
+			body, contentTypeValue, err := buildReqBodyWithFile("file", "quiz.csv", csvFile)
+			if err != nil {
+				t.Fatalf("failed to create request body: %v", err)
+			}
+			req := buildAuthorizedRequest(t, test.method, body, uploadServer.JwtParams, "quizId")
+			req.Header.Add("Content-Type", contentTypeValue)
+
+			recorder := httptest.NewRecorder()
+			uploadServer.Quiz(recorder, req)
+
+			if diff := cmp.Diff(http.StatusOK, recorder.Code); diff != "" {
+				t.Errorf("Wrong status code: %s", diff)
+			}
+			if diff := cmp.Diff(mustETag(t, qAndA), recorder.Header().Get("ETag")); diff != "" {
+				t.Errorf("Wrong ETag: %s", diff)
+			}
+			if writes != 0 || len(publisher.published) != 0 {
+				t.Errorf("Expected the unchanged question set not to be written or published but got %d writes and %d events", writes, len(publisher.published))
+			}
+		})
+	}
+}
+
+// Retries with the same Idempotency-Key get the original result, even when the
+// preconditions would now fail
+func TestUploadQuizHandler_idempotency_key(t *testing.T) {
+	type request struct {
+		method		string
+		upload		quiz.QuestionAndAnswers
+		headers		map[string]string
+		wantStatus	int
+		wantReplay	bool
+	}
+	tests := map[string]struct {
+		store		idempotency.Store
+		requests	[]request
+	}{
+		"retried create only": {
+			idempotency.NewMemoryStore(),
+			[]request{
+				{http.MethodPost, qAndA, map[string]string{"Idempotency-Key": "k1", "If-None-Match": "*"}, http.StatusCreated, false},
+				{http.MethodPost, qAndA, map[string]string{"Idempotency-Key": "k1", "If-None-Match": "*"}, http.StatusCreated, true},
+				{http.MethodPost, qAndA, map[string]string{"Idempotency-Key": "k2", "If-None-Match": "*"}, http.StatusPreconditionFailed, false},
+			},
+		},
+		"key reused": {
+			idempotency.NewMemoryStore(),
+			[]request{
+				{http.MethodPost, qAndA, map[string]string{"Idempotency-Key": "k1"}, http.StatusCreated, false},
+				{http.MethodPost, replacement, map[string]string{"Idempotency-Key": "k1"}, http.StatusUnprocessableEntity, false},
+				{http.MethodPut, qAndA, map[string]string{"Idempotency-Key": "k1"}, http.StatusUnprocessableEntity, false},
+			},
+		},
+		"failed requests not recorded": {
+			idempotency.NewMemoryStore(),
+			[]request{
+				{http.MethodPut, qAndA, map[string]string{"Idempotency-Key": "k1"}, http.StatusNotFound, false},
+				{http.MethodPost, qAndA, map[string]string{"Idempotency-Key": "k1"}, http.StatusCreated, false},
+			},
+		},
+		"invalid key": {
+			idempotency.NewMemoryStore(),
+			[]request{
+				{http.MethodPost, qAndA, map[string]string{"Idempotency-Key": "k\x7f"}, http.StatusBadRequest, false},
+				{http.MethodPost, qAndA, map[string]string{"Idempotency-Key": strings.Repeat("k", maxIdempotencyKeyLength + 1)}, http.StatusBadRequest, false},
+			},
+		},
+		"store fails": {
+			failingIdempotencyStore{},
+			[]request{
+				{http.MethodPost, qAndA, map[string]string{"Idempotency-Key": "k1", "If-None-Match": "*"}, http.StatusCreated, false},
+				{http.MethodPost, qAndA, map[string]string{"Idempotency-Key": "k1", "If-None-Match": "*"}, http.StatusPreconditionFailed, false},
+			},
+		},
+		"no store": {
+			nil,
+			[]request{
+				{http.MethodPost, qAndA, map[string]string{"Idempotency-Key": "k1", "If-None-Match": "*"}, http.StatusCreated, false},
+				{http.MethodPost, qAndA, map[string]string{"Idempotency-Key": "k1", "If-None-Match": "*"}, http.StatusPreconditionFailed, false},
+			},
+		},
+	}
+
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			uploadServer := Upload {
+				QuizStore: NewMockQuizStore(nil),
+				JwtParams: auth.JwtParams{Secret: "testsecret", Issuer: "go.test", Audience: "go.test"},
+				Idempotency: Idempotency{Store: test.store},
+				Logger: logrus.StandardLogger(),
+			}
+
+			for i, r := range test.requests {
+				recorder := httptest.NewRecorder()
+				uploadServer.Quiz(recorder, buildConditionalRequest(t, r.method, r.upload, r.headers))
+
+				if recorder.Code != r.wantStatus {
+					t.Errorf("Request %d: expected status %d but got %d", i, r.wantStatus, recorder.Code)
+				}
+				if replayed := recorder.Header().Get("Idempotent-Replayed") == "true"; replayed != r.wantReplay {
+					t.Errorf("Request %d: expected replayed %t but got %t", i, r.wantReplay, replayed)
+				}
+				if r.wantReplay && recorder.Header().Get("ETag") != mustETag(t, r.upload) {
+					t.Errorf("Request %d: expected the original ETag but got '%s'", i, recorder.Header().Get("ETag"))
+				}
+			}
+		})
+	}
+}
+
+// Retries while the original request with the same Idempotency-Key is in progress
+// are rejected rather than handled a second time
+func TestUploadQuizHandler_idempotency_key_in_progress(t *testing.T) {
+	store := idempotency.NewMemoryStore()
+	pending := idempotency.Result{Method: http.MethodPost, ETag: mustETag(t, qAndA)}
+	if _, err := store.PutIfAbsent("quizId:k1", pending, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	mockQuizStore := NewMockQuizStore(nil)
+	uploadServer := Upload {
+		QuizStore: mockQuizStore,
+		JwtParams: auth.JwtParams{Secret: "testsecret", Issuer: "go.test", Audience: "go.test"},
+		Idempotency: Idempotency{Store: store},
+		Logger: logrus.StandardLogger(),
+	}
+
+	recorder := httptest.NewRecorder()
+	uploadServer.Quiz(recorder, buildConditionalRequest(t, http.MethodPost, qAndA, map[string]string{"Idempotency-Key": "k1"}))
+
+	if diff := cmp.Diff(http.StatusConflict, recorder.Code); diff != "" {
+		t.Errorf("Wrong status code: %s", diff)
+	}
+	if recorder.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected a 'Retry-After' header")
+	}
+	if mockQuizStore.quizId != "" {
+		t.Errorf("Expected the question set not to be written")
+	}
+	if result, _, _ := store.Get("quizId:k1"); !result.Pending() {
+		t.Errorf("Expected the original request's reservation to be kept but got %+v", result)
+	}
+}
+
+// Changes made by another request after the preconditions were checked aren't overwritten
+func TestUploadQuizHandler_concurrent_change(t *testing.T) {
+	tests := map[string]struct {
+		method		string
+		upload		quiz.QuestionAndAnswers
+		headers		map[string]string
+	}{
+		"replace": {http.MethodPut, replacement, map[string]string{"If-Match": mustETag(t, qAndA)}},
+		"create only": {http.MethodPost, replacement, map[string]string{"If-None-Match": "*"}},
+		"delete": {http.MethodDelete, nil, map[string]string{"If-Match": mustETag(t, qAndA)}},
+	}
+
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			mockQuizStore := NewMockQuizStore(nil)
+			other := quiz.QuestionAndAnswers{{Question: "other", Category: "food", Options: []string{"a", "b"}, Answers: []int{1}}}
+			if test.method != http.MethodPost {
+				mockQuizStore.stored["quizId"] = qAndA
+			}
+			mockQuizStore.afterRead = func() { mockQuizStore.stored["quizId"] = other }
+			uploadServer := Upload {
+				QuizStore: mockQuizStore,
+				JwtParams: auth.JwtParams{Secret: "testsecret", Issuer: "go.test", Audience: "go.test"},
+				Logger: logrus.StandardLogger(),
+			}
+
+			recorder := httptest.NewRecorder()
+			uploadServer.Quiz(recorder, buildConditionalRequest(t, test.method, test.upload, test.headers))
+
+			if diff := cmp.Diff(http.StatusPreconditionFailed, recorder.Code); diff != "" {
+				t.Errorf("Wrong status code: %s", diff)
+			}
+			if diff := cmp.Diff(other, mockQuizStore.stored["quizId"]); diff != "" {
+				t.Errorf("Expected the other request's question set to be kept: %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS policy. See https://developer.mozilla.org/en-US/docs/Web/HTTP/CORS
+type CorsOptions struct {
+	// Origins allowed to make requests e.g. "https://quiz.example.com". "*" allows any
+	// origin, except with AllowCredentials, and a '*' in a pattern matches within a host
+	// e.g. "https://*.example.com"
+	AllowedOrigins		[]string
+	AllowedMethods		[]string
+	// Request headers allowed in addition to the CORS-safelisted ones. "*" allows any
+	AllowedHeaders		[]string
+	AllowCredentials	bool
+	// How long browsers can cache the preflight response. Not sent when 0
+	MaxAge				time.Duration
+}
+
+// Response headers that browsers let scripts read in addition to the CORS-safelisted ones
+var corsExposedHeaders = []string{"ETag", "Idempotent-Replayed", "Retry-After"}
+
+// Applies the CORS policy to requests with an Origin header. Preflight requests are
+// answered here and never reach handler. No CORS headers are added for origins
+// that aren't allowed so the browser blocks the response
+func CorsMiddleware(o CorsOptions, handler func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		requestMethod := r.Header.Get("Access-Control-Request-Method")
+		isPreflight := r.Method == http.MethodOptions && requestMethod != ""
+
+		if origin == "" {
+			handler(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+
+		if !o.allowsOrigin(origin) {
+			if isPreflight {
+				WriteProblem(w, NewProblem(ProblemCorsRejected, fmt.Sprintf("Origin '%s' is not allowed", origin)))
+				return
+			}
+			handler(w, r)
+			return
+		}
+
+		if !isPreflight {
+			o.setAllowOrigin(w, origin)
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+			handler(w, r)
+			return
+		}
+
+		// Preflight. See https://developer.mozilla.org/en-US/docs/Web/HTTP/CORS#preflighted_requests
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		if !containsFold(o.AllowedMethods, requestMethod) {
+			WriteProblem(w, NewProblem(ProblemCorsRejected, fmt.Sprintf("Method '%s' is not allowed", requestMethod)))
+			return
+		}
+		requestHeaders := splitHeaderList(r.Header.Get("Access-Control-Request-Headers"))
+		if !containsFold(o.AllowedHeaders, "*") {
+			for _, header := range requestHeaders {
+				if !containsFold(o.AllowedHeaders, header) {
+					WriteProblem(w, NewProblem(ProblemCorsRejected, fmt.Sprintf("Header '%s' is not allowed", header)))
+					return
+				}
+			}
+		}
+
+		o.setAllowOrigin(w, origin)
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(o.AllowedMethods, ", "))
+		if len(requestHeaders) > 0 {
+			// Echo them back as a literal "*" isn't honoured for requests with credentials
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(requestHeaders, ", "))
+		}
+		if o.MaxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(o.MaxAge.Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (o CorsOptions) allowsOrigin(origin string) bool {
+	for _, pattern := range o.AllowedOrigins {
+		if pattern == "*" {
+			// Letting any site make requests with the user's credentials would defeat CORS
+			if !o.AllowCredentials {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(pattern, origin) {
+			return true
+		}
+		// '*' doesn't match the '/' in the scheme so can only match within the host
+		if matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(origin)); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (o CorsOptions) setAllowOrigin(w http.ResponseWriter, origin string) {
+	if containsFold(o.AllowedOrigins, "*") && !o.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		// Only origins matched by name get here, and are echoed as browsers reject "*"
+		// for requests with credentials
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if o.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// Splits a comma separated header value e.g. "authorization, content-type"
+func splitHeaderList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func containsFold(items []string, item string) bool {
+	for _, i := range items {
+		if strings.EqualFold(i, item) {
+			return true
+		}
+	}
+	return false
+}
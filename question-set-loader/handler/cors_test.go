@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCorsMiddleware(t *testing.T) {
+	options := CorsOptions{
+		AllowedOrigins: []string{"https://quiz.example.com", "https://*.staging.example.com"},
+		AllowedMethods: config.DefaultCorsMethods,
+		AllowedHeaders: config.DefaultCorsHeaders,
+		MaxAge: 10 * time.Minute,
+	}
+
+	tests := map[string]struct {
+		options CorsOptions
+		method string
+		headers map[string]string
+		wantStatus int
+		wantHeaders map[string]string
+		wantHandled bool // Whether the request reached the wrapped handler
+	}{
+		"no origin": {
+			options, http.MethodPost, map[string]string{},
+			http.StatusOK, map[string]string{"Access-Control-Allow-Origin": ""}, true,
+		},
+		"allowed origin": {
+			options, http.MethodPost, map[string]string{"Origin": "https://quiz.example.com"},
+			http.StatusOK, map[string]string{"Access-Control-Allow-Origin": "https://quiz.example.com", "Vary": "Origin"}, true,
+		},
+		"wildcard origin": {
+			options, http.MethodPost, map[string]string{"Origin": "https://pr-12.staging.example.com"},
+			http.StatusOK, map[string]string{"Access-Control-Allow-Origin": "https://pr-12.staging.example.com"}, true,
+		},
+		"wildcard doesn't match another domain": {
+			options, http.MethodPost, map[string]string{"Origin": "https://evil.com/.staging.example.com"},
+			http.StatusOK, map[string]string{"Access-Control-Allow-Origin": ""}, true,
+		},
+		"disallowed origin": {
+			options, http.MethodPost, map[string]string{"Origin": "https://evil.com"},
+			http.StatusOK, map[string]string{"Access-Control-Allow-Origin": ""}, true,
+		},
+		"preflight": {
+			options, http.MethodOptions, map[string]string{
+				"Origin": "https://quiz.example.com",
+				"Access-Control-Request-Method": "PUT",
+				"Access-Control-Request-Headers": "authorization",
+			},
+			http.StatusNoContent, map[string]string{
+				"Access-Control-Allow-Origin": "https://quiz.example.com",
+				"Access-Control-Allow-Methods": "GET, POST, PUT, DELETE",
+				"Access-Control-Allow-Headers": "authorization",
+				"Access-Control-Max-Age": "600",
+			}, false,
+		},
+		"preflight disallowed origin": {
+			options, http.MethodOptions, map[string]string{"Origin": "https://evil.com", "Access-Control-Request-Method": "POST"},
+			http.StatusForbidden, map[string]string{"Access-Control-Allow-Origin": "", "Content-Type": ProblemContentType}, false,
+		},
+		"preflight disallowed method": {
+			options, http.MethodOptions, map[string]string{"Origin": "https://quiz.example.com", "Access-Control-Request-Method": "PATCH"},
+			http.StatusForbidden, map[string]string{"Access-Control-Allow-Origin": ""}, false,
+		},
+		"preflight disallowed header": {
+			options, http.MethodOptions, map[string]string{
+				"Origin": "https://quiz.example.com",
+				"Access-Control-Request-Method": "POST",
+				"Access-Control-Request-Headers": "authorization, x-custom",
+			},
+			http.StatusForbidden, map[string]string{"Access-Control-Allow-Origin": ""}, false,
+		},
+		"any origin": {
+			CorsOptions{AllowedOrigins: []string{"*"}, AllowedMethods: config.DefaultCorsMethods}, http.MethodGet, map[string]string{"Origin": "http://localhost:3000"},
+			http.StatusOK, map[string]string{"Access-Control-Allow-Origin": "*"}, true,
+		},
+		"any origin with credentials": {
+			CorsOptions{AllowedOrigins: []string{"*"}, AllowedMethods: config.DefaultCorsMethods, AllowCredentials: true}, http.MethodGet, map[string]string{"Origin": "http://localhost:3000"},
+			http.StatusOK, map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Allow-Credentials": ""}, true,
+		},
+		"named origin with credentials": {
+			CorsOptions{AllowedOrigins: []string{"*", "https://quiz.example.com"}, AllowedMethods: config.DefaultCorsMethods, AllowCredentials: true}, http.MethodGet, map[string]string{"Origin": "https://quiz.example.com"},
+			http.StatusOK, map[string]string{"Access-Control-Allow-Origin": "https://quiz.example.com", "Access-Control-Allow-Credentials": "true"}, true,
+		},
+		"preflight any origin with credentials": {
+			CorsOptions{AllowedOrigins: []string{"*"}, AllowedMethods: config.DefaultCorsMethods, AllowCredentials: true}, http.MethodOptions, map[string]string{"Origin": "http://localhost:3000", "Access-Control-Request-Method": "POST"},
+			http.StatusForbidden, map[string]string{"Access-Control-Allow-Origin": ""}, false,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			handled := false
+			handler := CorsMiddleware(tc.options, func(w http.ResponseWriter, r *http.Request) {
+				handled = true
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tc.method, "/api/upload/quiz", nil)
+			for key, value := range tc.headers {
+				req.Header.Set(key, value)
+			}
+			recorder := httptest.NewRecorder()
+			handler(recorder, req)
+			response := recorder.Result()
+
+			if diff := cmp.Diff(tc.wantStatus, response.StatusCode); diff != "" {
+				t.Errorf("Wrong status code: %s", diff)
+			}
+			for key, want := range tc.wantHeaders {
+				if diff := cmp.Diff(want, response.Header.Get(key)); diff != "" {
+					t.Errorf("Wrong '%s' header: %s", key, diff)
+				}
+			}
+			if diff := cmp.Diff(tc.wantHandled, handled); diff != "" {
+				t.Errorf("Wrong handled: %s", diff)
+			}
+		})
+	}
+}
+
+// Tests the default CORS policy allows every method the quiz endpoint serves
+func TestDefaultCorsMethods(t *testing.T) {
+	if diff := cmp.Diff(quizMethods, config.DefaultCorsMethods); diff != "" {
+		t.Errorf("Default CORS methods differ from the quiz endpoint's: %s", diff)
+	}
+}
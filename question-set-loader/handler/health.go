@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Responds 200 while the process is up and serving requests
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok\n"))
+}
+
+// Responds 200 when every check passes, else 503 with the failing checks in the
+// problem detail. Each check is keyed by the name of what it checks e.g. "store"
+func Readyz(checks map[string]func() error) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		names := make([]string, 0, len(checks))
+		for name := range checks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		failures := []string{}
+		for _, name := range names {
+			if err := checks[name](); err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %s", name, err))
+			}
+		}
+		if len(failures) > 0 {
+			WriteProblem(w, NewProblem(ProblemNotReady, strings.Join(failures, "; ")))
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("ok\n"))
+	}
+}
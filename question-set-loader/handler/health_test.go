@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHealthz(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	Healthz(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected 200 but got %d", recorder.Code)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	ok := func() error { return nil }
+	fail := func() error { return errors.New("read-only file system") }
+
+	tests := map[string]struct {
+		checks map[string]func() error
+		wantStatus int
+		wantDetail string
+	}{
+		"no checks": {map[string]func() error{}, http.StatusOK, ""},
+		"passing": {map[string]func() error{"store": ok}, http.StatusOK, ""},
+		"failing": {
+			map[string]func() error{"store": fail, "cache": ok},
+			http.StatusServiceUnavailable, "store: read-only file system",
+		},
+		"several failing": {
+			map[string]func() error{"store": fail, "cache": fail},
+			http.StatusServiceUnavailable, "cache: read-only file system; store: read-only file system",
+		},
+	}
+
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			Readyz(test.checks)(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if recorder.Code != test.wantStatus {
+				t.Fatalf("Expected %d but got %d", test.wantStatus, recorder.Code)
+			}
+			if test.wantStatus == http.StatusOK {
+				return
+			}
+			var problem Problem
+			if err := json.Unmarshal(recorder.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("Failed to decode problem: %v", err)
+			}
+			if diff := cmp.Diff(NewProblem(ProblemNotReady, test.wantDetail), problem); diff != "" {
+				t.Errorf("Wrong problem: %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/audit"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/quiz"
+)
+
+// Path the library is served under. The entries are at '<LibraryPath>/<id>'
+const LibraryPath = "/api/upload/library"
+
+// Handles the library of question sets. GET on LibraryPath lists the entries and
+// POST on an entry copies it to the quiz in the hosts token, in place of uploading it
+func (u *Upload) Library(w http.ResponseWriter, r *http.Request) {
+
+	libraryId := strings.Trim(strings.TrimPrefix(r.URL.Path, LibraryPath), "/")
+	allowed := http.MethodPost
+	if libraryId == "" {
+		allowed = http.MethodGet
+	}
+	if r.Method != allowed {
+		w.Header().Set("Allow", allowed)
+		WriteProblem(w, NewProblem(ProblemMethodNotAllowed, fmt.Sprintf("Unexpected HTTP method '%s'", r.Method)))
+		return
+	}
+
+	host, ok := u.admit(w, r)
+	if !ok {
+		return
+	}
+
+	if libraryId == "" {
+		u.listLibrary(w)
+		return
+	}
+	entry, aw := u.startAudit(w, r, host)
+	u.importFromLibrary(aw, r, host.QuizId, libraryId, entry)
+	u.finishAudit(entry, aw)
+}
+
+// Returns the summary of every entry in the library
+func (u *Upload) listLibrary(w http.ResponseWriter) {
+	entries, skipped, err := u.QuizLibrary.List()
+	if err != nil {
+		u.Logger.Error(fmt.Sprintf("Failed to list the library. Error: %s", err))
+		WriteProblem(w, NewProblem(ProblemInternalError, "Couldn't list the library"))
+		return
+	}
+	for name, err := range skipped {
+		u.Logger.Warn(fmt.Sprintf("Skipped invalid library file '%s'. Error: %s", name, err))
+	}
+
+	writeJson(w, http.StatusOK, entries)
+}
+
+// Writes the library entry as the question set for the quiz, with the same preconditions
+// and idempotency key handling as uploads. Its content hash is recorded in entry
+func (u *Upload) importFromLibrary(w http.ResponseWriter, r *http.Request, quizId string, libraryId string, entry *audit.Entry) {
+
+	u.Logger.Info(fmt.Sprintf("Received import of library entry '%s' for quiz '%s'", libraryId, quizId))
+
+	key, ok := idempotencyKey(w, r)
+	if !ok {
+		return
+	}
+
+	qAndA, err := u.QuizLibrary.Get(libraryId)
+	if errors.Is(err, quiz.ErrLibraryEntryNotFound) {
+		WriteProblem(w, NewProblem(ProblemLibraryEntryNotFound, fmt.Sprintf("No library entry '%s'", libraryId)))
+		return
+	}
+	if err != nil {
+		u.Logger.Error(fmt.Sprintf("Failed to read library entry '%s'. Error: %s", libraryId, err))
+		WriteProblem(w, NewProblem(ProblemInternalError, fmt.Sprintf("Couldn't read library entry '%s'", libraryId)))
+		return
+	}
+	etag, err := etagOf(qAndA)
+	if err != nil {
+		u.Logger.Error(fmt.Sprintf("Failed to hash library entry '%s'. Error: %s", libraryId, err))
+		WriteProblem(w, NewProblem(ProblemInternalError, "Couldn't hash the question set"))
+		return
+	}
+	entry.ContentHash = strings.Trim(etag, `"`)
+	u.saveQuiz(w, r, quizId, key, qAndA, etag)
+}
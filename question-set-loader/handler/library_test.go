@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/auth"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/idempotency"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/quiz"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+)
+
+func buildLibraryServer(t *testing.T, jwtParams auth.JwtParams) (Upload, *mockQuizStore) {
+	directory := t.TempDir()
+	data, err := json.Marshal(qAndA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(directory, "general-knowledge.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mockQuizStore := NewMockQuizStore(nil)
+	return Upload {
+		QuizStore: mockQuizStore,
+		JwtParams: jwtParams,
+		QuizLibrary: quiz.QuizLibrary{Directory: directory},
+		Publisher: &mockPublisher{},
+		Logger: logrus.StandardLogger(),
+	}, mockQuizStore
+}
+
+// Tests listing the library
+func TestLibraryHandler_list(t *testing.T) {
+	jwtParams := auth.JwtParams{
+		Secret: "testsecret",
+		Issuer: "go.test",
+		Audience: "go.test",
+	}
+	uploadServer, _ := buildLibraryServer(t, jwtParams)
+
+	req := buildAuthorizedRequest(t, http.MethodGet, nil, jwtParams, "quizId")
+	req.URL.Path = LibraryPath
+	recorder := httptest.NewRecorder()
+	uploadServer.Library(recorder, req)
+
+	if diff := cmp.Diff(recorder.Code, http.StatusOK); diff != "" {
+		t.Fatalf("Wrong status code: %s", diff)
+	}
+	var got []quiz.LibraryEntry
+	if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	want := []quiz.LibraryEntry{{Id: "general-knowledge", Name: "General knowledge", Categories: []string{"food"}, QuestionCount: len(qAndA)}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Wrong library entries: %s", diff)
+	}
+}
+
+// Tests attaching a library entry to a quiz
+func TestLibraryHandler_import(t *testing.T) {
+	jwtParams := auth.JwtParams{
+		Secret: "testsecret",
+		Issuer: "go.test",
+		Audience: "go.test",
+	}
+	quizId := "quizId"
+
+	tests := map[string]struct {
+		method string
+		path string
+		authorized bool
+		wantStatus int
+		wantWritten bool
+	}{
+		"import": {http.MethodPost, LibraryPath + "/general-knowledge", true, http.StatusCreated, true},
+		"missing entry": {http.MethodPost, LibraryPath + "/missing", true, http.StatusNotFound, false},
+		"path traversal": {http.MethodPost, LibraryPath + "/..%2Fsecrets", true, http.StatusNotFound, false},
+		"unauthorized": {http.MethodPost, LibraryPath + "/general-knowledge", false, http.StatusUnauthorized, false},
+		"wrong method on entry": {http.MethodGet, LibraryPath + "/general-knowledge", true, http.StatusMethodNotAllowed, false},
+		"wrong method on list": {http.MethodPost, LibraryPath, true, http.StatusMethodNotAllowed, false},
+	}
+
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			uploadServer, mockQuizStore := buildLibraryServer(t, jwtParams)
+
+			req := httptest.NewRequest(test.method, test.path, nil)
+			if test.authorized {
+				authorized := buildAuthorizedRequest(t, test.method, nil, jwtParams, quizId)
+				req.Header.Set("Authorization", authorized.Header.Get("Authorization"))
+			}
+			recorder := httptest.NewRecorder()
+			uploadServer.Library(recorder, req)
+
+			if diff := cmp.Diff(recorder.Code, test.wantStatus); diff != "" {
+				t.Fatalf("Wrong status code: %s", diff)
+			}
+			if !test.wantWritten {
+				if mockQuizStore.quizId != "" {
+					t.Errorf("Question set was written for quiz '%s'", mockQuizStore.quizId)
+				}
+				return
+			}
+			if diff := cmp.Diff(mockQuizStore.stored[quizId], qAndA); diff != "" {
+				t.Errorf("Wrong question set written: %s", diff)
+			}
+			published := uploadServer.Publisher.(*mockPublisher).published
+			if len(published) != 1 || published[0].Data.QuizId != quizId {
+				t.Errorf("Expected a question set ready event for quiz '%s' but got %+v", quizId, published)
+			}
+		})
+	}
+}
+
+// Tests imports honour the preconditions and idempotency key like uploads
+func TestLibraryHandler_import_conditional(t *testing.T) {
+	jwtParams := auth.JwtParams{
+		Secret: "testsecret",
+		Issuer: "go.test",
+		Audience: "go.test",
+	}
+	type request struct {
+		headers		map[string]string
+		wantStatus	int
+		wantReplay	bool
+	}
+	tests := map[string]struct {
+		stored		quiz.QuestionAndAnswers
+		requests	[]request
+	}{
+		"create only": {nil, []request{
+			{map[string]string{"If-None-Match": "*"}, http.StatusCreated, false},
+			{map[string]string{"If-None-Match": "*"}, http.StatusPreconditionFailed, false},
+		}},
+		"create only over existing": {replacement, []request{
+			{map[string]string{"If-None-Match": "*"}, http.StatusPreconditionFailed, false},
+		}},
+		"if match": {replacement, []request{
+			{map[string]string{"If-Match": `"sha256:other"`}, http.StatusPreconditionFailed, false},
+			{map[string]string{"If-Match": mustETag(t, replacement)}, http.StatusCreated, false},
+		}},
+		"retried": {nil, []request{
+			{map[string]string{"Idempotency-Key": "k1", "If-None-Match": "*"}, http.StatusCreated, false},
+			{map[string]string{"Idempotency-Key": "k1", "If-None-Match": "*"}, http.StatusCreated, true},
+		}},
+		"invalid key": {nil, []request{
+			{map[string]string{"Idempotency-Key": "k\x7f"}, http.StatusBadRequest, false},
+		}},
+	}
+
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			uploadServer, mockQuizStore := buildLibraryServer(t, jwtParams)
+			uploadServer.Idempotency = Idempotency{Store: idempotency.NewMemoryStore()}
+			if test.stored != nil {
+				mockQuizStore.stored["quizId"] = test.stored
+			}
+
+			wantPublished := 0
+			for i, r := range test.requests {
+				if r.wantStatus == http.StatusCreated && !r.wantReplay {
+					wantPublished++
+				}
+				req := buildAuthorizedRequest(t, http.MethodPost, nil, jwtParams, "quizId")
+				req.URL.Path = LibraryPath + "/general-knowledge"
+				for name, value := range r.headers {
+					req.Header.Set(name, value)
+				}
+				recorder := httptest.NewRecorder()
+				uploadServer.Library(recorder, req)
+
+				if recorder.Code != r.wantStatus {
+					t.Errorf("Request %d: expected status %d but got %d", i, r.wantStatus, recorder.Code)
+				}
+				if replayed := recorder.Header().Get("Idempotent-Replayed") == "true"; replayed != r.wantReplay {
+					t.Errorf("Request %d: expected replayed %t but got %t", i, r.wantReplay, replayed)
+				}
+			}
+			published := uploadServer.Publisher.(*mockPublisher).published
+			if len(published) != wantPublished {
+				t.Errorf("Expected %d question set ready events but got %d", wantPublished, len(published))
+			}
+		})
+	}
+}
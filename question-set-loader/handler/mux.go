@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Path of the quiz endpoint
+const QuizPath = "/api/upload/quiz"
+
+// Routes the API endpoints. The container and the Lambda both serve this so that
+// every endpoint behaves the same in either deployment
+func NewServeMux(upload *Upload, cors CorsOptions, logger *log.Logger) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc(QuizPath, LoggerMiddleware(logger, CorsMiddleware(cors, upload.Quiz)))
+	mux.HandleFunc(ValidatePath, LoggerMiddleware(logger, CorsMiddleware(cors, upload.Validate)))
+	mux.HandleFunc(CategoriesPath, LoggerMiddleware(logger, CorsMiddleware(cors, upload.Categories)))
+
+	library := LoggerMiddleware(logger, CorsMiddleware(cors, upload.Library))
+	mux.HandleFunc(LibraryPath, library)
+	mux.HandleFunc(LibraryPath + "/", library)
+	return mux
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/quiz"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Identifies the kind of problem. These are stable so clients can switch on them
+type ProblemType string
+
+const (
+	ProblemMethodNotAllowed			ProblemType = "urn:mc-speedrun:problem:method-not-allowed"
+	ProblemMissingAuthorization		ProblemType = "urn:mc-speedrun:problem:missing-authorization"
+	ProblemInvalidAuthorization		ProblemType = "urn:mc-speedrun:problem:invalid-authorization"
+	ProblemInvalidToken				ProblemType = "urn:mc-speedrun:problem:invalid-token"
+	ProblemTokenRevoked				ProblemType = "urn:mc-speedrun:problem:token-revoked"
+	ProblemQuizFinished				ProblemType = "urn:mc-speedrun:problem:quiz-finished"
+	ProblemRevocationUnavailable	ProblemType = "urn:mc-speedrun:problem:revocation-unavailable"
+	ProblemQuestionSetNotFound		ProblemType = "urn:mc-speedrun:problem:question-set-not-found"
+	ProblemLibraryEntryNotFound		ProblemType = "urn:mc-speedrun:problem:library-entry-not-found"
+	ProblemUnsupportedMediaType		ProblemType = "urn:mc-speedrun:problem:unsupported-media-type"
+	ProblemMissingFile				ProblemType = "urn:mc-speedrun:problem:missing-file"
+	ProblemFileTooLarge				ProblemType = "urn:mc-speedrun:problem:file-too-large"
+	ProblemInvalidQuestionSet		ProblemType = "urn:mc-speedrun:problem:invalid-question-set"
+	ProblemStorageFailure			ProblemType = "urn:mc-speedrun:problem:storage-failure"
+	ProblemInternalError			ProblemType = "urn:mc-speedrun:problem:internal-error"
+	ProblemCorsRejected				ProblemType = "urn:mc-speedrun:problem:cors-rejected"
+	ProblemNotReady					ProblemType = "urn:mc-speedrun:problem:not-ready"
+	ProblemRateLimited				ProblemType = "urn:mc-speedrun:problem:rate-limited"
+	ProblemPreconditionFailed		ProblemType = "urn:mc-speedrun:problem:precondition-failed"
+	ProblemInvalidIdempotencyKey	ProblemType = "urn:mc-speedrun:problem:invalid-idempotency-key"
+	ProblemIdempotencyKeyReused		ProblemType = "urn:mc-speedrun:problem:idempotency-key-reused"
+	ProblemIdempotencyKeyInUse		ProblemType = "urn:mc-speedrun:problem:idempotency-key-in-use"
+	ProblemInvalidQuery				ProblemType = "urn:mc-speedrun:problem:invalid-query"
+)
+
+// Title and status code of each problem type
+var problemDefinitions = map[ProblemType]struct {
+	title	string
+	status	int
+}{
+	ProblemMethodNotAllowed:		{"Method not allowed", http.StatusMethodNotAllowed},
+	ProblemMissingAuthorization:	{"Missing authorization", http.StatusUnauthorized},
+	ProblemInvalidAuthorization:	{"Invalid authorization header", http.StatusUnauthorized},
+	ProblemInvalidToken:			{"Invalid token", http.StatusUnauthorized},
+	ProblemTokenRevoked:			{"Token revoked", http.StatusUnauthorized},
+	ProblemQuizFinished:			{"Quiz finished", http.StatusUnauthorized},
+	ProblemRevocationUnavailable:	{"Revocation check unavailable", http.StatusServiceUnavailable},
+	ProblemQuestionSetNotFound:		{"Question set not found", http.StatusNotFound},
+	ProblemLibraryEntryNotFound:	{"Library entry not found", http.StatusNotFound},
+	ProblemUnsupportedMediaType:	{"Unsupported media type", http.StatusUnsupportedMediaType},
+	ProblemMissingFile:				{"Missing file", http.StatusBadRequest},
+	ProblemFileTooLarge:			{"File too large", http.StatusRequestEntityTooLarge},
+	ProblemInvalidQuestionSet:		{"Invalid question set", http.StatusBadRequest},
+	ProblemStorageFailure:			{"Storage failure", http.StatusInternalServerError},
+	ProblemInternalError:			{"Internal error", http.StatusInternalServerError},
+	ProblemCorsRejected:			{"Cross-origin request rejected", http.StatusForbidden},
+	ProblemNotReady:				{"Not ready", http.StatusServiceUnavailable},
+	ProblemRateLimited:				{"Too many requests", http.StatusTooManyRequests},
+	ProblemPreconditionFailed:		{"Precondition failed", http.StatusPreconditionFailed},
+	ProblemInvalidIdempotencyKey:	{"Invalid idempotency key", http.StatusBadRequest},
+	ProblemIdempotencyKeyReused:	{"Idempotency key reused", http.StatusUnprocessableEntity},
+	ProblemIdempotencyKeyInUse:		{"Idempotency key in use", http.StatusConflict},
+	ProblemInvalidQuery:			{"Invalid query", http.StatusBadRequest},
+}
+
+// Problem details document sent for every failed request. See https://datatracker.ietf.org/doc/html/rfc7807
+type Problem struct {
+	Type	ProblemType				`json:"type"`
+	Title	string					`json:"title"`
+	Status	int						`json:"status"`
+	Detail	string					`json:"detail,omitempty"`
+	Errors	quiz.ValidationErrors	`json:"errors,omitempty"` // Every problem with an invalid question set
+}
+
+// Returns the problem of type with the detail for this occurrence
+func NewProblem(problemType ProblemType, detail string) Problem {
+	definition := problemDefinitions[problemType]
+	return Problem{
+		Type: problemType,
+		Title: definition.title,
+		Status: definition.status,
+		Detail: detail,
+	}
+}
+
+// Writes the problem as the response. Used by the Lambda too so that its own errors
+// are the same documents as the API's
+func WriteProblem(w http.ResponseWriter, problem Problem) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(problem)
+}
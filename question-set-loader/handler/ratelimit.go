@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/ratelimit"
+)
+
+// Rate limits on the quiz endpoint. Nothing is limited without a Limiter and limits
+// that aren't enabled are skipped
+type RateLimits struct {
+	Limiter				ratelimit.Limiter
+	Client				ratelimit.Limit	// Keyed by the client's IP
+	Quiz				ratelimit.Limit	// Keyed by the quizId in the token
+	// Take the client's IP from the last 'X-Forwarded-For' entry. Only set this when
+	// behind a proxy that appends to it, otherwise clients can pick their own IP
+	TrustForwardedFor	bool
+}
+
+// Takes a token from the bucket for key. When it's empty the 429 response is written
+// and ok is false. Requests are let through if the limiter fails so that uploads
+// don't depend on it
+func (u *Upload) throttle(w http.ResponseWriter, key string, limit ratelimit.Limit, detail string) (ok bool) {
+	if u.RateLimits.Limiter == nil || !limit.Enabled() {
+		return true
+	}
+
+	allowed, retryAfter, err := u.RateLimits.Limiter.Allow(key, limit)
+	if err != nil {
+		u.Logger.Error(err.Error())
+		return true
+	}
+	if !allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		WriteProblem(w, NewProblem(ProblemRateLimited, detail))
+		return false
+	}
+	return true
+}
+
+// Returns the IP address of the client that made the request
+func clientIP(r *http.Request, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		forwardedFor := splitHeaderList(strings.Join(r.Header.Values("X-Forwarded-For"), ","))
+		if len(forwardedFor) > 0 {
+			return forwardedFor[len(forwardedFor)-1]
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/auth"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/ratelimit"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+)
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(key string, limit ratelimit.Limit) (bool, time.Duration, error) {
+	return false, 0, errors.New("connection refused")
+}
+
+func TestUploadQuizHandler_rate_limited(t *testing.T) {
+	jwtParams := auth.JwtParams{
+		Secret: "testsecret",
+		Issuer: "go.test",
+		Audience: "go.test",
+	}
+
+	type request struct {
+		remoteAddr string
+		forwardedFor string
+		quizId string
+		wantStatus int
+	}
+	tests := map[string]struct {
+		rateLimits RateLimits
+		requests []request
+	}{
+		"per quiz": {
+			RateLimits{Limiter: ratelimit.NewMemoryLimiter(), Quiz: ratelimit.PerMinute(1, 2)},
+			[]request{
+				{"10.0.0.1:1234", "", "quiz1", http.StatusNotFound},
+				{"10.0.0.2:1234", "", "quiz1", http.StatusNotFound},
+				{"10.0.0.3:1234", "", "quiz1", http.StatusTooManyRequests},
+				{"10.0.0.3:1234", "", "quiz2", http.StatusNotFound},
+			},
+		},
+		"per client": {
+			RateLimits{Limiter: ratelimit.NewMemoryLimiter(), Client: ratelimit.PerMinute(1, 1)},
+			[]request{
+				{"10.0.0.1:1234", "", "quiz1", http.StatusNotFound},
+				{"10.0.0.1:5678", "", "quiz2", http.StatusTooManyRequests},
+				{"10.0.0.2:1234", "", "quiz1", http.StatusNotFound},
+			},
+		},
+		"forwarded for ignored": {
+			RateLimits{Limiter: ratelimit.NewMemoryLimiter(), Client: ratelimit.PerMinute(1, 1)},
+			[]request{
+				{"10.0.0.1:1234", "203.0.113.1", "quiz1", http.StatusNotFound},
+				{"10.0.0.1:1234", "203.0.113.2", "quiz1", http.StatusTooManyRequests},
+			},
+		},
+		"forwarded for trusted": {
+			RateLimits{Limiter: ratelimit.NewMemoryLimiter(), Client: ratelimit.PerMinute(1, 1), TrustForwardedFor: true},
+			[]request{
+				{"10.0.0.1:1234", "203.0.113.1", "quiz1", http.StatusNotFound},
+				{"10.0.0.1:1234", "203.0.113.2", "quiz1", http.StatusNotFound},
+				{"10.0.0.1:1234", "198.51.100.7, 203.0.113.2", "quiz1", http.StatusTooManyRequests},
+			},
+		},
+		"limiter failing": {
+			RateLimits{Limiter: failingLimiter{}, Client: ratelimit.PerMinute(1, 1), Quiz: ratelimit.PerMinute(1, 1)},
+			[]request{
+				{"10.0.0.1:1234", "", "quiz1", http.StatusNotFound},
+				{"10.0.0.1:1234", "", "quiz1", http.StatusNotFound},
+			},
+		},
+	}
+
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			uploadServer := Upload {
+				QuizStore: NewMockQuizStore(nil),
+				JwtParams: jwtParams,
+				RateLimits: test.rateLimits,
+				Logger: logrus.StandardLogger(),
+			}
+
+			for i, r := range test.requests {
+				req := buildAuthorizedRequest(t, http.MethodGet, nil, jwtParams, r.quizId)
+				req.RemoteAddr = r.remoteAddr
+				if r.forwardedFor != "" {
+					req.Header.Set("X-Forwarded-For", r.forwardedFor)
+				}
+				recorder := httptest.NewRecorder()
+				uploadServer.Quiz(recorder, req)
+
+				if diff := cmp.Diff(recorder.Code, r.wantStatus); diff != "" {
+					t.Fatalf("Request %d: wrong status code: %s", i, diff)
+				}
+				if r.wantStatus == http.StatusTooManyRequests {
+					if retryAfter := recorder.Header().Get("Retry-After"); retryAfter != "60" {
+						t.Errorf("Request %d: expected Retry-After of 60 but got '%s'", i, retryAfter)
+					}
+				}
+			}
+		})
+	}
+}
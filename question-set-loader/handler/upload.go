@@ -1,111 +1,421 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/audit"
 	"github.com/Ryangwaite/mc-speedrun/question-set-loader/auth"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/publish"
 	"github.com/Ryangwaite/mc-speedrun/question-set-loader/quiz"
 	log "github.com/sirupsen/logrus"
 )
 
 const MaxFileSize int64 = 10 * (1 << 20)  // 10 MiB
 
+// Allowance on top of MaxFileSize for the multipart boundaries, part headers and
+// any other form fields sent with the file
+const maxFormOverhead int64 = 64 * (1 << 10) // 64 KiB
+
+// Whether err is from reading past an http.MaxBytesReader's limit. The error isn't
+// exported before go 1.19's http.MaxBytesError so it's matched by its message, which
+// the parsers and the multipart reader may have wrapped
+func isTooLarge(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "http: request body too large")
+}
+
 type Upload struct {
-	DevelopmentMode 	bool
-	QuizWriter 			quiz.QuizWriter
+	QuizStore 			quiz.QuizStore
 	auth.JwtParams
+	RateLimits			RateLimits
+	Idempotency			Idempotency
+	QuizLibrary			quiz.QuizLibrary
+	// Told when a question set has been stored. Nothing is published when nil
+	Publisher			publish.Publisher
+	// Records every upload, replacement and deletion. Nothing is recorded when nil
+	Audit				audit.Sink
 	Logger				*log.Logger
 }
 
+// How long publishing the question set ready event can hold up the response
+const publishTimeout = 2 * time.Second
+
+// HTTP methods supported on the quiz endpoint
+var quizMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}
+
+// Handles the question set for the quiz in the hosts token. POST uploads it, PUT replaces
+// an existing one, GET returns what's stored and DELETE removes it. Each responds with
+// the question set's 'ETag' and honours 'If-Match' and 'If-None-Match'
 func (u *Upload) Quiz(w http.ResponseWriter, r *http.Request) {
 
-	if u.DevelopmentMode {
-		// Enable CORS from any host
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-		// Handle CORS pre-flight request from browsers . See https://developer.mozilla.org/en-US/docs/Web/HTTP/CORS#preflighted_requests
-		if r.Method == "OPTIONS" {
-			requestMethod := r.Header.Get("Access-Control-Request-Method")
-			origin := r.Header.Get("Origin")
-			if requestMethod == "POST" {
-				// Let the clients subsequent request through
-				w.Header().Set("Access-Control-Allow-Headers", "Authorization")
-				w.WriteHeader(http.StatusNoContent)
-				u.Logger.Info(fmt.Sprintf("Received preflight request from origin '%s'", origin))
-				return
-			}
-		}
+	if !isQuizMethod(r.Method) {
+		w.Header().Set("Allow", strings.Join(quizMethods, ", "))
+		WriteProblem(w, NewProblem(ProblemMethodNotAllowed, fmt.Sprintf("Unexpected HTTP method '%s'", r.Method)))
+		return
 	}
 
-	if r.Method != "POST" {
-		http.Error(w, fmt.Sprintf("Unexpected HTTP method '%s'", r.Method), http.StatusUnauthorized)
+	host, ok := u.admit(w, r)
+	if !ok {
 		return
 	}
 
-	// Authentication
+	if r.Method == http.MethodGet {
+		u.readQuiz(w, r, host.QuizId)
+		return
+	}
+
+	entry, aw := u.startAudit(w, r, host)
+	if r.Method == http.MethodDelete {
+		u.deleteQuiz(aw, r, host.QuizId, entry)
+	} else {
+		u.writeQuiz(aw, r, host.QuizId, entry)
+	}
+	u.finishAudit(entry, aw)
+}
+
+func isQuizMethod(method string) bool {
+	for _, quizMethod := range quizMethods {
+		if method == quizMethod {
+			return true
+		}
+	}
+	return false
+}
+
+// Applies the rate limits and validates the hosts token. Returns the claims from the
+// token. On failure the error response is written and ok is false
+func (u *Upload) admit(w http.ResponseWriter, r *http.Request) (host auth.HostClaims, ok bool) {
+	// Limit clients before the token is checked so that floods are cheap to turn away
+	ip := clientIP(r, u.RateLimits.TrustForwardedFor)
+	if !u.throttle(w, "client:" + ip, u.RateLimits.Client, fmt.Sprintf("Too many requests from '%s'", ip)) {
+		return auth.HostClaims{}, false
+	}
+
+	host, ok = u.authenticate(w, r)
+	if !ok {
+		return auth.HostClaims{}, false
+	}
 
+	if !u.throttle(w, "quiz:" + host.QuizId, u.RateLimits.Quiz, fmt.Sprintf("Too many requests for quiz '%s'", host.QuizId)) {
+		return auth.HostClaims{}, false
+	}
+	return host, true
+}
+
+// Validates the hosts bearer token and returns the claims from it. On failure the
+// error response is written and ok is false
+func (u *Upload) authenticate(w http.ResponseWriter, r *http.Request) (host auth.HostClaims, ok bool) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "Absent 'Authorization' header", http.StatusUnauthorized)
-		return
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		WriteProblem(w, NewProblem(ProblemMissingAuthorization, "Absent 'Authorization' header"))
+		return auth.HostClaims{}, false
 	}
 
 	authHeaderParts := strings.Fields(authHeader)
 	if len(authHeaderParts) != 2 || authHeaderParts[0] != "Bearer" {
-		http.Error(w, fmt.Sprintf("Invalid 'Authorization' header value '%s'", authHeader), http.StatusUnauthorized)
-		return
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		WriteProblem(w, NewProblem(ProblemInvalidAuthorization, "Invalid 'Authorization' header value, it must be of the form 'Bearer <token>'"))
+		return auth.HostClaims{}, false
 	}
 
 	jwtToken := authHeaderParts[1]
-	quizId, err := auth.ValidateJwt(jwtToken, u.JwtParams)
+	host, err := auth.ValidateHostJwt(jwtToken, u.JwtParams)
+	switch {
+	case errors.Is(err, auth.ErrTokenRevoked):
+		WriteProblem(w, NewProblem(ProblemTokenRevoked, "Token has been revoked"))
+		return auth.HostClaims{}, false
+	case errors.Is(err, auth.ErrQuizFinished):
+		WriteProblem(w, NewProblem(ProblemQuizFinished, "Quiz has already finished"))
+		return auth.HostClaims{}, false
+	case errors.Is(err, auth.ErrRevocationCheckFailed):
+		u.Logger.Error(err.Error())
+		WriteProblem(w, NewProblem(ProblemRevocationUnavailable, "Couldn't check whether the token has been revoked"))
+		return auth.HostClaims{}, false
+	case err != nil:
+		// The token isn't echoed back so it doesn't end up in client or proxy logs
+		u.Logger.Info(fmt.Sprintf("Rejected token. Error: %s", err))
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		WriteProblem(w, NewProblem(ProblemInvalidToken, "Token is invalid or has expired"))
+		return auth.HostClaims{}, false
+	}
+
+	return host, true
+}
+
+// Returns the stored question set and its entity tag, which are empty when there isn't
+// one. On failure the error response is written and ok is false
+func (u *Upload) currentQuiz(w http.ResponseWriter, quizId string) (qAndA quiz.QuestionAndAnswers, etag string, ok bool) {
+	qAndA, etag, _, ok = u.currentVersion(w, quizId)
+	return qAndA, etag, ok
+}
+
+// Same as currentQuiz but also returns the store's version of the question set, which
+// a change can be made conditional on
+func (u *Upload) currentVersion(w http.ResponseWriter, quizId string) (qAndA quiz.QuestionAndAnswers, etag string, version string, ok bool) {
+	qAndA, version, err := u.QuizStore.ReadVersion(quizId)
+	if errors.Is(err, quiz.ErrQuizNotFound) {
+		return nil, "", "", true
+	}
+	if err == nil {
+		etag, err = etagOf(qAndA)
+	}
+	if err != nil {
+		u.storageFailure(w, "read", quizId, err)
+		return nil, "", "", false
+	}
+	return qAndA, etag, version, true
+}
+
+// Writes the question set. When the request has preconditions, it's only written if the
+// stored question set is still version, the one they were checked against, so that a
+// change made in between isn't lost. On failure the error response is written and ok
+// is false
+func (u *Upload) storeQuiz(w http.ResponseWriter, r *http.Request, quizId string, qAndA *quiz.QuestionAndAnswers, version string) (ok bool) {
+	var err error
+	if hasPreconditions(r) {
+		err = u.QuizStore.WriteIfVersion(quizId, qAndA, version)
+	} else {
+		err = u.QuizStore.Write(quizId, qAndA)
+	}
+	if errors.Is(err, quiz.ErrVersionMismatch) {
+		WriteProblem(w, NewProblem(ProblemPreconditionFailed, fmt.Sprintf("Question set for quiz '%s' was changed by another request", quizId)))
+		return false
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Invalid token '%s'", jwtToken), http.StatusUnauthorized)
+		u.storageFailure(w, "write", quizId, err)
+		return false
+	}
+	return true
+}
+
+// Returns the stored question set as JSON
+func (u *Upload) readQuiz(w http.ResponseWriter, r *http.Request, quizId string) {
+	qAndA, etag, ok := u.currentQuiz(w, quizId)
+	if !ok {
+		return
+	}
+	if etag == "" {
+		WriteProblem(w, NewProblem(ProblemQuestionSetNotFound, fmt.Sprintf("No question set uploaded for quiz '%s'", quizId)))
+		return
+	}
+	if !checkPreconditions(w, r, quizId, etag) {
 		return
 	}
 
+	w.Header().Set("ETag", etag)
+	writeJson(w, http.StatusOK, qAndA)
+}
+
+// Removes the stored question set. Its content hash is recorded in entry
+func (u *Upload) deleteQuiz(w http.ResponseWriter, r *http.Request, quizId string, entry *audit.Entry) {
+	_, etag, version, ok := u.currentVersion(w, quizId)
+	if !ok {
+		return
+	}
+	entry.ContentHash = strings.Trim(etag, `"`)
+	if !checkPreconditions(w, r, quizId, etag) {
+		return
+	}
+
+	var err error
+	switch {
+	case !hasPreconditions(r):
+		err = u.QuizStore.Delete(quizId)
+	case version == "":
+		err = quiz.ErrQuizNotFound
+	default:
+		// Only deleted if it's still the question set the preconditions were checked against
+		err = u.QuizStore.DeleteIfVersion(quizId, version)
+	}
+	if errors.Is(err, quiz.ErrQuizNotFound) {
+		WriteProblem(w, NewProblem(ProblemQuestionSetNotFound, fmt.Sprintf("No question set uploaded for quiz '%s'", quizId)))
+		return
+	}
+	if errors.Is(err, quiz.ErrVersionMismatch) {
+		WriteProblem(w, NewProblem(ProblemPreconditionFailed, fmt.Sprintf("Question set for quiz '%s' was changed by another request", quizId)))
+		return
+	}
+	if err != nil {
+		u.storageFailure(w, "delete", quizId, err)
+		return
+	}
+	u.Logger.Info(fmt.Sprintf("Deleted quiz '%s'", quizId))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Validates the uploaded file and stores it. POST creates the question set, PUT
+// replaces one that was previously uploaded. A question set that's the same as the
+// stored one isn't rewritten, and a retry of a request with an 'Idempotency-Key' gets
+// the original response. The uploaded question set's content hash is recorded in entry
+func (u *Upload) writeQuiz(w http.ResponseWriter, r *http.Request, quizId string, entry *audit.Entry) {
+
 	u.Logger.Info(fmt.Sprintf("Received quiz upload for id '%s'", quizId))
 
-	// Validate Payload
+	key, ok := idempotencyKey(w, r)
+	if !ok {
+		return
+	}
 
-	err = r.ParseMultipartForm(MaxFileSize) // set in memory limit equal to max file size
+	// Validate Payload
+	qAndA, ok, err := u.parseUpload(w, r)
+	if !ok {
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		problem := NewProblem(ProblemInvalidQuestionSet, fmt.Sprintf("Uploaded file is invalid for quiz '%s'", quizId))
+		var validationErrors quiz.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			// Report every problem so that they can all be fixed in one go
+			problem.Errors = validationErrors
+		} else {
+			problem.Detail = fmt.Sprintf("%s: %s", problem.Detail, err)
+		}
+		WriteProblem(w, problem)
 		return
 	}
 
-	inFile, header, err := r.FormFile("file")
+	etag, err := etagOf(qAndA)
 	if err != nil {
-		http.Error(w, "Missing form field 'file'", http.StatusBadRequest)
+		u.Logger.Error(fmt.Sprintf("Failed to hash question set for quiz '%s'. Error: %s", quizId, err))
+		WriteProblem(w, NewProblem(ProblemInternalError, "Couldn't hash the question set"))
 		return
 	}
-	defer inFile.Close()
+	entry.ContentHash = strings.Trim(etag, `"`)
+	u.saveQuiz(w, r, quizId, key, qAndA, etag)
+}
 
-	if header.Size > MaxFileSize {
-		http.Error(w, "File exceeds the 10MiB limit", http.StatusRequestEntityTooLarge)
+// Stores the question set with the entity tag etag for the quiz, subject to the
+// request's preconditions and idempotency key
+func (u *Upload) saveQuiz(w http.ResponseWriter, r *http.Request, quizId string, key string, qAndA quiz.QuestionAndAnswers, etag string) {
+	// Before the preconditions, which the original request may have changed the outcome of
+	reservation, written := u.reserveIdempotent(w, r, quizId, key, etag)
+	if written {
 		return
 	}
+	defer reservation.release()
 
-	fileBytes := make([]byte, header.Size)
-	if _, err := inFile.Read(fileBytes); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	_, currentETag, version, ok := u.currentVersion(w, quizId)
+	if !ok {
+		return
+	}
+	if r.Method == http.MethodPut && currentETag == "" {
+		WriteProblem(w, NewProblem(ProblemQuestionSetNotFound, fmt.Sprintf("No question set uploaded for quiz '%s' to replace", quizId)))
+		return
+	}
+	if !checkPreconditions(w, r, quizId, currentETag) {
 		return
 	}
 
-	qAndA, err := quiz.QuizFileFromBytes(&fileBytes)
+	// Nothing is created when the question set is unchanged, even by a POST
+	status := http.StatusOK
+	if etag == currentETag {
+		u.Logger.Info(fmt.Sprintf("Quiz '%s' is unchanged so wasn't rewritten", quizId))
+	} else {
+		// Save the file to disk
+		if !u.storeQuiz(w, r, quizId, &qAndA, version) {
+			return
+		}
+		u.Logger.Info(fmt.Sprintf("Wrote quiz '%s'", quizId))
+		u.publishReady(r.Context(), quizId, qAndA)
+		if r.Method == http.MethodPost {
+			status = http.StatusCreated
+		}
+	}
+	reservation.record(status)
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(status)
+}
+
+// Parses and validates the question set in the 'file' part of the multipart form.
+// Problems with the request itself, such as a missing or oversized file, are written
+// as the response and ok is false. Otherwise err is what's wrong with the file's
+// content, which is a quiz.ValidationErrors when it could be read but isn't valid
+func (u *Upload) parseUpload(w http.ResponseWriter, r *http.Request) (qAndA quiz.QuestionAndAnswers, ok bool, err error) {
+	// Stream the file part rather than buffering the form so that memory use doesn't
+	// grow with the size or number of uploads in flight
+	r.Body = http.MaxBytesReader(w, r.Body, MaxFileSize + maxFormOverhead)
+	formReader, err := r.MultipartReader()
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Uploaded file is invalid for quiz '%s'", quizId), http.StatusBadRequest)
+		WriteProblem(w, NewProblem(ProblemUnsupportedMediaType, "Request body must be 'multipart/form-data'"))
+		return nil, false, nil
+	}
+
+	part, err := nextFilePart(formReader)
+	if isTooLarge(err) {
+		WriteProblem(w, NewProblem(ProblemFileTooLarge, "File exceeds the 10MiB limit"))
+		return nil, false, nil
+	}
+	if err != nil {
+		WriteProblem(w, NewProblem(ProblemMissingFile, "Missing form field 'file'"))
+		return nil, false, nil
+	}
+	defer part.Close()
+
+	format := quiz.DetectFormat(part.Header.Get("Content-Type"), part.FileName())
+	qAndA, err = quiz.ParseQuizReader(http.MaxBytesReader(w, part, MaxFileSize), format)
+	if isTooLarge(err) {
+		WriteProblem(w, NewProblem(ProblemFileTooLarge, "File exceeds the 10MiB limit"))
+		return nil, false, nil
+	}
+	return qAndA, true, err
+}
+
+// Skips over the form until the 'file' part. Other fields are discarded as they're
+// read so they count towards the request body limit without being held in memory
+func nextFilePart(formReader *multipart.Reader) (*multipart.Part, error) {
+	for {
+		part, err := formReader.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
+		if _, err := io.Copy(io.Discard, part); err != nil {
+			return nil, err
+		}
+		part.Close()
+	}
+}
+
+// Publishes that the question set for the quiz has been stored. The question set is
+// already saved so a failure is logged rather than failing the request
+func (u *Upload) publishReady(ctx context.Context, quizId string, qAndA quiz.QuestionAndAnswers) {
+	if u.Publisher == nil {
 		return
 	}
-	
-	// Save the file to disk
-	if err := u.QuizWriter.Write(quizId, &qAndA); err != nil {
-		http.Error(w, fmt.Sprintf("failed to save file. Error: %s", err), http.StatusBadRequest)
+	event, err := publish.NewQuestionSetReadyEvent(quizId, qAndA)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+		defer cancel()
+		err = u.Publisher.Publish(ctx, event)
+	}
+	if err != nil {
+		u.Logger.Error(fmt.Sprintf("Failed to publish question set ready event for quiz '%s'. Error: %s", quizId, err))
 		return
 	}
-	u.Logger.Info(fmt.Sprintf("Wrote quiz '%s'", quizId))
+	u.Logger.Info(fmt.Sprintf("Published question set ready event '%s' for quiz '%s'", event.Id, quizId))
+}
 
-	w.WriteHeader(http.StatusCreated)
-}
\ No newline at end of file
+// Logs the failed store operation and responds without exposing its details
+func (u *Upload) storageFailure(w http.ResponseWriter, operation string, quizId string, err error) {
+	u.Logger.Error(fmt.Sprintf("Failed to %s question set for quiz '%s'. Error: %s", operation, quizId, err))
+	WriteProblem(w, NewProblem(ProblemStorageFailure, fmt.Sprintf("Couldn't %s the question set", operation)))
+}
+
+// Writes body as the JSON response with the provided status code
+func writeJson(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
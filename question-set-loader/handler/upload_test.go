@@ -2,51 +2,146 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/Ryangwaite/mc-speedrun/question-set-loader/auth"
 	"github.com/Ryangwaite/mc-speedrun/question-set-loader/internal/testutils"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/publish"
 	"github.com/Ryangwaite/mc-speedrun/question-set-loader/quiz"
 	"github.com/google/go-cmp/cmp"
 	"github.com/sirupsen/logrus"
 )
 
-type mockQuizWriter struct {
+type mockQuizStore struct {
 	quizId string
 	qAndA quiz.QuestionAndAnswers
 	writeImpl func(quizId string, qAndA *quiz.QuestionAndAnswers) error
+	stored map[string]quiz.QuestionAndAnswers
+	deleted []string
+	afterRead func()	// Runs after the version is read, to change the question set in between
 }
 
-func NewMockQuizWriter(writeImpl *func(quizId string, qAndA *quiz.QuestionAndAnswers) error) *mockQuizWriter {
+func NewMockQuizStore(writeImpl *func(quizId string, qAndA *quiz.QuestionAndAnswers) error) *mockQuizStore {
+	store := &mockQuizStore{stored: make(map[string]quiz.QuestionAndAnswers)}
 	if writeImpl != nil {
-		return &mockQuizWriter{writeImpl: *writeImpl}
+		store.writeImpl = *writeImpl
 	} else {
-		return &mockQuizWriter{writeImpl: func(quizId string, qAndA *quiz.QuestionAndAnswers) error {return nil}}
+		store.writeImpl = func(quizId string, qAndA *quiz.QuestionAndAnswers) error {return nil}
 	}
+	return store
 }
 
-func (m *mockQuizWriter) Write(quizId string, qAndA *quiz.QuestionAndAnswers) error {
+func (m *mockQuizStore) Write(quizId string, qAndA *quiz.QuestionAndAnswers) error {
 	m.quizId = quizId
 	m.qAndA = *qAndA
-	return m.writeImpl(quizId, qAndA)
+	if err := m.writeImpl(quizId, qAndA); err != nil {
+		return err
+	}
+	m.stored[quizId] = *qAndA
+	return nil
+}
+
+func (m *mockQuizStore) Read(quizId string) (quiz.QuestionAndAnswers, error) {
+	qAndA, ok := m.stored[quizId]
+	if !ok {
+		return nil, quiz.ErrQuizNotFound
+	}
+	return qAndA, nil
+}
+
+func (m *mockQuizStore) Delete(quizId string) error {
+	if _, ok := m.stored[quizId]; !ok {
+		return quiz.ErrQuizNotFound
+	}
+	delete(m.stored, quizId)
+	m.deleted = append(m.deleted, quizId)
+	return nil
+}
+
+func (m *mockQuizStore) ReadVersion(quizId string) (quiz.QuestionAndAnswers, string, error) {
+	qAndA, err := m.Read(quizId)
+	if m.afterRead != nil {
+		m.afterRead()
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	version, err := etagOf(qAndA)
+	return qAndA, version, err
+}
+
+// Returns quiz.ErrVersionMismatch unless the stored question set is still version
+func (m *mockQuizStore) checkVersion(quizId string, version string) error {
+	current := ""
+	if qAndA, ok := m.stored[quizId]; ok {
+		var err error
+		if current, err = etagOf(qAndA); err != nil {
+			return err
+		}
+	}
+	if current != version {
+		return quiz.ErrVersionMismatch
+	}
+	return nil
+}
+
+func (m *mockQuizStore) WriteIfVersion(quizId string, qAndA *quiz.QuestionAndAnswers, version string) error {
+	if err := m.checkVersion(quizId, version); err != nil {
+		return err
+	}
+	return m.Write(quizId, qAndA)
+}
+
+func (m *mockQuizStore) DeleteIfVersion(quizId string, version string) error {
+	if err := m.checkVersion(quizId, version); err != nil {
+		return err
+	}
+	return m.Delete(quizId)
+}
+
+type mockPublisher struct {
+	published []publish.QuestionSetReadyEvent
+	err error
+}
+
+func (m *mockPublisher) Publish(ctx context.Context, event publish.QuestionSetReadyEvent) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.published = append(m.published, event)
+	return nil
+}
+
+func (m *mockPublisher) Close() error {
+	return nil
 }
 
 // Returns buffer containing the questionsAndAnswers written as multipart form data,
 // corresponding content-type or non-nil error on failure
 func buildReqBodyWithBytes(formKey string, bodyBytes []byte) (reqBody *bytes.Buffer, contentTypeValue string, err error) {
+	return buildReqBodyWithFile(formKey, "quiz.json", bodyBytes)
+}
+
+// Returns buffer containing the file bytes written as multipart form data with the
+// provided filename, corresponding content-type or non-nil error on failure
+func buildReqBodyWithFile(formKey string, filename string, bodyBytes []byte) (reqBody *bytes.Buffer, contentTypeValue string, err error) {
 	// Create writer for writing the request body
 	body := new(bytes.Buffer)
 	reqBodyWriter := multipart.NewWriter(body)
 	defer reqBodyWriter.Close()
 
-	partWriter, err := reqBodyWriter.CreateFormFile(formKey, "quiz.json")
+	partWriter, err := reqBodyWriter.CreateFormFile(formKey, filename)
 	if err != nil {
 		err = fmt.Errorf("failed to create writer for 'file': %w", err)
 		return
@@ -109,8 +204,7 @@ func TestUploadQuizHandler_wrong_method(t *testing.T) {
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 
 	uploadServer := Upload {
-		DevelopmentMode: false,
-		QuizWriter: NewMockQuizWriter(nil),
+		QuizStore: NewMockQuizStore(nil),
 		JwtParams: jwtParams,
 		Logger: logrus.StandardLogger(),
 	}
@@ -122,7 +216,7 @@ func TestUploadQuizHandler_wrong_method(t *testing.T) {
 	defer response.Body.Close()
 
 	// Assert
-	if diff := cmp.Diff(response.StatusCode, http.StatusUnauthorized); diff != "" {
+	if diff := cmp.Diff(response.StatusCode, http.StatusMethodNotAllowed); diff != "" {
 		t.Errorf("Wrong status code: %s", diff)
 	}
 	
@@ -148,8 +242,7 @@ func TestUploadQuizHandler_absent_authorization_header(t *testing.T) {
 	req.Header.Add("Content-Type", contentTypeValue)
 
 	uploadServer := Upload {
-		DevelopmentMode: false,
-		QuizWriter: NewMockQuizWriter(nil),
+		QuizStore: NewMockQuizStore(nil),
 		JwtParams: auth.JwtParams{},
 		Logger: logrus.StandardLogger(),
 	}
@@ -188,8 +281,7 @@ func TestUploadQuizHandler_invalid_authorization_format(t *testing.T) {
 	req.Header.Add("Authorization", "Invalid format")
 
 	uploadServer := Upload {
-		DevelopmentMode: false,
-		QuizWriter: NewMockQuizWriter(nil),
+		QuizStore: NewMockQuizStore(nil),
 		JwtParams: auth.JwtParams{},
 		Logger: logrus.StandardLogger(),
 	}
@@ -246,8 +338,7 @@ func TestUploadQuizHandler_invalid_auth_token(t *testing.T) {
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 
 	uploadServer := Upload {
-		DevelopmentMode: false,
-		QuizWriter: NewMockQuizWriter(nil),
+		QuizStore: NewMockQuizStore(nil),
 		JwtParams: jwtParams,
 		Logger: logrus.StandardLogger(),
 	}
@@ -272,6 +363,9 @@ func TestUploadQuizHandler_invalid_auth_token(t *testing.T) {
 	if !strings.Contains(actualError, expectedError) {
 		t.Fatalf("Response body '%s' did not contain error message '%s'", actualError, expectedError)
 	}
+	if strings.Contains(actualError, token) {
+		t.Errorf("Response body '%s' echoed the token", actualError)
+	}
 }
 
 // Tests uploading when the form key is not "file"
@@ -304,8 +398,7 @@ func TestUploadQuizHandler_invalid_form_key(t *testing.T) {
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 
 	uploadServer := Upload {
-		DevelopmentMode: false,
-		QuizWriter: NewMockQuizWriter(nil),
+		QuizStore: NewMockQuizStore(nil),
 		JwtParams: jwtParams,
 		Logger: logrus.StandardLogger(),
 	}
@@ -361,10 +454,9 @@ func TestUploadQuizHandler_incorrectly_formatted_file(t *testing.T) {
 	}
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 
-	mockQuizWriter := NewMockQuizWriter(nil)
+	mockQuizStore := NewMockQuizStore(nil)
 	uploadServer := Upload {
-		DevelopmentMode: false,
-		QuizWriter: mockQuizWriter,
+		QuizStore: mockQuizStore,
 		JwtParams: jwtParams,
 		Logger: logrus.StandardLogger(),
 	}
@@ -391,6 +483,86 @@ func TestUploadQuizHandler_incorrectly_formatted_file(t *testing.T) {
 	}
 }
 
+// Tests uploading a file that deserializes but fails validation
+func TestUploadQuizHandler_invalid_question_set(t *testing.T) {
+	// Initialize
+	jwtParams := auth.JwtParams{
+		Secret: "testsecret",
+		Issuer: "go.test",
+		Audience: "go.test",
+	}
+	quizId := "quizId"
+
+	invalidQAndA := quiz.QuestionAndAnswers {
+		{
+			Question: "",
+			Category: "food",
+			Options: []string {"a", "b"},
+			Answers: []int{2},
+		},
+	}
+	body, contentTypeValue, err := buildReqBodyWithQAndA("file", &invalidQAndA)
+	if err != nil {
+		t.Fatalf("failed to create request body: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/upload/quiz", body)
+	req.Header.Add("Content-Type", contentTypeValue)
+
+	token, err := testutils.BuildJwt(testutils.JwtTestParams{
+		Secret: jwtParams.Secret,
+		Issuer: jwtParams.Issuer,
+		Audience: jwtParams.Audience,
+		IsHost: true,
+		QuizId: quizId,
+	})
+	if err != nil {
+		t.Fatalf("failed to create auth token: %v", err)
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	mockQuizStore := NewMockQuizStore(nil)
+	uploadServer := Upload {
+		QuizStore: mockQuizStore,
+		JwtParams: jwtParams,
+		Logger: logrus.StandardLogger(),
+	}
+
+	// Act
+	recorder := httptest.NewRecorder()
+	uploadServer.Quiz(recorder, req)
+	response := recorder.Result()
+	defer response.Body.Close()
+
+	// Assert
+	if diff := cmp.Diff(response.StatusCode, http.StatusBadRequest); diff != "" {
+		t.Errorf("Wrong status code: %s", diff)
+	}
+	if diff := cmp.Diff(response.Header.Get("Content-Type"), ProblemContentType); diff != "" {
+		t.Errorf("Wrong content type: %s", diff)
+	}
+
+	var got Problem
+	if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	want := Problem{
+		Type: ProblemInvalidQuestionSet,
+		Title: "Invalid question set",
+		Status: http.StatusBadRequest,
+		Detail: "Uploaded file is invalid for quiz 'quizId'",
+		Errors: quiz.ValidationErrors{
+			{Index: 0, Field: "question", Reason: "must be non-empty"},
+			{Index: 0, Field: "answers[0]", Reason: "option index 2 is out of range for 2 options"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Wrong response body: %s", diff)
+	}
+	if mockQuizStore.quizId != "" {
+		t.Errorf("Invalid question set was written for quiz '%s'", mockQuizStore.quizId)
+	}
+}
+
 // Tests uploading a quiz where the file fails to save to disk
 func TestUploadQuizHandler_fail_to_save_file(t *testing.T) {
 	// Initialize
@@ -423,10 +595,9 @@ func TestUploadQuizHandler_fail_to_save_file(t *testing.T) {
 	writeImpl := func(quizId string, qAndA *quiz.QuestionAndAnswers) error {
 		return fmt.Errorf("failed to write file")
 	}
-	mockQuizWriter := NewMockQuizWriter(&writeImpl)
+	mockQuizStore := NewMockQuizStore(&writeImpl)
 	uploadServer := Upload {
-		DevelopmentMode: false,
-		QuizWriter: mockQuizWriter,
+		QuizStore: mockQuizStore,
 		JwtParams: jwtParams,
 		Logger: logrus.StandardLogger(),
 	}
@@ -438,7 +609,7 @@ func TestUploadQuizHandler_fail_to_save_file(t *testing.T) {
 	defer response.Body.Close()
 
 	// Assert
-	if diff := cmp.Diff(response.StatusCode, http.StatusBadRequest); diff != "" {
+	if diff := cmp.Diff(response.StatusCode, http.StatusInternalServerError); diff != "" {
 		t.Errorf("Wrong status code: %s", diff)
 	}
 	
@@ -446,7 +617,7 @@ func TestUploadQuizHandler_fail_to_save_file(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to read response body bytes: %v", err)
 	}
-	expectedError := "failed to save file"
+	expectedError := "Couldn't write the question set"
 	actualError := string(bodyBytes)
 	if !strings.Contains(actualError, expectedError) {
 		t.Fatalf("Response body '%s' did not contain error message '%s'", actualError, expectedError)
@@ -482,10 +653,9 @@ func TestUploadQuizHandler_success(t *testing.T) {
 	}
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 
-	mockQuizWriter := NewMockQuizWriter(nil)
+	mockQuizStore := NewMockQuizStore(nil)
 	uploadServer := Upload {
-		DevelopmentMode: false,
-		QuizWriter: mockQuizWriter,
+		QuizStore: mockQuizStore,
 		JwtParams: jwtParams,
 		Logger: logrus.StandardLogger(),
 	}
@@ -511,10 +681,425 @@ func TestUploadQuizHandler_success(t *testing.T) {
 		t.Fatalf("Response body '%s' did not contain error message '%s'", actualResponse, expectedResponse)
 	}
 
-	if diff := cmp.Diff(mockQuizWriter.quizId, quizId); diff != "" {
-		t.Fatalf("Wrong quizId '%s' expected '%s'", mockQuizWriter.quizId, quizId)
+	if diff := cmp.Diff(mockQuizStore.quizId, quizId); diff != "" {
+		t.Fatalf("Wrong quizId '%s' expected '%s'", mockQuizStore.quizId, quizId)
 	}
-	if diff := cmp.Diff(mockQuizWriter.qAndA, qAndA); diff != "" {
-		t.Fatalf("Wrong uploaded question set '%v' expected '%v'", mockQuizWriter.qAndA, qAndA)
+	if diff := cmp.Diff(mockQuizStore.qAndA, qAndA); diff != "" {
+		t.Fatalf("Wrong uploaded question set '%v' expected '%v'", mockQuizStore.qAndA, qAndA)
+	}
+}
+
+// Tests a successful upload of a csv file
+func TestUploadQuizHandler_success_csv(t *testing.T) {
+	// Initialize
+	jwtParams := auth.JwtParams{
+		Secret: "testsecret",
+		Issuer: "go.test",
+		Audience: "go.test",
+	}
+	quizId := "quizId"
+
+	csvFile := []byte("question,category,option,option,option,option,answers\nquestion 1,food,a,b,c,d,1;2\n")
+	body, contentTypeValue, err := buildReqBodyWithFile("file", "quiz.csv", csvFile)
+	if err != nil {
+		t.Fatalf("failed to create request body: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/upload/quiz", body)
+	req.Header.Add("Content-Type", contentTypeValue)
+
+	token, err := testutils.BuildJwt(testutils.JwtTestParams{
+		Secret: jwtParams.Secret,
+		Issuer: jwtParams.Issuer,
+		Audience: jwtParams.Audience,
+		IsHost: true,
+		QuizId: quizId,
+	})
+	if err != nil {
+		t.Fatalf("failed to create auth token: %v", err)
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	mockQuizStore := NewMockQuizStore(nil)
+	uploadServer := Upload {
+		QuizStore: mockQuizStore,
+		JwtParams: jwtParams,
+		Logger: logrus.StandardLogger(),
+	}
+
+	// Act
+	recorder := httptest.NewRecorder()
+	uploadServer.Quiz(recorder, req)
+	response := recorder.Result()
+	defer response.Body.Close()
+
+	// Assert
+	if diff := cmp.Diff(response.StatusCode, http.StatusCreated); diff != "" {
+		t.Errorf("Wrong status code: %s", diff)
+	}
+	if diff := cmp.Diff(mockQuizStore.qAndA, qAndA); diff != "" {
+		t.Fatalf("Wrong uploaded question set '%v' expected '%v'", mockQuizStore.qAndA, qAndA)
+	}
+}
+
+// Returns a request with a valid host token for quizId
+func buildAuthorizedRequest(t *testing.T, method string, body io.Reader, jwtParams auth.JwtParams, quizId string) *http.Request {
+	req := httptest.NewRequest(method, "/api/upload/quiz", body)
+	token, err := testutils.BuildJwt(testutils.JwtTestParams{
+		Secret: jwtParams.Secret,
+		Issuer: jwtParams.Issuer,
+		Audience: jwtParams.Audience,
+		IsHost: true,
+		QuizId: quizId,
+	})
+	if err != nil {
+		t.Fatalf("failed to create auth token: %v", err)
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	return req
+}
+
+// Tests reading back an uploaded question set
+func TestUploadQuizHandler_get(t *testing.T) {
+	// Initialize
+	jwtParams := auth.JwtParams{
+		Secret: "testsecret",
+		Issuer: "go.test",
+		Audience: "go.test",
+	}
+	quizId := "quizId"
+
+	mockQuizStore := NewMockQuizStore(nil)
+	uploadServer := Upload {
+		QuizStore: mockQuizStore,
+		JwtParams: jwtParams,
+		Logger: logrus.StandardLogger(),
+	}
+
+	// Act - before anything is uploaded
+	recorder := httptest.NewRecorder()
+	uploadServer.Quiz(recorder, buildAuthorizedRequest(t, http.MethodGet, nil, jwtParams, quizId))
+
+	// Assert
+	if diff := cmp.Diff(recorder.Result().StatusCode, http.StatusNotFound); diff != "" {
+		t.Errorf("Wrong status code: %s", diff)
+	}
+
+	// Act - after upload
+	mockQuizStore.stored[quizId] = qAndA
+	recorder = httptest.NewRecorder()
+	uploadServer.Quiz(recorder, buildAuthorizedRequest(t, http.MethodGet, nil, jwtParams, quizId))
+	response := recorder.Result()
+	defer response.Body.Close()
+
+	// Assert
+	if diff := cmp.Diff(response.StatusCode, http.StatusOK); diff != "" {
+		t.Errorf("Wrong status code: %s", diff)
+	}
+	var got quiz.QuestionAndAnswers
+	if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if diff := cmp.Diff(got, qAndA); diff != "" {
+		t.Errorf("Wrong question set returned: %s", diff)
+	}
+}
+
+// Tests replacing an uploaded question set
+func TestUploadQuizHandler_put(t *testing.T) {
+	// Initialize
+	jwtParams := auth.JwtParams{
+		Secret: "testsecret",
+		Issuer: "go.test",
+		Audience: "go.test",
+	}
+	quizId := "quizId"
+
+	mockQuizStore := NewMockQuizStore(nil)
+	uploadServer := Upload {
+		QuizStore: mockQuizStore,
+		JwtParams: jwtParams,
+		Logger: logrus.StandardLogger(),
+	}
+
+	replacement := quiz.QuestionAndAnswers {
+		{
+			Question: "question 2",
+			Category: "tech",
+			Options: []string {"i", "ii"},
+			Answers: []int{0},
+		},
+	}
+	buildPutRequest := func() *http.Request {
+		body, contentTypeValue, err := buildReqBodyWithQAndA("file", &replacement)
+		if err != nil {
+			t.Fatalf("failed to create request body: %v", err)
+		}
+		req := buildAuthorizedRequest(t, http.MethodPut, body, jwtParams, quizId)
+		req.Header.Add("Content-Type", contentTypeValue)
+		return req
+	}
+
+	// Act - nothing to replace
+	recorder := httptest.NewRecorder()
+	uploadServer.Quiz(recorder, buildPutRequest())
+
+	// Assert
+	if diff := cmp.Diff(recorder.Result().StatusCode, http.StatusNotFound); diff != "" {
+		t.Errorf("Wrong status code: %s", diff)
+	}
+
+	// Act - replace the existing question set
+	mockQuizStore.stored[quizId] = qAndA
+	recorder = httptest.NewRecorder()
+	uploadServer.Quiz(recorder, buildPutRequest())
+
+	// Assert
+	if diff := cmp.Diff(recorder.Result().StatusCode, http.StatusOK); diff != "" {
+		t.Errorf("Wrong status code: %s", diff)
+	}
+	if diff := cmp.Diff(mockQuizStore.stored[quizId], replacement); diff != "" {
+		t.Errorf("Question set wasn't replaced: %s", diff)
+	}
+}
+
+// Tests deleting an uploaded question set
+func TestUploadQuizHandler_delete(t *testing.T) {
+	// Initialize
+	jwtParams := auth.JwtParams{
+		Secret: "testsecret",
+		Issuer: "go.test",
+		Audience: "go.test",
+	}
+	quizId := "quizId"
+
+	mockQuizStore := NewMockQuizStore(nil)
+	mockQuizStore.stored[quizId] = qAndA
+	uploadServer := Upload {
+		QuizStore: mockQuizStore,
+		JwtParams: jwtParams,
+		Logger: logrus.StandardLogger(),
+	}
+
+	// Act
+	recorder := httptest.NewRecorder()
+	uploadServer.Quiz(recorder, buildAuthorizedRequest(t, http.MethodDelete, nil, jwtParams, quizId))
+
+	// Assert
+	if diff := cmp.Diff(recorder.Result().StatusCode, http.StatusNoContent); diff != "" {
+		t.Errorf("Wrong status code: %s", diff)
+	}
+	if diff := cmp.Diff(mockQuizStore.deleted, []string{quizId}); diff != "" {
+		t.Errorf("Wrong question sets deleted: %s", diff)
+	}
+
+	// Act - already deleted
+	recorder = httptest.NewRecorder()
+	uploadServer.Quiz(recorder, buildAuthorizedRequest(t, http.MethodDelete, nil, jwtParams, quizId))
+
+	// Assert
+	if diff := cmp.Diff(recorder.Result().StatusCode, http.StatusNotFound); diff != "" {
+		t.Errorf("Wrong status code: %s", diff)
+	}
+}
+
+// Tests uploading a file bigger than MaxFileSize
+func TestUploadQuizHandler_file_too_large(t *testing.T) {
+	// Initialize
+	jwtParams := auth.JwtParams{
+		Secret: "testsecret",
+		Issuer: "go.test",
+		Audience: "go.test",
+	}
+
+	mockQuizStore := NewMockQuizStore(nil)
+	uploadServer := Upload {
+		QuizStore: mockQuizStore,
+		JwtParams: jwtParams,
+		Logger: logrus.StandardLogger(),
+	}
+
+	// Valid JSON up until the limit so that it's the size that's rejected
+	fileBytes := append([]byte("["), bytes.Repeat([]byte(" "), int(MaxFileSize))...)
+	body, contentTypeValue, err := buildReqBodyWithBytes("file", fileBytes)
+	if err != nil {
+		t.Fatalf("failed to create request body: %v", err)
+	}
+	req := buildAuthorizedRequest(t, http.MethodPost, body, jwtParams, "quizId")
+	req.Header.Add("Content-Type", contentTypeValue)
+
+	// Act
+	recorder := httptest.NewRecorder()
+	uploadServer.Quiz(recorder, req)
+
+	// Assert
+	if diff := cmp.Diff(recorder.Result().StatusCode, http.StatusRequestEntityTooLarge); diff != "" {
+		t.Errorf("Wrong status code: %s", diff)
+	}
+	if mockQuizStore.quizId != "" {
+		t.Errorf("Oversized file was saved")
+	}
+}
+
+// Tests a form whose other fields take the request body over the limit before the file
+func TestUploadQuizHandler_form_too_large(t *testing.T) {
+	// Initialize
+	jwtParams := auth.JwtParams{
+		Secret: "testsecret",
+		Issuer: "go.test",
+		Audience: "go.test",
+	}
+
+	mockQuizStore := NewMockQuizStore(nil)
+	uploadServer := Upload {
+		QuizStore: mockQuizStore,
+		JwtParams: jwtParams,
+		Logger: logrus.StandardLogger(),
+	}
+
+	body := new(bytes.Buffer)
+	reqBodyWriter := multipart.NewWriter(body)
+	if err := reqBodyWriter.WriteField("notes", strings.Repeat("a", int(MaxFileSize + maxFormOverhead))); err != nil {
+		t.Fatalf("failed to write form field: %v", err)
+	}
+	partWriter, err := reqBodyWriter.CreateFormFile("file", "quiz.json")
+	if err != nil {
+		t.Fatalf("failed to create writer for 'file': %v", err)
+	}
+	partWriter.Write([]byte("[]"))
+	reqBodyWriter.Close()
+	req := buildAuthorizedRequest(t, http.MethodPost, body, jwtParams, "quizId")
+	req.Header.Add("Content-Type", reqBodyWriter.FormDataContentType())
+
+	// Act
+	recorder := httptest.NewRecorder()
+	uploadServer.Quiz(recorder, req)
+
+	// Assert
+	if diff := cmp.Diff(recorder.Result().StatusCode, http.StatusRequestEntityTooLarge); diff != "" {
+		t.Errorf("Wrong status code: %s", diff)
+	}
+	if mockQuizStore.quizId != "" {
+		t.Errorf("Oversized form was saved")
+	}
+}
+
+// Tests uploading for a quiz that has finished
+func TestUploadQuizHandler_quiz_finished(t *testing.T) {
+	// Initialize
+	revocationFile := filepath.Join(t.TempDir(), "revoked.json")
+	if err := os.WriteFile(revocationFile, []byte(`{"quizIds": ["quizId"]}`), 0644); err != nil {
+		t.Fatalf("failed to write revocation file: %v", err)
+	}
+	jwtParams := auth.JwtParams{
+		Secret: "testsecret",
+		Issuer: "go.test",
+		Audience: "go.test",
+		Revocations: auth.NewFileRevocationList(revocationFile),
+	}
+
+	mockQuizStore := NewMockQuizStore(nil)
+	uploadServer := Upload {
+		QuizStore: mockQuizStore,
+		JwtParams: jwtParams,
+		Logger: logrus.StandardLogger(),
+	}
+
+	body, contentTypeValue, err := buildReqBodyWithQAndA("file", &qAndA)
+	if err != nil {
+		t.Fatalf("failed to create request body: %v", err)
+	}
+	req := buildAuthorizedRequest(t, http.MethodPost, body, jwtParams, "quizId")
+	req.Header.Add("Content-Type", contentTypeValue)
+
+	// Act
+	recorder := httptest.NewRecorder()
+	uploadServer.Quiz(recorder, req)
+	response := recorder.Result()
+	defer response.Body.Close()
+
+	// Assert
+	if diff := cmp.Diff(response.StatusCode, http.StatusUnauthorized); diff != "" {
+		t.Errorf("Wrong status code: %s", diff)
+	}
+	var got Problem
+	if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if diff := cmp.Diff(got.Type, ProblemQuizFinished); diff != "" {
+		t.Errorf("Wrong problem type: %s", diff)
+	}
+	if mockQuizStore.quizId != "" {
+		t.Errorf("Question set was saved for a finished quiz")
+	}
+}
+
+// Publishes the question set ready event once the question set is stored, and only then
+func TestUploadQuizHandler_publishes_ready_event(t *testing.T) {
+	jwtParams := auth.JwtParams{
+		Secret: "testsecret",
+		Issuer: "go.test",
+		Audience: "go.test",
+	}
+	quizId := "quizId"
+	storeErr := func(quizId string, qAndA *quiz.QuestionAndAnswers) error { return fmt.Errorf("disk full") }
+
+	tests := map[string]struct {
+		file			[]byte
+		writeImpl		*func(quizId string, qAndA *quiz.QuestionAndAnswers) error
+		publishErr		error
+		wantStatus		int
+		wantPublished	bool
+	}{
+		"stored": {mustMarshal(t, qAndA), nil, nil, http.StatusCreated, true},
+		"publish fails": {mustMarshal(t, qAndA), nil, fmt.Errorf("broker down"), http.StatusCreated, false},
+		"invalid": {[]byte("[]"), nil, nil, http.StatusBadRequest, false},
+		"store fails": {mustMarshal(t, qAndA), &storeErr, nil, http.StatusInternalServerError, false},
+	}
+
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			body, contentTypeValue, err := buildReqBodyWithBytes("file", test.file)
+			if err != nil {
+				t.Fatalf("failed to create request body: %v", err)
+			}
+			req := buildAuthorizedRequest(t, http.MethodPost, body, jwtParams, quizId)
+			req.Header.Add("Content-Type", contentTypeValue)
+
+			publisher := &mockPublisher{err: test.publishErr}
+			uploadServer := Upload {
+				QuizStore: NewMockQuizStore(test.writeImpl),
+				JwtParams: jwtParams,
+				Publisher: publisher,
+				Logger: logrus.StandardLogger(),
+			}
+			recorder := httptest.NewRecorder()
+			uploadServer.Quiz(recorder, req)
+
+			if diff := cmp.Diff(recorder.Code, test.wantStatus); diff != "" {
+				t.Fatalf("Wrong status code: %s", diff)
+			}
+			if !test.wantPublished {
+				if len(publisher.published) != 0 {
+					t.Errorf("Expected no events but got %+v", publisher.published)
+				}
+				return
+			}
+			if len(publisher.published) != 1 {
+				t.Fatalf("Expected 1 event but got %d", len(publisher.published))
+			}
+			contentHash, _ := qAndA.ContentHash()
+			want := publish.QuestionSetReadyEventDataV1{QuizId: quizId, QuestionCount: 1, Categories: []string{"food"}, ContentHash: contentHash}
+			if diff := cmp.Diff(want, publisher.published[0].Data); diff != "" {
+				t.Errorf("Wrong event data: %s", diff)
+			}
+		})
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
 	}
+	return data
 }
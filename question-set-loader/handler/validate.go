@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/quiz"
+)
+
+// Path of the endpoint that checks a question set without storing it
+const ValidatePath = QuizPath + "/validate"
+
+// Outcome of checking a question set without storing it. The summary is of whatever
+// could be read, so it's empty when the file couldn't be read at all
+type ValidationReport struct {
+	Valid	bool					`json:"valid"`
+	quiz.Summary
+	Errors	quiz.ValidationErrors	`json:"errors"`
+}
+
+// Parses and validates the question set in the 'file' part of a multipart form the same
+// way as uploading it, without storing it. Responds '200 OK' with a ValidationReport
+// whether or not the question set is valid, so hosts can fix every problem before
+// uploading. Problems with the request itself get the same responses as an upload
+func (u *Upload) Validate(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		WriteProblem(w, NewProblem(ProblemMethodNotAllowed, fmt.Sprintf("Unexpected HTTP method '%s'", r.Method)))
+		return
+	}
+
+	host, ok := u.admit(w, r)
+	if !ok {
+		return
+	}
+
+	qAndA, ok, err := u.parseUpload(w, r)
+	if !ok {
+		return
+	}
+
+	report := ValidationReport{
+		Valid: err == nil,
+		Summary: qAndA.Summarise(),
+		Errors: quiz.ValidationErrors{},
+	}
+	var validationErrors quiz.ValidationErrors
+	switch {
+	case errors.As(err, &validationErrors):
+		report.Errors = validationErrors
+	case err != nil:
+		// Couldn't be read so it's a problem with the question set as a whole
+		report.Errors = quiz.ValidationErrors{{Index: quiz.QuestionSetIndex, Reason: err.Error()}}
+	}
+	u.Logger.Info(fmt.Sprintf("Validated question set for quiz '%s' with %d error(s) and %d warning(s)", host.QuizId, len(report.Errors), len(report.Warnings)))
+
+	writeJson(w, http.StatusOK, report)
+}
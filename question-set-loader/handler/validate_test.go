@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/auth"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/quiz"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+)
+
+// Tests checking question sets without storing them
+func TestValidateHandler(t *testing.T) {
+	jwtParams := auth.JwtParams{
+		Secret: "testsecret",
+		Issuer: "go.test",
+		Audience: "go.test",
+	}
+
+	tests := map[string]struct {
+		filename	string
+		file		string
+		want		ValidationReport
+	}{
+		"valid": {
+			"quiz.json",
+			`[{"question": "q1", "category": "food", "options": ["a", "b"], "answers": [0, 1]},
+			  {"question": "Q1 ", "category": "tech", "options": ["a", "b"], "answers": [0]}]`,
+			ValidationReport{
+				Valid: true,
+				Summary: quiz.Summary{
+					QuestionCount: 2,
+					CategoryCounts: map[string]int{"food": 1, "tech": 1},
+					MultiAnswerCount: 1,
+					Warnings: quiz.ValidationErrors{{Index: 1, Field: "question", Reason: "duplicates question 0 'q1'"}},
+				},
+				Errors: quiz.ValidationErrors{},
+			},
+		},
+		"invalid": {
+			"quiz.csv",
+			"question,category,option,option,answers\nq1,food,a,b,1\nq2,food,a,b,5\n",
+			ValidationReport{
+				Valid: false,
+				Summary: quiz.Summary{
+					QuestionCount: 2,
+					CategoryCounts: map[string]int{"food": 2},
+					Warnings: quiz.ValidationErrors{},
+				},
+				Errors: quiz.ValidationErrors{{Index: 1, Field: "answers[0]", Reason: "option index 5 is out of range for 2 options"}},
+			},
+		},
+		"invalid gift": {
+			"quiz.gift",
+			"$CATEGORY: food\n\nq1 {=a ~b}\n\nq2 {~a ~b}\n\nq3 {#4}\n",
+			ValidationReport{
+				Valid: false,
+				Summary: quiz.Summary{
+					QuestionCount: 2,
+					CategoryCounts: map[string]int{"food": 2},
+					Warnings: quiz.ValidationErrors{},
+				},
+				Errors: quiz.ValidationErrors{{Index: 2, Reason: "numeric questions are not supported", Line: 7}},
+			},
+		},
+		"invalid aiken": {
+			"quiz.aiken",
+			"q1\nA. a\nB. b\nANSWER: C\n",
+			ValidationReport{
+				Valid: false,
+				Summary: quiz.Summary{
+					QuestionCount: 1,
+					CategoryCounts: map[string]int{"general": 1},
+					Warnings: quiz.ValidationErrors{},
+				},
+				Errors: quiz.ValidationErrors{{Index: 0, Field: "answers", Reason: "'C' is not one of the option letters", Line: 4}},
+			},
+		},
+		"unparseable": {
+			"quiz.json",
+			`[{"question": `,
+			ValidationReport{
+				Valid: false,
+				Summary: quiz.Summary{CategoryCounts: map[string]int{}, Warnings: quiz.ValidationErrors{}},
+				Errors: quiz.ValidationErrors{{Index: quiz.QuestionSetIndex, Reason: "failed to deserialize json quiz file: Error: question 0: unexpected EOF"}},
+			},
+		},
+	}
+
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			body, contentTypeValue, err := buildReqBodyWithFile("file", test.filename, []byte(test.file))
+			if err != nil {
+				t.Fatalf("failed to create request body: %v", err)
+			}
+			req := buildAuthorizedRequest(t, http.MethodPost, body, jwtParams, "quizId")
+			req.URL.Path = ValidatePath
+			req.Header.Add("Content-Type", contentTypeValue)
+
+			mockQuizStore := NewMockQuizStore(nil)
+			publisher := &mockPublisher{}
+			uploadServer := Upload {
+				QuizStore: mockQuizStore,
+				JwtParams: jwtParams,
+				Publisher: publisher,
+				Logger: logrus.StandardLogger(),
+			}
+			recorder := httptest.NewRecorder()
+			NewServeMux(&uploadServer, CorsOptions{}, logrus.StandardLogger()).ServeHTTP(recorder, req)
+
+			if diff := cmp.Diff(http.StatusOK, recorder.Code); diff != "" {
+				t.Fatalf("Wrong status code: %s", diff)
+			}
+			var got ValidationReport
+			if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Wrong report: %s", diff)
+			}
+			if mockQuizStore.quizId != "" || len(publisher.published) != 0 {
+				t.Errorf("Validated question set was stored or published")
+			}
+		})
+	}
+}
+
+// Problems with the request are reported the same way as for uploads
+func TestValidateHandler_bad_requests(t *testing.T) {
+	jwtParams := auth.JwtParams{
+		Secret: "testsecret",
+		Issuer: "go.test",
+		Audience: "go.test",
+	}
+	uploadServer := Upload {
+		QuizStore: NewMockQuizStore(nil),
+		JwtParams: jwtParams,
+		Logger: logrus.StandardLogger(),
+	}
+
+	body, contentTypeValue, err := buildReqBodyWithBytes("notfile", []byte("[]"))
+	if err != nil {
+		t.Fatalf("failed to create request body: %v", err)
+	}
+	missingFile := buildAuthorizedRequest(t, http.MethodPost, body, jwtParams, "quizId")
+	missingFile.Header.Add("Content-Type", contentTypeValue)
+	notMultipart := buildAuthorizedRequest(t, http.MethodPost, strings.NewReader("[]"), jwtParams, "quizId")
+	notMultipart.Header.Add("Content-Type", "application/json")
+	unauthorized := buildAuthorizedRequest(t, http.MethodPost, nil, jwtParams, "quizId")
+	unauthorized.Header.Del("Authorization")
+
+	tests := map[string]struct {
+		req			*http.Request
+		wantType	ProblemType
+	}{
+		"wrong method": {buildAuthorizedRequest(t, http.MethodPut, nil, jwtParams, "quizId"), ProblemMethodNotAllowed},
+		"unauthorized": {unauthorized, ProblemMissingAuthorization},
+		"missing file": {missingFile, ProblemMissingFile},
+		"not multipart": {notMultipart, ProblemUnsupportedMediaType},
+	}
+
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			uploadServer.Validate(recorder, test.req)
+
+			var got Problem
+			if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			if diff := cmp.Diff(test.wantType, got.Type); diff != "" {
+				t.Errorf("Wrong problem type: %s", diff)
+			}
+		})
+	}
+}
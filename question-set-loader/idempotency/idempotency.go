@@ -0,0 +1,42 @@
+package idempotency
+
+import (
+	"time"
+)
+
+const StoreMemory = "memory"
+const StoreRedis = "redis"
+
+// How long results are kept by default. Long enough to cover a client retrying
+// across a lost connection or an app restart
+const DefaultTTL = 24 * time.Hour
+
+// How long a key is reserved for a request that's in progress. It's released early if
+// the request fails, this only matters if the instance handling it stops
+const PendingTTL = 5 * time.Minute
+
+// Outcome of a request made with an idempotency key, returned in place of handling
+// the request again when it's retried
+type Result struct {
+	Method	string	`json:"method"`
+	Status	int		`json:"status"`	// 0 while the request is in progress
+	ETag	string	`json:"etag"`	// Of the question set sent with the request
+}
+
+// Whether the request made with the key is still in progress
+func (r Result) Pending() bool {
+	return r.Status == 0
+}
+
+// Remembers the result of each request made with an idempotency key
+type Store interface {
+	// Returns the result recorded for key. ok is false when there isn't one or it has expired
+	Get(key string) (result Result, ok bool, err error)
+	// Records the result for key, replacing any already recorded, for ttl
+	Put(key string, result Result, ttl time.Duration) error
+	// Records the result for key for ttl, in one step with checking there isn't one
+	// already. ok is false when there is one, which is left as it is
+	PutIfAbsent(key string, result Result, ttl time.Duration) (ok bool, err error)
+	// Removes the result recorded for key, if there is one
+	Delete(key string) error
+}
@@ -0,0 +1,102 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/go-cmp/cmp"
+)
+
+// Runs the same operations against each Store. advance moves the store's clock on
+func testStore(t *testing.T, store Store, advance func(time.Duration)) {
+	created := Result{Method: "POST", Status: 201, ETag: `"sha256:ab"`}
+	replaced := Result{Method: "PUT", Status: 200, ETag: `"sha256:cd"`}
+
+	if _, ok, err := store.Get("quiz1:key1"); ok || err != nil {
+		t.Fatalf("Expected no result before one is put but got %t %v", ok, err)
+	}
+
+	if err := store.Put("quiz1:key1", created, time.Hour); err != nil {
+		t.Fatalf("Failed to put result: %v", err)
+	}
+	got, ok, err := store.Get("quiz1:key1")
+	if !ok || err != nil {
+		t.Fatalf("Expected the result but got %t %v", ok, err)
+	}
+	if diff := cmp.Diff(created, got); diff != "" {
+		t.Errorf("Wrong result: %s", diff)
+	}
+	if _, ok, _ := store.Get("quiz2:key1"); ok {
+		t.Errorf("Expected other keys to have no result")
+	}
+
+	store.Put("quiz1:key1", replaced, time.Hour)
+	if got, _, _ := store.Get("quiz1:key1"); got != replaced {
+		t.Errorf("Expected the result to be replaced but got %+v", got)
+	}
+
+	// Only the first request with a key reserves it
+	pending := Result{Method: "POST", ETag: `"sha256:ef"`}
+	if ok, err := store.PutIfAbsent("quiz1:key3", pending, time.Minute); !ok || err != nil {
+		t.Fatalf("Expected the key to be reserved but got %t %v", ok, err)
+	}
+	if ok, err := store.PutIfAbsent("quiz1:key3", created, time.Minute); ok || err != nil {
+		t.Fatalf("Expected the key to already be reserved but got %t %v", ok, err)
+	}
+	if got, _, _ := store.Get("quiz1:key3"); got != pending || !got.Pending() {
+		t.Errorf("Expected the reservation to be left as it was but got %+v", got)
+	}
+	if err := store.Delete("quiz1:key3"); err != nil {
+		t.Fatalf("Failed to delete result: %v", err)
+	}
+	if ok, _ := store.PutIfAbsent("quiz1:key3", pending, time.Minute); !ok {
+		t.Errorf("Expected the key to be reserved again once deleted")
+	}
+
+	advance(time.Hour)
+	if _, ok, _ := store.Get("quiz1:key1"); ok {
+		t.Errorf("Expected the result to expire")
+	}
+	if ok, _ := store.PutIfAbsent("quiz1:key3", pending, time.Minute); !ok {
+		t.Errorf("Expected an expired reservation to be replaced")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Unix(1643273706, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	testStore(t, store, func(d time.Duration) { now = now.Add(d) })
+
+	// Expired results are removed on the next put after the sweep interval
+	store.Put("quiz1:key2", Result{}, time.Hour)
+	if _, ok := store.entries["quiz1:key1"]; ok {
+		t.Errorf("Expected the expired result to be removed")
+	}
+	if _, ok := store.entries["quiz1:key2"]; !ok {
+		t.Errorf("Expected the unexpired result to be kept")
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	store := NewRedisStore(RedisOptions{Addr: mr.Addr()})
+	store.Put("quiz1:key2", Result{}, 2 * time.Hour)
+	if ttl := mr.TTL("idempotency:quiz1:key2"); ttl != 2 * time.Hour {
+		t.Errorf("Expected the result to expire with its ttl but TTL is %s", ttl)
+	}
+
+	testStore(t, store, mr.FastForward)
+
+	mr.Close()
+	if _, _, err := store.Get("quiz1:key1"); err == nil {
+		t.Errorf("Failed to detect error")
+	}
+}
@@ -0,0 +1,82 @@
+package idempotency
+
+import (
+	"sync"
+	"time"
+)
+
+// How often expired results are removed from a MemoryStore
+const sweepInterval = time.Minute
+
+type entry struct {
+	result	Result
+	expires	time.Time
+}
+
+// Store holding the results in memory so retries are only recognised by this instance
+type MemoryStore struct {
+	now			func() time.Time
+
+	mu			sync.Mutex
+	entries		map[string]entry
+	lastSweep	time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{now: time.Now, entries: make(map[string]entry)}
+}
+
+func (m *MemoryStore) Get(key string) (Result, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok || !m.now().Before(e.expires) {
+		return Result{}, false, nil
+	}
+	return e.result, true, nil
+}
+
+func (m *MemoryStore) Put(key string, result Result, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+	m.entries[key] = entry{result: result, expires: now.Add(ttl)}
+	return nil
+}
+
+func (m *MemoryStore) PutIfAbsent(key string, result Result, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if e, ok := m.entries[key]; ok && now.Before(e.expires) {
+		return false, nil
+	}
+	m.sweep(now)
+	m.entries[key] = entry{result: result, expires: now.Add(ttl)}
+	return true, nil
+}
+
+func (m *MemoryStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, key)
+	return nil
+}
+
+// Removes the expired results so the map doesn't grow with every key ever used
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	for key, e := range m.entries {
+		if !now.Before(e.expires) {
+			delete(m.entries, key)
+		}
+	}
+	m.lastSweep = now
+}
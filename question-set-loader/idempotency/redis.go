@@ -0,0 +1,94 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type RedisOptions struct {
+	Addr     string
+	Password string
+}
+
+// Store holding the results in Redis so retries are recognised by every instance.
+// Results are stored as JSON under 'idempotency:<key>' and expire with their ttl
+type RedisStore struct {
+	rdb *redis.Client
+}
+
+func NewRedisStore(o RedisOptions) *RedisStore {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     o.Addr,
+		Password: o.Password,
+	})
+
+	return &RedisStore{rdb: rdb}
+}
+
+func (r *RedisStore) Get(key string) (Result, bool, error) {
+
+	// Give the lookup 1 second to complete
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	value, err := r.rdb.Get(ctx, "idempotency:" + key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return Result{}, false, nil
+	}
+	if err != nil {
+		return Result{}, false, fmt.Errorf("failed to get result for idempotency key '%s': %w", key, err)
+	}
+
+	var result Result
+	if err := json.Unmarshal(value, &result); err != nil {
+		return Result{}, false, fmt.Errorf("failed to decode result for idempotency key '%s': %w", key, err)
+	}
+	return result, true, nil
+}
+
+func (r *RedisStore) Put(key string, result Result, ttl time.Duration) error {
+	value, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	if err := r.rdb.Set(ctx, "idempotency:" + key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to put result for idempotency key '%s': %w", key, err)
+	}
+	return nil
+}
+
+// Uses SETNX so that only one request can reserve the key
+func (r *RedisStore) PutIfAbsent(key string, result Result, ttl time.Duration) (bool, error) {
+	value, err := json.Marshal(result)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	ok, err := r.rdb.SetNX(ctx, "idempotency:" + key, value, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to put result for idempotency key '%s': %w", key, err)
+	}
+	return ok, nil
+}
+
+func (r *RedisStore) Delete(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	if err := r.rdb.Del(ctx, "idempotency:" + key).Err(); err != nil {
+		return fmt.Errorf("failed to delete result for idempotency key '%s': %w", key, err)
+	}
+	return nil
+}
@@ -1,6 +1,11 @@
 package testutils
 
-import "github.com/golang-jwt/jwt/v4"
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+
+	"github.com/golang-jwt/jwt/v4"
+)
 
 type JwtTestParams struct {
 	Secret string
@@ -8,6 +13,9 @@ type JwtTestParams struct {
 	Issuer interface{}
 	IsHost interface{}
 	QuizId interface{}
+	Kid string // Set as the 'kid' header when non-empty
+	SigningKey interface{} // *rsa.PrivateKey or *ecdsa.PrivateKey to sign with RS256 or ES256 instead of the Secret
+	Claims map[string]interface{} // Any other claims e.g. "exp" and "jti"
 }
 
 // Builds a jwt token from the provide parameters. Set the interface
@@ -25,9 +33,24 @@ func BuildJwt(jwtParams JwtTestParams) (string, error) {
 	if jwtParams.QuizId != nil {
 		claims["quizId"] = jwtParams.QuizId
 	}
+	for name, value := range jwtParams.Claims {
+		claims[name] = value
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(jwtParams.Secret))
+	var method jwt.SigningMethod = jwt.SigningMethodHS256
+	var key interface{} = []byte(jwtParams.Secret)
+	switch jwtParams.SigningKey.(type) {
+	case *rsa.PrivateKey:
+		method, key = jwt.SigningMethodRS256, jwtParams.SigningKey
+	case *ecdsa.PrivateKey:
+		method, key = jwt.SigningMethodES256, jwtParams.SigningKey
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	if jwtParams.Kid != "" {
+		token.Header["kid"] = jwtParams.Kid
+	}
+	tokenString, err := token.SignedString(key)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,240 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/handler"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/quiz"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "question_set_loader"
+
+// Prometheus metrics for the API, exposed by Handler
+type Metrics struct {
+	registry				*prometheus.Registry
+	requests				*prometheus.CounterVec
+	requestDuration			*prometheus.HistogramVec
+	uploadSize				prometheus.Histogram
+	validationFailures		*prometheus.CounterVec
+	jwtRejections			*prometheus.CounterVec
+	storeWriteDuration		*prometheus.HistogramVec
+}
+
+// Problem types that are counted as JWT rejections
+var jwtRejectionProblems = map[handler.ProblemType]bool{
+	handler.ProblemMissingAuthorization: true,
+	handler.ProblemInvalidAuthorization: true,
+	handler.ProblemInvalidToken: true,
+	handler.ProblemTokenRevoked: true,
+	handler.ProblemQuizFinished: true,
+}
+
+// Matches the indexes in validation error fields e.g. the "[2]" in "options[2]"
+var fieldIndexPattern = regexp.MustCompile(`\[\d+\]`)
+
+// Creates the metrics in their own registry along with the Go runtime and process metrics
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name: "requests_total",
+			Help: "Requests to the API by method and status code.",
+		}, []string{"method", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name: "request_duration_seconds",
+			Help: "Time taken to handle requests to the API by method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method"}),
+		uploadSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name: "upload_size_bytes",
+			Help: "Size of the request bodies uploading question sets.",
+			Buckets: prometheus.ExponentialBuckets(1 << 10, 4, 8), // 1KiB to 16MiB
+		}),
+		validationFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name: "validation_failures_total",
+			Help: "Problems found in uploaded question sets by the field they're in. 'unparseable' files couldn't be read at all.",
+		}, []string{"reason"}),
+		jwtRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name: "jwt_rejections_total",
+			Help: "Requests rejected because of their token by reason.",
+		}, []string{"reason"}),
+		storeWriteDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name: "store_write_duration_seconds",
+			Help: "Time taken to write question sets to the store by result.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"result"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.uploadSize,
+		m.validationFailures,
+		m.jwtRejections,
+		m.storeWriteDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Records the request, and the reasons for failures described by problem+json
+// responses, for every request that passes through to next
+func (m *Metrics) Middleware(next func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		body := &countingReader{r: r.Body}
+		r.Body = body
+		mrw := &metricsResponseWriter{wrappedWriter: w}
+
+		next(mrw, r)
+
+		if mrw.statusCode == 0 {
+			mrw.statusCode = http.StatusOK
+		}
+		m.requests.WithLabelValues(r.Method, strconv.Itoa(mrw.statusCode)).Inc()
+		m.requestDuration.WithLabelValues(r.Method).Observe(time.Since(start).Seconds())
+		// Only requests with a body are uploads e.g. not importing from the library
+		if (r.Method == http.MethodPost || r.Method == http.MethodPut) && body.n > 0 {
+			m.uploadSize.Observe(float64(body.n))
+		}
+		if mrw.isProblem() {
+			m.observeProblem(mrw.body.Bytes())
+		}
+	}
+}
+
+func (m *Metrics) observeProblem(body []byte) {
+	var problem handler.Problem
+	if err := json.Unmarshal(body, &problem); err != nil {
+		return
+	}
+
+	switch {
+	case jwtRejectionProblems[problem.Type]:
+		m.jwtRejections.WithLabelValues(problemName(problem.Type)).Inc()
+	case problem.Type == handler.ProblemInvalidQuestionSet && len(problem.Errors) == 0:
+		m.validationFailures.WithLabelValues("unparseable").Inc()
+	case problem.Type == handler.ProblemInvalidQuestionSet:
+		for _, validationError := range problem.Errors {
+			m.validationFailures.WithLabelValues(validationReason(validationError)).Inc()
+		}
+	}
+}
+
+// Returns the last part of the problem type URN e.g. "invalid-token"
+func problemName(problemType handler.ProblemType) string {
+	parts := strings.Split(string(problemType), ":")
+	return parts[len(parts)-1]
+}
+
+// Reduces the validation error to its field without indexes e.g. "answers[0]" is
+// "answers", so that the label has few values
+func validationReason(validationError quiz.ValidationError) string {
+	if validationError.Index == quiz.QuestionSetIndex {
+		return "question_set"
+	}
+	if validationError.Field == "" {
+		return "question"
+	}
+	return fieldIndexPattern.ReplaceAllString(validationError.Field, "")
+}
+
+// Wraps the store so that the time taken by each write is recorded
+func (m *Metrics) InstrumentQuizStore(store quiz.QuizStore) quiz.QuizStore {
+	return instrumentedQuizStore{store, m}
+}
+
+type instrumentedQuizStore struct {
+	quiz.QuizStore
+	metrics *Metrics
+}
+
+func (s instrumentedQuizStore) Write(quizId string, qAndA *quiz.QuestionAndAnswers) error {
+	start := time.Now()
+	err := s.QuizStore.Write(quizId, qAndA)
+	s.observeWrite(start, err)
+	return err
+}
+
+func (s instrumentedQuizStore) WriteIfVersion(quizId string, qAndA *quiz.QuestionAndAnswers, version string) error {
+	start := time.Now()
+	err := s.QuizStore.WriteIfVersion(quizId, qAndA, version)
+	s.observeWrite(start, err)
+	return err
+}
+
+func (s instrumentedQuizStore) observeWrite(start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	s.metrics.storeWriteDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
+
+// Counts the bytes read from the request body
+type countingReader struct {
+	r	io.ReadCloser
+	n	int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) Close() error {
+	return c.r.Close()
+}
+
+// Captures the status code, and the body of problem+json responses
+type metricsResponseWriter struct {
+	wrappedWriter	http.ResponseWriter
+	statusCode		int
+	body			bytes.Buffer
+}
+
+func (mrw *metricsResponseWriter) Header() http.Header {
+	return mrw.wrappedWriter.Header()
+}
+
+func (mrw *metricsResponseWriter) Write(data []byte) (int, error) {
+	if mrw.statusCode == 0 {
+		mrw.statusCode = http.StatusOK
+	}
+	if mrw.isProblem() {
+		mrw.body.Write(data)
+	}
+	return mrw.wrappedWriter.Write(data)
+}
+
+func (mrw *metricsResponseWriter) WriteHeader(statusCode int) {
+	mrw.statusCode = statusCode
+	mrw.wrappedWriter.WriteHeader(statusCode)
+}
+
+func (mrw *metricsResponseWriter) isProblem() bool {
+	return mrw.Header().Get("Content-Type") == handler.ProblemContentType
+}
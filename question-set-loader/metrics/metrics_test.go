@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/handler"
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/quiz"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func problemHandler(problem handler.Problem) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", handler.ProblemContentType)
+		w.WriteHeader(problem.Status)
+		json.NewEncoder(w).Encode(problem)
+	}
+}
+
+func TestMiddleware_counts_requests(t *testing.T) {
+	m := New()
+	ok := m.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte("ok"))
+	})
+	invalidToken := m.Middleware(problemHandler(handler.NewProblem(handler.ProblemInvalidToken, "expired")))
+
+	ok(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/upload/quiz", strings.NewReader("0123456789")))
+	ok(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/upload/quiz", nil))
+	invalidToken(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/upload/quiz", nil))
+
+	if got := testutil.ToFloat64(m.requests.WithLabelValues(http.MethodPost, "200")); got != 1 {
+		t.Errorf("Expected 1 successful POST but got %v", got)
+	}
+	if got := testutil.ToFloat64(m.requests.WithLabelValues(http.MethodGet, "200")); got != 1 {
+		t.Errorf("Expected 1 successful GET but got %v", got)
+	}
+	if got := testutil.ToFloat64(m.requests.WithLabelValues(http.MethodPost, "401")); got != 1 {
+		t.Errorf("Expected 1 unauthorized POST but got %v", got)
+	}
+	if got := testutil.ToFloat64(m.jwtRejections.WithLabelValues("invalid-token")); got != 1 {
+		t.Errorf("Expected 1 invalid-token rejection but got %v", got)
+	}
+	if got := testutil.CollectAndCount(m.uploadSize); got != 1 {
+		t.Errorf("Expected the upload size histogram but got %d metrics", got)
+	}
+}
+
+func TestMiddleware_counts_validation_failures(t *testing.T) {
+	m := New()
+	invalid := handler.NewProblem(handler.ProblemInvalidQuestionSet, "")
+	invalid.Errors = quiz.ValidationErrors{
+		{Index: 0, Field: "options[2]", Reason: "must not be empty"},
+		{Index: 1, Field: "options[0]", Reason: "must not be empty"},
+		{Index: 1, Field: "answers", Reason: "must contain at least 1 answer"},
+		{Index: quiz.QuestionSetIndex, Field: "", Reason: "must contain at least 1 question"},
+	}
+	m.Middleware(problemHandler(invalid))(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/upload/quiz", nil))
+	m.Middleware(problemHandler(handler.NewProblem(handler.ProblemInvalidQuestionSet, "not json")))(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/upload/quiz", nil))
+
+	tests := map[string]float64{
+		"options": 2,
+		"answers": 1,
+		"question_set": 1,
+		"unparseable": 1,
+	}
+	for reason, want := range tests {
+		if got := testutil.ToFloat64(m.validationFailures.WithLabelValues(reason)); got != want {
+			t.Errorf("Expected %v '%s' failures but got %v", want, reason, got)
+		}
+	}
+}
+
+type mockQuizStore struct {
+	quiz.QuizStore
+	err error
+}
+
+func (s mockQuizStore) Write(quizId string, qAndA *quiz.QuestionAndAnswers) error {
+	return s.err
+}
+
+func TestInstrumentQuizStore(t *testing.T) {
+	m := New()
+	storeErr := errors.New("disk full")
+
+	m.InstrumentQuizStore(mockQuizStore{}).Write("quiz", &quiz.QuestionAndAnswers{})
+	err := m.InstrumentQuizStore(mockQuizStore{err: storeErr}).Write("quiz", &quiz.QuestionAndAnswers{})
+
+	if err != storeErr {
+		t.Errorf("Expected the store's error but got %v", err)
+	}
+	if got := testutil.CollectAndCount(m.storeWriteDuration); got != 2 {
+		t.Errorf("Expected write durations for success and error but got %d", got)
+	}
+}
@@ -0,0 +1,78 @@
+package publish
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/quiz"
+)
+
+const BrokerNone = "none"
+const BrokerRabbitMq = "rabbitmq"
+const BrokerRedis = "redis"
+
+// Default RabbitMQ queue or Redis channel the events are published on
+const DefaultChannel = "question-set-ready"
+
+const QuestionSetReadyEventType = "com.ryangwaite.mc-speedrun.question-set.ready.v1"
+const QuestionSetReadyEventSource = "/mc-speedrun/question-set-loader"
+
+// Sent once a question set has been stored so that the quiz can be started with it.
+// Same envelope as the quiz complete event sent by the speed-run service
+type QuestionSetReadyEvent struct {
+	Type		string							`json:"type"`
+	Source		string							`json:"source"`
+	Id			string							`json:"id"`
+	Time		time.Time						`json:"time"`
+	Data		QuestionSetReadyEventDataV1		`json:"data"`
+}
+
+type QuestionSetReadyEventDataV1 struct {
+	QuizId			string		`json:"quizId"`
+	QuestionCount	int			`json:"questionCount"`
+	Categories		[]string	`json:"categories"`
+	ContentHash		string		`json:"contentHash"`
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, event QuestionSetReadyEvent) error
+	Close() error
+}
+
+// Returns the event announcing that qAndA has been stored for the quiz
+func NewQuestionSetReadyEvent(quizId string, qAndA quiz.QuestionAndAnswers) (QuestionSetReadyEvent, error) {
+	contentHash, err := qAndA.ContentHash()
+	if err != nil {
+		return QuestionSetReadyEvent{}, fmt.Errorf("failed to hash question set: %w", err)
+	}
+	id, err := newEventId()
+	if err != nil {
+		return QuestionSetReadyEvent{}, fmt.Errorf("failed to generate event id: %w", err)
+	}
+
+	return QuestionSetReadyEvent{
+		Type: QuestionSetReadyEventType,
+		Source: QuestionSetReadyEventSource,
+		Id: id,
+		Time: time.Now().UTC(),
+		Data: QuestionSetReadyEventDataV1{
+			QuizId: quizId,
+			QuestionCount: len(qAndA),
+			Categories: qAndA.Categories(),
+			ContentHash: contentHash,
+		},
+	}, nil
+}
+
+// Returns a random (version 4) UUID like the ids of the speed-run service's events
+func newEventId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // Version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
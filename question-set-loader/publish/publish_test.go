@@ -0,0 +1,260 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Ryangwaite/mc-speedrun/question-set-loader/quiz"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/go-cmp/cmp"
+	"github.com/streadway/amqp"
+)
+
+var qAndA = quiz.QuestionAndAnswers{
+	{Question: "q1", Category: "tech", Options: []string{"a", "b"}, Answers: []int{0}},
+	{Question: "q2", Category: "food", Options: []string{"a", "b"}, Answers: []int{1}},
+	{Question: "q3", Category: "tech", Options: []string{"a", "b"}, Answers: []int{0, 1}},
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewQuestionSetReadyEvent(t *testing.T) {
+	event, err := NewQuestionSetReadyEvent("quiz1", qAndA)
+	if err != nil {
+		t.Fatalf("Failed to build event: %v", err)
+	}
+
+	contentHash, _ := qAndA.ContentHash()
+	want := QuestionSetReadyEventDataV1{QuizId: "quiz1", QuestionCount: 3, Categories: []string{"food", "tech"}, ContentHash: contentHash}
+	if diff := cmp.Diff(want, event.Data); diff != "" {
+		t.Errorf("Wrong event data: %s", diff)
+	}
+	if event.Type != QuestionSetReadyEventType || event.Source != QuestionSetReadyEventSource {
+		t.Errorf("Wrong event type '%s' or source '%s'", event.Type, event.Source)
+	}
+	if !uuidPattern.MatchString(event.Id) {
+		t.Errorf("Expected a UUID for the id but got '%s'", event.Id)
+	}
+	if time.Since(event.Time) > time.Minute {
+		t.Errorf("Expected the event time to be now but got %s", event.Time)
+	}
+}
+
+// The envelope is serialized with the same keys as the quiz complete event
+func TestQuestionSetReadyEvent_json(t *testing.T) {
+	event := QuestionSetReadyEvent{
+		Type: QuestionSetReadyEventType,
+		Source: QuestionSetReadyEventSource,
+		Id: "id",
+		Time: time.Date(2022, 1, 27, 8, 54, 38, 0, time.UTC),
+		Data: QuestionSetReadyEventDataV1{QuizId: "quiz1", QuestionCount: 1, Categories: []string{"tech"}, ContentHash: "sha256:ab"},
+	}
+	got, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("Failed to serialize event: %v", err)
+	}
+
+	want := `{"type":"com.ryangwaite.mc-speedrun.question-set.ready.v1","source":"/mc-speedrun/question-set-loader","id":"id",` +
+		`"time":"2022-01-27T08:54:38Z","data":{"quizId":"quiz1","questionCount":1,"categories":["tech"],"contentHash":"sha256:ab"}}`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("Wrong event json: %s", diff)
+	}
+}
+
+//---------------------------------------------------
+// Mock implementations of the RabbitMqPublisher components
+
+type mockAmqpConnection struct {
+	closeCallCount int
+}
+
+func (m *mockAmqpConnection) Close() error {
+	m.closeCallCount++
+	return nil
+}
+
+type publishCall struct {
+	exchange	string
+	key			string
+	msg			amqp.Publishing
+}
+
+type mockAmqpChannel struct {
+	published		[]publishCall
+	closeCallCount	int
+	err				error
+}
+
+func (m *mockAmqpChannel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.published = append(m.published, publishCall{exchange, key, msg})
+	return nil
+}
+
+// Connections made by a publisher, in order
+type mockAmqpDialer struct {
+	conns		[]*mockAmqpConnection
+	channels	[]*mockAmqpChannel
+	closed		[]chan *amqp.Error
+	err			error
+}
+
+func (m *mockAmqpDialer) dial() (amqpConnection, amqpChannel, <-chan *amqp.Error, error) {
+	if m.err != nil {
+		return nil, nil, nil, m.err
+	}
+	conn, amqpCh, closed := &mockAmqpConnection{}, &mockAmqpChannel{}, make(chan *amqp.Error, 1)
+	m.conns = append(m.conns, conn)
+	m.channels = append(m.channels, amqpCh)
+	m.closed = append(m.closed, closed)
+	return conn, amqpCh, closed, nil
+}
+
+func newMockRabbitMqPublisher(t *testing.T, dialer *mockAmqpDialer) *RabbitMqPublisher {
+	publisher := &RabbitMqPublisher{dial: dialer.dial, queueName: DefaultChannel}
+	if err := publisher.connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	return publisher
+}
+
+func (m *mockAmqpChannel) Close() error {
+	m.closeCallCount++
+	return nil
+}
+
+//---------------------------------------------------
+
+func TestRabbitMqPublisher_Publish(t *testing.T) {
+	dialer := &mockAmqpDialer{}
+	publisher := newMockRabbitMqPublisher(t, dialer)
+	mockConnection, mockChannel := dialer.conns[0], dialer.channels[0]
+
+	event, _ := NewQuestionSetReadyEvent("quiz1", qAndA)
+	if err := publisher.Publish(context.Background(), event); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+
+	if len(mockChannel.published) != 1 {
+		t.Fatalf("Expected 1 message to be published but got %d", len(mockChannel.published))
+	}
+	published := mockChannel.published[0]
+	if published.exchange != "" || published.key != DefaultChannel {
+		t.Errorf("Expected publishing to the default exchange with key '%s' but got '%s' and '%s'", DefaultChannel, published.exchange, published.key)
+	}
+	if published.msg.DeliveryMode != amqp.Persistent || published.msg.ContentType != "application/json" {
+		t.Errorf("Expected a persistent json message but got %+v", published.msg)
+	}
+	var got QuestionSetReadyEvent
+	if err := json.Unmarshal(published.msg.Body, &got); err != nil {
+		t.Fatalf("Failed to deserialize published message: %v", err)
+	}
+	if diff := cmp.Diff(event, got); diff != "" {
+		t.Errorf("Wrong event published: %s", diff)
+	}
+
+	publisher.Close()
+	if mockChannel.closeCallCount != 1 || mockConnection.closeCallCount != 1 {
+		t.Errorf("Expected the channel and connection to be closed")
+	}
+	if err := publisher.Publish(context.Background(), event); err == nil || len(dialer.conns) != 1 {
+		t.Errorf("Expected publishing after closing to fail without dialling")
+	}
+}
+
+// Tests the publisher dials the broker again when the connection is lost
+func TestRabbitMqPublisher_reconnect(t *testing.T) {
+	tests := map[string]struct {
+		lose		func(dialer *mockAmqpDialer)
+		dialErr		error
+		wantErr		bool
+		wantDials	int
+	}{
+		"closed by broker": {
+			func(dialer *mockAmqpDialer) { dialer.closed[0] <- amqp.ErrClosed },
+			nil, false, 2,
+		},
+		"publish fails": {
+			func(dialer *mockAmqpDialer) { dialer.channels[0].err = amqp.ErrClosed },
+			nil, false, 2,
+		},
+		"broker down": {
+			func(dialer *mockAmqpDialer) { dialer.closed[0] <- amqp.ErrClosed },
+			errors.New("connection refused"), true, 1,
+		},
+	}
+
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			dialer := &mockAmqpDialer{}
+			publisher := newMockRabbitMqPublisher(t, dialer)
+			test.lose(dialer)
+			dialer.err = test.dialErr
+
+			event, _ := NewQuestionSetReadyEvent("quiz1", qAndA)
+			err := publisher.Publish(context.Background(), event)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Expected error %t but got %v", test.wantErr, err)
+			}
+			if len(dialer.conns) != test.wantDials {
+				t.Fatalf("Expected %d dials but got %d", test.wantDials, len(dialer.conns))
+			}
+			if dialer.conns[0].closeCallCount != 1 {
+				t.Errorf("Expected the lost connection to be closed")
+			}
+			if test.wantErr {
+				return
+			}
+			if published := dialer.channels[len(dialer.channels)-1].published; len(published) != 1 {
+				t.Errorf("Expected the event to be published on the new connection but got %d", len(published))
+			}
+
+			// Recovers once the broker is back
+			dialer.err = nil
+			if err := publisher.Publish(context.Background(), event); err != nil {
+				t.Errorf("Failed to publish after reconnecting: %v", err)
+			}
+		})
+	}
+}
+
+func TestRedisPublisher_Publish(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+	subscriber := redis.NewClient(&redis.Options{Addr: mr.Addr()}).Subscribe(context.Background(), DefaultChannel)
+	defer subscriber.Close()
+	if _, err := subscriber.Receive(context.Background()); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	publisher := NewRedisPublisher(RedisOptions{Addr: mr.Addr(), Channel: DefaultChannel})
+	defer publisher.Close()
+
+	event, _ := NewQuestionSetReadyEvent("quiz1", qAndA)
+	if err := publisher.Publish(context.Background(), event); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+
+	select {
+	case msg := <-subscriber.Channel():
+		var got QuestionSetReadyEvent
+		if err := json.Unmarshal([]byte(msg.Payload), &got); err != nil {
+			t.Fatalf("Failed to deserialize published message: %v", err)
+		}
+		if diff := cmp.Diff(event, got); diff != "" {
+			t.Errorf("Wrong event published: %s", diff)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Event wasn't published")
+	}
+}
@@ -0,0 +1,184 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/streadway/amqp"
+)
+
+//--------------------------------------------------------
+// Interfaces for injecting mocks
+type amqpConnection interface {
+	Close() error
+}
+
+type amqpChannel interface {
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	Close() error
+}
+
+//------------------------------------------------------
+
+type RabbitMqOptions struct {
+	Host		string
+	Port		int
+	Username	string
+	Password	string
+	QueueName	string
+}
+
+// Publishes the events on a durable RabbitMQ queue through the default exchange, the
+// same way the speed-run service publishes quiz complete events. The publisher lives
+// as long as the container or warm Lambda, so when the broker closes the connection it
+// is dialled again on the next publish
+type RabbitMqPublisher struct {
+	dial		func() (amqpConnection, amqpChannel, <-chan *amqp.Error, error)
+	conn		amqpConnection
+	amqpCh		amqpChannel
+	closed		<-chan *amqp.Error // Notified when the broker closes the channel or its connection
+	queueName	string
+	stopped		bool // Closed by the caller, so not to be dialled again
+	mu			sync.Mutex // Publishes on a channel mustn't interleave
+}
+
+// Get a new RabbitMQ connected publisher
+func NewRabbitMqPublisher(o RabbitMqOptions) (*RabbitMqPublisher, error) {
+	p := &RabbitMqPublisher{
+		dial: func() (amqpConnection, amqpChannel, <-chan *amqp.Error, error) {
+			return dialRabbitMq(o)
+		},
+		queueName: o.QueueName,
+	}
+	if err := p.connect(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Connects to the broker and declares the queue
+func dialRabbitMq(o RabbitMqOptions) (amqpConnection, amqpChannel, <-chan *amqp.Error, error) {
+	connectionString := fmt.Sprintf("amqp://%s:%s@%s:%d/", o.Username, o.Password, o.Host, o.Port)
+	conn, err := amqp.Dial(connectionString) // TODO: Add TLS
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	amqpCh, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+
+	_, err = amqpCh.QueueDeclare(
+		o.QueueName,
+		true,			// durable
+		false,			// dont delete when unused
+		false,			// not exclusive
+		false,			// no-wait
+		nil,
+	)
+	if err != nil {
+		amqpCh.Close()
+		conn.Close()
+		return nil, nil, nil, err
+	}
+
+	// Channels are closed along with their connection so this hears about both
+	closed := amqpCh.NotifyClose(make(chan *amqp.Error, 1))
+	return conn, amqpCh, closed, nil
+}
+
+// Dials the broker, replacing the previous connection. Must hold mu or be constructing p
+func (p *RabbitMqPublisher) connect() error {
+	p.disconnect()
+	conn, amqpCh, closed, err := p.dial()
+	if err != nil {
+		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+	p.conn, p.amqpCh, p.closed = conn, amqpCh, closed
+	return nil
+}
+
+// Closes the connection, if there is one. Must hold mu
+func (p *RabbitMqPublisher) disconnect() error {
+	var err error
+	if p.amqpCh != nil {
+		p.amqpCh.Close()
+	}
+	if p.conn != nil {
+		err = p.conn.Close()
+	}
+	p.conn, p.amqpCh, p.closed = nil, nil, nil
+	return err
+}
+
+// Whether the broker has closed the connection. Must hold mu
+func (p *RabbitMqPublisher) isClosed() bool {
+	if p.amqpCh == nil {
+		return true
+	}
+	select {
+	case <-p.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *RabbitMqPublisher) Publish(ctx context.Context, event QuestionSetReadyEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	msg := amqp.Publishing{
+		ContentType: "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId: event.Id,
+		Timestamp: event.Time,
+		Type: event.Type,
+		Body: body,
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return errors.New("publisher is closed")
+	}
+	if p.isClosed() {
+		if err := p.connect(); err != nil {
+			return err
+		}
+	}
+	if err := p.publish(msg); err == nil {
+		return nil
+	}
+	// The connection may have dropped without the close being noticed yet, so the
+	// event is published again once on a new one
+	if err := p.connect(); err != nil {
+		return err
+	}
+	return p.publish(msg)
+}
+
+// Must hold mu
+func (p *RabbitMqPublisher) publish(msg amqp.Publishing) error {
+	return p.amqpCh.Publish(
+		"",				// default exchange, routes to the queue named by the key
+		p.queueName,
+		false,			// mandatory
+		false,			// immediate
+		msg,
+	)
+}
+
+// Close the underlying RabbitMQ connection
+func (p *RabbitMqPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopped = true
+	return p.disconnect()
+}
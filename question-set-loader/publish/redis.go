@@ -0,0 +1,45 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type RedisOptions struct {
+	Addr		string
+	Password	string
+	Channel		string
+}
+
+// Publishes the events on a Redis pub/sub channel. Only subscribers listening at the
+// time receive them
+type RedisPublisher struct {
+	rdb		*redis.Client
+	channel	string
+}
+
+func NewRedisPublisher(o RedisOptions) *RedisPublisher {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     o.Addr,
+		Password: o.Password,
+	})
+
+	return &RedisPublisher{
+		rdb: rdb,
+		channel: o.Channel,
+	}
+}
+
+func (p *RedisPublisher) Publish(ctx context.Context, event QuestionSetReadyEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.rdb.Publish(ctx, p.channel, body).Err()
+}
+
+func (p *RedisPublisher) Close() error {
+	return p.rdb.Close()
+}
@@ -0,0 +1,130 @@
+package quiz
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Matches an Aiken option line e.g. "A. option" or "B) option"
+var aikenOptionPattern = regexp.MustCompile(`^([A-Z])[.)]\s+(.*)$`)
+
+// Matches an Aiken answer line e.g. "ANSWER: B". Multiple correct answers may be
+// comma separated e.g. "ANSWER: A, C"
+var aikenAnswerPattern = regexp.MustCompile(`^ANSWER:\s*(.*)$`)
+
+// Deserializes a Moodle Aiken quiz file. Each question is on one line, followed by
+// its lettered options and then the answer line. Aiken has no categories so every
+// question is given DefaultCategory. See https://docs.moodle.org/en/Aiken_format
+func quizFileFromAiken(fileBytes []byte) (QuestionAndAnswers, error) {
+	qAndA := QuestionAndAnswers{}
+	lines := []int{}
+	errs := ValidationErrors{}
+
+	var current *QuestionAndAnswer
+	currentLine := 0
+	// Adds the current question to the set, or reports it if it was never answered
+	finish := func(lineNum int) {
+		if current == nil {
+			return
+		}
+		if current.Answers == nil {
+			errs = append(errs, ValidationError{
+				Index:  len(qAndA),
+				Reason: fmt.Sprintf("question starting on line %d has no 'ANSWER:' line", currentLine),
+				Line:   lineNum,
+			})
+		}
+		qAndA = append(qAndA, *current)
+		lines = append(lines, currentLine)
+		current = nil
+	}
+
+	file := strings.TrimPrefix(string(fileBytes), "\ufeff") // Byte order mark
+	rawLines := strings.Split(strings.ReplaceAll(file, "\r\n", "\n"), "\n")
+	for i, rawLine := range rawLines {
+		lineNum := i + 1
+		line := strings.TrimSpace(rawLine)
+		if line == "" {
+			continue
+		}
+
+		if match := aikenAnswerPattern.FindStringSubmatch(line); match != nil {
+			if current == nil {
+				errs = append(errs, ValidationError{Index: len(qAndA), Reason: "'ANSWER:' line without a question", Line: lineNum})
+				continue
+			}
+			current.Answers = []int{}
+			for _, letter := range strings.Split(match[1], ",") {
+				letter = strings.TrimSpace(letter)
+				index := -1
+				if len(letter) == 1 {
+					index = int(letter[0]) - 'A'
+				}
+				if index < 0 || index >= len(current.Options) {
+					errs = append(errs, ValidationError{
+						Index:  len(qAndA),
+						Field:  "answers",
+						Reason: fmt.Sprintf("'%s' is not one of the option letters", letter),
+						Line:   lineNum,
+					})
+					continue
+				}
+				current.Answers = append(current.Answers, index)
+			}
+			finish(lineNum)
+			continue
+		}
+
+		if match := aikenOptionPattern.FindStringSubmatch(line); match != nil && current != nil {
+			expectedLetter := string(rune('A' + len(current.Options)))
+			if match[1] != expectedLetter {
+				errs = append(errs, ValidationError{
+					Index:  len(qAndA),
+					Field:  fmt.Sprintf("options[%d]", len(current.Options)),
+					Reason: fmt.Sprintf("expected option '%s' but got '%s'", expectedLetter, match[1]),
+					Line:   lineNum,
+				})
+			}
+			current.Options = append(current.Options, match[2])
+			continue
+		}
+
+		if current != nil {
+			if len(current.Options) == 0 {
+				errs = append(errs, ValidationError{
+					Index:  len(qAndA),
+					Field:  "question",
+					Reason: "must be on a single line",
+					Line:   lineNum,
+				})
+				continue
+			}
+			// The previous question was never answered
+			finish(lineNum)
+		}
+
+		current = &QuestionAndAnswer{Question: line, Category: DefaultCategory, Options: []string{}}
+		currentLine = lineNum
+	}
+	finish(len(rawLines))
+
+	// The questions that could be read are returned so the file can still be summarised
+	if len(errs) > 0 {
+		return qAndA, errs
+	}
+	return qAndA, annotateLines(qAndA.Validate(), lines)
+}
+
+// True if the file looks like Aiken rather than GIFT i.e. it has an answer line
+// and no GIFT answer blocks
+func looksLikeAiken(fileBytes []byte) bool {
+	hasAnswerLine := false
+	for _, line := range strings.Split(string(fileBytes), "\n") {
+		if aikenAnswerPattern.MatchString(strings.TrimSpace(line)) {
+			hasAnswerLine = true
+			break
+		}
+	}
+	return hasAnswerLine && indexUnescaped(string(fileBytes), "{", 0) == -1
+}
@@ -0,0 +1,94 @@
+package quiz
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseQuizFile_aiken(t *testing.T) {
+	fileBytes := []byte(`Which of these databases are relational?
+A. DynamoDB
+B. Athena
+C. Redshift
+D. Redis
+ANSWER: C
+
+Which of these are fiat currencies?
+A) USD
+B) Bitcoin
+C) AUD
+ANSWER: A, C
+`)
+
+	got, err := ParseQuizFile(&fileBytes, FormatAiken)
+	if err != nil {
+		t.Fatalf("Failed to parse aiken quiz file: %v", err)
+	}
+
+	want := QuestionAndAnswers{
+		{Question: "Which of these databases are relational?", Category: DefaultCategory, Options: []string{"DynamoDB", "Athena", "Redshift", "Redis"}, Answers: []int{2}},
+		{Question: "Which of these are fiat currencies?", Category: DefaultCategory, Options: []string{"USD", "Bitcoin", "AUD"}, Answers: []int{0, 2}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Parsed aiken doesn't match: %s", diff)
+	}
+}
+
+func TestParseQuizFile_aiken_invalid(t *testing.T) {
+	fileBytes := []byte(`Question one?
+A. a
+C. b
+ANSWER: E
+
+Question two?
+A. a
+B. b
+
+Question three?
+A. a
+B. b
+ANSWER: A
+`)
+
+	got, err := ParseQuizFile(&fileBytes, FormatAiken)
+	validationErrors, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("Expected ValidationErrors, got: %v", err)
+	}
+	if len(got) != 3 {
+		t.Errorf("Expected the questions to be returned with the errors but got %d", len(got))
+	}
+
+	want := ValidationErrors{
+		{Index: 0, Field: "options[1]", Reason: "expected option 'B' but got 'C'", Line: 3},
+		{Index: 0, Field: "answers", Reason: "'E' is not one of the option letters", Line: 4},
+		{Index: 1, Reason: "question starting on line 6 has no 'ANSWER:' line", Line: 10},
+	}
+	if diff := cmp.Diff(want, validationErrors); diff != "" {
+		t.Fatalf("Wrong validation errors: %s", diff)
+	}
+}
+
+func TestParseQuizFile_text_detects_format(t *testing.T) {
+	tests := map[string]string{
+		"aiken": "Question?\nA. a\nB. b\nANSWER: B\n",
+		"gift":  "Question? {~a =b}\n",
+	}
+
+	want := QuestionAndAnswers{
+		{Question: "Question?", Category: DefaultCategory, Options: []string{"a", "b"}, Answers: []int{1}},
+	}
+	for name, file := range tests {
+		t.Run(name, func(t *testing.T) {
+			fileBytes := []byte(file)
+			got, err := ParseQuizFile(&fileBytes, FormatText)
+			if err != nil {
+				t.Fatalf("Failed to parse text quiz file: %v", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Fatalf("Parsed text doesn't match: %s", diff)
+			}
+		})
+	}
+}
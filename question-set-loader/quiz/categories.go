@@ -0,0 +1,57 @@
+package quiz
+
+import (
+	"sort"
+	"strings"
+)
+
+// Number of questions in a category
+type CategoryCount struct {
+	Name			string	`json:"name"`
+	QuestionCount	int		`json:"questionCount"`
+}
+
+// Tidies the categories in place so that ones that only differ by case or spacing are
+// the same. Runs of whitespace become a single space and leading and trailing spaces
+// are dropped. Every case variant of a category takes the spelling of its first question
+// e.g. "Food", "food " and "FOOD" are all "Food"
+func (qAndA QuestionAndAnswers) normaliseCategories() {
+	spellings := map[string]string{}
+	for i := range qAndA {
+		category := strings.Join(strings.Fields(qAndA[i].Category), " ")
+		key := strings.ToLower(category)
+		if spelling, ok := spellings[key]; ok {
+			category = spelling
+		} else {
+			spellings[key] = category
+		}
+		qAndA[i].Category = category
+	}
+}
+
+// Returns the distinct categories of the questions, sorted
+func (qAndA QuestionAndAnswers) Categories() []string {
+	seen := map[string]bool{}
+	categories := []string{}
+	for _, q := range qAndA {
+		if !seen[q.Category] {
+			seen[q.Category] = true
+			categories = append(categories, q.Category)
+		}
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// Returns each distinct category with the number of questions in it, sorted by name
+func (qAndA QuestionAndAnswers) CategoryCounts() []CategoryCount {
+	counts := map[string]int{}
+	for _, q := range qAndA {
+		counts[q.Category]++
+	}
+	categoryCounts := []CategoryCount{}
+	for _, category := range qAndA.Categories() {
+		categoryCounts = append(categoryCounts, CategoryCount{Name: category, QuestionCount: counts[category]})
+	}
+	return categoryCounts
+}
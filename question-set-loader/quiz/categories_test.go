@@ -0,0 +1,63 @@
+package quiz
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNormaliseCategories(t *testing.T) {
+	qAndA := QuestionAndAnswers{
+		{Question: "q1", Category: " General   knowledge "},
+		{Question: "q2", Category: "general knowledge"},
+		{Question: "q3", Category: "GENERAL\tKNOWLEDGE"},
+		{Question: "q4", Category: "food"},
+		{Question: "q5", Category: "Food"},
+		{Question: "q6", Category: "   "},
+	}
+	qAndA.normaliseCategories()
+
+	got := []string{}
+	for _, q := range qAndA {
+		got = append(got, q.Category)
+	}
+	want := []string{"General knowledge", "General knowledge", "General knowledge", "food", "food", ""}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Wrong categories: %s", diff)
+	}
+}
+
+// Uploaded question sets are normalised before they're validated so that a blank
+// category is still rejected
+func TestParseQuizFile_normalises_categories(t *testing.T) {
+	csv := []byte("question,category,option,option,answers\nq1,Food ,a,b,0\nq2,FOOD,a,b,1\nq3,  tech,a,b,0\n")
+	got, err := ParseQuizFile(&csv, FormatCsv)
+	if err != nil {
+		t.Fatalf("Failed to parse question set: %v", err)
+	}
+	if diff := cmp.Diff([]string{"Food", "tech"}, got.Categories()); diff != "" {
+		t.Errorf("Wrong categories: %s", diff)
+	}
+
+	blank := []byte(`[{"question": "q1", "category": "  ", "options": ["a", "b"], "answers": [0]}]`)
+	_, err = ParseQuizFile(&blank, FormatJson)
+	want := ValidationErrors{{Index: 0, Field: "category", Reason: "must be non-empty"}}
+	if diff := cmp.Diff(want, err); diff != "" {
+		t.Errorf("Wrong error: %s", diff)
+	}
+}
+
+func TestCategoryCounts(t *testing.T) {
+	qAndA := QuestionAndAnswers{
+		{Question: "q1", Category: "tech"},
+		{Question: "q2", Category: "food"},
+		{Question: "q3", Category: "tech"},
+	}
+	want := []CategoryCount{{Name: "food", QuestionCount: 1}, {Name: "tech", QuestionCount: 2}}
+	if diff := cmp.Diff(want, qAndA.CategoryCounts()); diff != "" {
+		t.Errorf("Wrong category counts: %s", diff)
+	}
+	if diff := cmp.Diff([]CategoryCount{}, QuestionAndAnswers{}.CategoryCounts()); diff != "" {
+		t.Errorf("Wrong category counts for an empty question set: %s", diff)
+	}
+}
@@ -0,0 +1,195 @@
+package quiz
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CSV column headers. Headers are matched case-insensitively and option columns may be
+// numbered e.g. "option1", "option 2"
+const (
+	csvQuestionHeader = "question"
+	csvCategoryHeader = "category"
+	csvOptionHeader   = "option"
+	csvAnswersHeader  = "answers"
+	// Optional
+	csvTypeHeader        = "type"
+	csvPointsHeader      = "points"
+	csvTimeLimitHeader   = "timelimitseconds"
+	csvExplanationHeader = "explanation"
+)
+
+// Separates the option indices in the answers column
+const csvAnswersSeparator = ";"
+
+// Column positions of the fields in a CSV quiz file
+type csvLayout struct {
+	question int
+	category int
+	options  []int
+	answers  int
+	// -1 when the optional column isn't present
+	questionType int
+	points       int
+	timeLimit    int
+	explanation  int
+}
+
+// Deserializes a CSV quiz file. The first record is the header, every record after
+// it is one question. e.g.
+//
+//	question,category,option,option,option,option,answers
+//	What is 1+1?,maths,1,2,3,4,1
+//	Which are even?,maths,1,2,3,4,1;3
+//
+// Empty option cells are ignored so that questions can have differing numbers of options.
+// The type, points, time limit seconds and explanation columns are optional
+func quizFileFromCsv(fileBytes []byte) (QuestionAndAnswers, error) {
+	reader := csv.NewReader(bytes.NewReader(fileBytes))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	layout, err := parseCsvHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	qAndA := QuestionAndAnswers{}
+	validationErrors := ValidationErrors{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read record: %w", err)
+		}
+
+		questionIndex := len(qAndA)
+		question := QuestionAndAnswer{
+			Question: record[layout.question],
+			Category: record[layout.category],
+			Options:  []string{},
+			Answers:  []int{},
+		}
+		for _, column := range layout.options {
+			if option := strings.TrimSpace(record[column]); option != "" {
+				question.Options = append(question.Options, option)
+			}
+		}
+		for i, rawAnswer := range strings.Split(record[layout.answers], csvAnswersSeparator) {
+			rawAnswer = strings.TrimSpace(rawAnswer)
+			if rawAnswer == "" {
+				continue
+			}
+			answer, err := strconv.Atoi(rawAnswer)
+			if err != nil {
+				line, _ := reader.FieldPos(layout.answers)
+				validationErrors = append(validationErrors, ValidationError{
+					Index:  questionIndex,
+					Field:  fmt.Sprintf("answers[%d]", i),
+					Reason: fmt.Sprintf("'%s' is not an option index", rawAnswer),
+					Line:   line,
+				})
+				continue
+			}
+			question.Answers = append(question.Answers, answer)
+		}
+		if layout.questionType != -1 {
+			question.Type = strings.TrimSpace(record[layout.questionType])
+		}
+		if layout.explanation != -1 {
+			question.Explanation = strings.TrimSpace(record[layout.explanation])
+		}
+		numberColumns := []struct {
+			field  string
+			column int
+			value  *int
+		}{
+			{"points", layout.points, &question.Points},
+			{"timeLimitSeconds", layout.timeLimit, &question.TimeLimitSeconds},
+		}
+		for _, number := range numberColumns {
+			if number.column == -1 {
+				continue
+			}
+			rawValue := strings.TrimSpace(record[number.column])
+			if rawValue == "" {
+				continue
+			}
+			value, err := strconv.Atoi(rawValue)
+			if err != nil {
+				line, _ := reader.FieldPos(number.column)
+				validationErrors = append(validationErrors, ValidationError{
+					Index:  questionIndex,
+					Field:  number.field,
+					Reason: fmt.Sprintf("'%s' is not a whole number", rawValue),
+					Line:   line,
+				})
+				continue
+			}
+			*number.value = value
+		}
+		qAndA = append(qAndA, question)
+	}
+
+	// The questions that could be read are returned so the file can still be summarised
+	if len(validationErrors) > 0 {
+		return qAndA, validationErrors
+	}
+	return qAndA, nil
+}
+
+// Locates the columns of each field from the header record
+func parseCsvHeader(header []string) (csvLayout, error) {
+	layout := csvLayout{question: -1, category: -1, answers: -1, questionType: -1, points: -1, timeLimit: -1, explanation: -1}
+	for column, rawName := range header {
+		name := strings.ToLower(strings.Join(strings.Fields(rawName), ""))
+		switch {
+		case name == csvQuestionHeader:
+			layout.question = column
+		case name == csvCategoryHeader:
+			layout.category = column
+		case name == csvAnswersHeader:
+			layout.answers = column
+		case name == csvTypeHeader:
+			layout.questionType = column
+		case name == csvPointsHeader:
+			layout.points = column
+		case name == csvTimeLimitHeader:
+			layout.timeLimit = column
+		case name == csvExplanationHeader:
+			layout.explanation = column
+		case strings.HasPrefix(name, csvOptionHeader):
+			layout.options = append(layout.options, column)
+		default:
+			return layout, fmt.Errorf("unexpected column '%s' in header", rawName)
+		}
+	}
+
+	missing := []string{}
+	if layout.question == -1 {
+		missing = append(missing, csvQuestionHeader)
+	}
+	if layout.category == -1 {
+		missing = append(missing, csvCategoryHeader)
+	}
+	if len(layout.options) == 0 {
+		missing = append(missing, csvOptionHeader)
+	}
+	if layout.answers == -1 {
+		missing = append(missing, csvAnswersHeader)
+	}
+	if len(missing) > 0 {
+		return layout, fmt.Errorf("header is missing column(s): %s", strings.Join(missing, ", "))
+	}
+
+	return layout, nil
+}
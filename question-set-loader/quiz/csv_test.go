@@ -0,0 +1,107 @@
+package quiz
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseQuizFile_csv(t *testing.T) {
+	fileBytes := []byte(`Question,Category,Option 1,Option 2,Option 3,Option 4,Answers
+question 1,food,a,b,c,d,1;2
+"question, 2",tech,i,ii,,,0
+`)
+
+	got, err := ParseQuizFile(&fileBytes, FormatCsv)
+	if err != nil {
+		t.Fatalf("Failed to parse csv quiz file: %v", err)
+	}
+
+	want := QuestionAndAnswers{
+		{Question: "question 1", Category: "food", Options: []string{"a", "b", "c", "d"}, Answers: []int{1, 2}},
+		{Question: "question, 2", Category: "tech", Options: []string{"i", "ii"}, Answers: []int{0}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Parsed csv doesn't match: %s", diff)
+	}
+}
+
+func TestParseQuizFile_csv_optional_columns(t *testing.T) {
+	fileBytes := []byte(`question,category,option,option,answers,type,points,time limit seconds,explanation
+question 1,food,a,b,1,single-select,5,30,b is right
+question 2,food,True,False,0,true-false,,,
+`)
+
+	got, err := ParseQuizFile(&fileBytes, FormatCsv)
+	if err != nil {
+		t.Fatalf("Failed to parse csv quiz file: %v", err)
+	}
+
+	want := QuestionAndAnswers{
+		{Question: "question 1", Category: "food", Options: []string{"a", "b"}, Answers: []int{1}, Type: TypeSingleSelect, Points: 5, TimeLimitSeconds: 30, Explanation: "b is right"},
+		{Question: "question 2", Category: "food", Options: []string{"True", "False"}, Answers: []int{0}, Type: TypeTrueFalse},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Parsed csv doesn't match: %s", diff)
+	}
+}
+
+func TestParseQuizFile_csv_invalid_numbers(t *testing.T) {
+	fileBytes := []byte(`question,category,option,option,answers,points,timeLimitSeconds
+question 1,food,a,b,0,many,ten
+`)
+
+	_, err := ParseQuizFile(&fileBytes, FormatCsv)
+	validationErrors, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("Expected ValidationErrors, got: %v", err)
+	}
+
+	want := ValidationErrors{
+		{Index: 0, Field: "points", Reason: "'many' is not a whole number", Line: 2},
+		{Index: 0, Field: "timeLimitSeconds", Reason: "'ten' is not a whole number", Line: 2},
+	}
+	if diff := cmp.Diff(want, validationErrors); diff != "" {
+		t.Fatalf("Wrong validation errors: %s", diff)
+	}
+}
+
+func TestParseQuizFile_csv_invalid_answers(t *testing.T) {
+	fileBytes := []byte(`question,category,option,option,answers
+question 1,food,a,b,first;1
+question 2,food,a,b,0
+question 3,food,a,b,x
+`)
+
+	_, err := ParseQuizFile(&fileBytes, FormatCsv)
+	validationErrors, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("Expected ValidationErrors, got: %v", err)
+	}
+
+	want := ValidationErrors{
+		{Index: 0, Field: "answers[0]", Reason: "'first' is not an option index", Line: 2},
+		{Index: 2, Field: "answers[0]", Reason: "'x' is not an option index", Line: 4},
+	}
+	if diff := cmp.Diff(want, validationErrors); diff != "" {
+		t.Fatalf("Wrong validation errors: %s", diff)
+	}
+}
+
+func TestParseQuizFile_csv_invalid_header(t *testing.T) {
+	tests := map[string]string{
+		"missing answers":   "question,category,option,option\n",
+		"missing options":   "question,category,answers\n",
+		"unexpected column": "question,category,option,option,answers,difficulty\n",
+		"empty file":        "",
+	}
+
+	for name, file := range tests {
+		t.Run(name, func(t *testing.T) {
+			fileBytes := []byte(file)
+			if _, err := ParseQuizFile(&fileBytes, FormatCsv); err == nil {
+				t.Errorf("Failed to detect error")
+			}
+		})
+	}
+}
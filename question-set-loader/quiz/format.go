@@ -0,0 +1,67 @@
+package quiz
+
+import (
+	"mime"
+	"path/filepath"
+	"strings"
+)
+
+// The file formats that a quiz can be uploaded in
+type Format string
+
+const (
+	FormatJson  Format = "json"
+	FormatYaml  Format = "yaml"
+	FormatCsv   Format = "csv"
+	FormatGift  Format = "gift"
+	FormatAiken Format = "aiken"
+	// Plain text that's either GIFT or Aiken, decided from the file content
+	FormatText Format = "text"
+)
+
+// Maps recognised media types to their format
+var contentTypeFormats = map[string]Format{
+	"application/json":   FormatJson,
+	"text/json":          FormatJson,
+	"application/yaml":   FormatYaml,
+	"application/x-yaml": FormatYaml,
+	"text/yaml":          FormatYaml,
+	"text/x-yaml":        FormatYaml,
+	"text/csv":           FormatCsv,
+	"application/csv":    FormatCsv,
+	"text/plain":         FormatText,
+}
+
+// Maps recognised file extensions to their format
+var extensionFormats = map[string]Format{
+	".json":  FormatJson,
+	".yaml":  FormatYaml,
+	".yml":   FormatYaml,
+	".csv":   FormatCsv,
+	".gift":  FormatGift,
+	".aiken": FormatAiken,
+	".txt":   FormatText,
+}
+
+// Works out the format of an uploaded file from its Content-Type, falling back to the
+// extension of filename when the Content-Type is absent or generic (e.g. application/octet-stream).
+// A specific extension is preferred over a text/plain Content-Type. Defaults to FormatJson
+// when neither are recognised
+func DetectFormat(contentType string, filename string) Format {
+	contentTypeFormat := Format("")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentTypeFormat = contentTypeFormats[strings.ToLower(mediaType)]
+	}
+	if contentTypeFormat != "" && contentTypeFormat != FormatText {
+		return contentTypeFormat
+	}
+
+	if format, ok := extensionFormats[strings.ToLower(filepath.Ext(filename))]; ok {
+		return format
+	}
+	if contentTypeFormat != "" {
+		return contentTypeFormat
+	}
+
+	return FormatJson
+}
@@ -0,0 +1,80 @@
+package quiz
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := map[string]struct {
+		contentType string
+		filename    string
+		want        Format
+	}{
+		"json content type":          {"application/json", "quiz", FormatJson},
+		"yaml content type":          {"application/x-yaml", "quiz", FormatYaml},
+		"csv content type":           {"text/csv; charset=utf-8", "quiz", FormatCsv},
+		"content type over ext":      {"text/csv", "quiz.json", FormatCsv},
+		"generic content type":       {"application/octet-stream", "quiz.yml", FormatYaml},
+		"no content type":            {"", "QUIZ.CSV", FormatCsv},
+		"gift extension":             {"", "quiz.gift", FormatGift},
+		"aiken extension":            {"application/octet-stream", "quiz.aiken", FormatAiken},
+		"extension over plain text":  {"text/plain", "quiz.gift", FormatGift},
+		"plain text":                 {"text/plain", "quiz", FormatText},
+		"txt extension":              {"", "quiz.txt", FormatText},
+		"unrecognised defaults json": {"application/octet-stream", "quiz.dat", FormatJson},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := DetectFormat(tc.contentType, tc.filename)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Wrong format detected: %s", diff)
+			}
+		})
+	}
+}
+
+func TestParseQuizFile_yaml(t *testing.T) {
+	fileBytes := []byte(`
+- question: question 1
+  category: food
+  options: [a, b, c, d]
+  answers: [1, 2]
+- question: question 2
+  category: tech
+  options:
+    - i
+    - ii
+  answers:
+    - 0
+`)
+
+	got, err := ParseQuizFile(&fileBytes, FormatYaml)
+	if err != nil {
+		t.Fatalf("Failed to parse yaml quiz file: %v", err)
+	}
+
+	want := QuestionAndAnswers{
+		{Question: "question 1", Category: "food", Options: []string{"a", "b", "c", "d"}, Answers: []int{1, 2}},
+		{Question: "question 2", Category: "tech", Options: []string{"i", "ii"}, Answers: []int{0}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Parsed yaml doesn't match: %s", diff)
+	}
+}
+
+func TestParseQuizFile_yaml_validated(t *testing.T) {
+	fileBytes := []byte(`
+- question: question 1
+  category: food
+  options: [a, b]
+  answers: [3]
+`)
+
+	_, err := ParseQuizFile(&fileBytes, FormatYaml)
+	if _, ok := err.(ValidationErrors); !ok {
+		t.Fatalf("Expected ValidationErrors, got: %v", err)
+	}
+}
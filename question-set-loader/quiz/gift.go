@@ -0,0 +1,301 @@
+package quiz
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Category given to questions in formats that can't express one, or when a
+// GIFT file has no $CATEGORY header before the question
+const DefaultCategory = "general"
+
+// Placeholder put in the question text in place of the answer block for GIFT
+// "missing word" questions
+const giftMissingWordPlaceholder = "_____"
+
+const giftCategoryHeader = "$CATEGORY:"
+
+// Characters that can be escaped with a backslash in GIFT text
+const giftEscapableChars = "~=#{}:\\"
+
+// A question block in a GIFT file
+type giftBlock struct {
+	text      string
+	startLine int
+}
+
+// Returns the line in the file of the offset into the block text
+func (b giftBlock) lineOf(offset int) int {
+	return b.startLine + strings.Count(b.text[:offset], "\n")
+}
+
+type giftParser struct {
+	category  string
+	qAndA     QuestionAndAnswers
+	lines     []int // Line each question starts on
+	errs      ValidationErrors
+	questions int // Question blocks seen, including rejected ones
+}
+
+// Deserializes a Moodle GIFT quiz file. Multiple choice (including multiple correct
+// answers given with positive percentage weights), true/false and missing word questions
+// are supported. $CATEGORY headers set the category of the questions that follow.
+// Question types that can't be represented e.g. essay, matching, numeric and short
+// answer are rejected with the line they appear on. See https://docs.moodle.org/en/GIFT_format
+func quizFileFromGift(fileBytes []byte) (QuestionAndAnswers, error) {
+	p := giftParser{category: DefaultCategory, qAndA: QuestionAndAnswers{}}
+
+	for _, block := range splitGiftBlocks(string(fileBytes)) {
+		p.parseBlock(block)
+	}
+
+	// The questions that could be read are returned so the file can still be summarised
+	if len(p.errs) > 0 {
+		return p.qAndA, p.errs
+	}
+	return p.qAndA, annotateLines(p.qAndA.Validate(), p.lines)
+}
+
+// Splits the file into blank line separated blocks, dropping comments
+func splitGiftBlocks(file string) []giftBlock {
+	blocks := []giftBlock{}
+	var current []string
+	startLine := 0
+
+	flush := func() {
+		if len(current) > 0 {
+			blocks = append(blocks, giftBlock{strings.Join(current, "\n"), startLine})
+		}
+		current = nil
+	}
+
+	file = strings.TrimPrefix(file, "\ufeff") // Byte order mark
+	for i, line := range strings.Split(strings.ReplaceAll(file, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+		if trimmed == "" {
+			flush()
+			continue
+		}
+		if len(current) == 0 {
+			startLine = i + 1
+		}
+		current = append(current, line)
+	}
+	flush()
+
+	return blocks
+}
+
+func (p *giftParser) parseBlock(block giftBlock) {
+	// Category headers are normally in their own block but may directly precede a question
+	for strings.HasPrefix(strings.TrimSpace(block.text), giftCategoryHeader) {
+		headerEnd := strings.Index(block.text, "\n")
+		header := block.text
+		if headerEnd != -1 {
+			header = block.text[:headerEnd]
+		}
+		p.category = giftCategory(strings.TrimPrefix(strings.TrimSpace(header), giftCategoryHeader))
+		if headerEnd == -1 {
+			return
+		}
+		block = giftBlock{block.text[headerEnd+1:], block.startLine + 1}
+	}
+
+	question, err := parseGiftQuestion(block)
+	if err != nil {
+		p.errs = append(p.errs, ValidationError{Index: p.questions, Reason: err.reason, Line: err.line})
+	} else {
+		question.Category = p.category
+		p.qAndA = append(p.qAndA, question)
+		p.lines = append(p.lines, block.startLine)
+	}
+	p.questions++
+}
+
+// Error found while parsing a GIFT question
+type giftError struct {
+	line   int
+	reason string
+}
+
+func parseGiftQuestion(block giftBlock) (QuestionAndAnswer, *giftError) {
+	text := block.text
+	fail := func(offset int, format string, a ...interface{}) (QuestionAndAnswer, *giftError) {
+		return QuestionAndAnswer{}, &giftError{block.lineOf(offset), fmt.Sprintf(format, a...)}
+	}
+
+	// Skip the optional ::title::
+	cursor := 0
+	if trimmed := strings.TrimLeft(text, " \t\n"); strings.HasPrefix(trimmed, "::") {
+		titleStart := len(text) - len(trimmed) + 2
+		titleEnd := indexUnescaped(text, ":", titleStart)
+		for titleEnd != -1 && !strings.HasPrefix(text[titleEnd:], "::") {
+			titleEnd = indexUnescaped(text, ":", titleEnd+1)
+		}
+		if titleEnd == -1 {
+			return fail(titleStart, "unterminated question title")
+		}
+		cursor = titleEnd + 2
+	}
+
+	// Skip the optional text format e.g. [html]
+	if trimmed := strings.TrimLeft(text[cursor:], " \t\n"); strings.HasPrefix(trimmed, "[") {
+		if formatEnd := strings.Index(trimmed, "]"); formatEnd != -1 {
+			cursor = len(text) - len(trimmed) + formatEnd + 1
+		}
+	}
+
+	open := indexUnescaped(text, "{", cursor)
+	if open == -1 {
+		return fail(cursor, "description items without an answer block are not supported")
+	}
+	close := indexUnescaped(text, "}", open+1)
+	if close == -1 {
+		return fail(open, "unterminated answer block")
+	}
+
+	question := unescapeGift(strings.TrimSpace(text[cursor:open]))
+	if suffix := unescapeGift(strings.TrimSpace(text[close+1:])); suffix != "" {
+		// Missing word question, the answer block is a gap in the text
+		question = strings.TrimSpace(question + " " + giftMissingWordPlaceholder + " " + suffix)
+	}
+
+	answerBlock := text[open+1 : close]
+	answerBlockWithoutFeedback := answerBlock
+	if feedback := indexUnescaped(answerBlock, "#", 0); feedback != -1 {
+		answerBlockWithoutFeedback = answerBlock[:feedback]
+	}
+	answerOffset := open + 1 + (len(answerBlock) - len(strings.TrimLeft(answerBlock, " \t\n")))
+
+	switch trimmed := strings.TrimSpace(answerBlock); {
+	case trimmed == "":
+		return fail(open, "essay questions are not supported")
+	case strings.HasPrefix(trimmed, "#"):
+		return fail(answerOffset, "numeric questions are not supported")
+	}
+
+	switch strings.ToUpper(strings.TrimSpace(answerBlockWithoutFeedback)) {
+	case "T", "TRUE":
+		return QuestionAndAnswer{Question: question, Type: TypeTrueFalse, Options: []string{"True", "False"}, Answers: []int{0}}, nil
+	case "F", "FALSE":
+		return QuestionAndAnswer{Question: question, Type: TypeTrueFalse, Options: []string{"True", "False"}, Answers: []int{1}}, nil
+	}
+
+	// Multiple choice - split into answers at each unescaped '=' or '~'
+	markers := []int{}
+	for i := indexUnescaped(answerBlock, "=~", 0); i != -1; i = indexUnescaped(answerBlock, "=~", i+1) {
+		markers = append(markers, i)
+	}
+	if len(markers) == 0 || strings.TrimSpace(answerBlock[:markers[0]]) != "" {
+		return fail(answerOffset, "answers must start with '=' or '~'")
+	}
+
+	qAndA := QuestionAndAnswer{Question: question, Options: []string{}, Answers: []int{}}
+	hasIncorrectMarker := false
+	for i, marker := range markers {
+		end := len(answerBlock)
+		if i+1 < len(markers) {
+			end = markers[i+1]
+		}
+		answer := answerBlock[marker+1 : end]
+		if feedback := indexUnescaped(answer, "#", 0); feedback != -1 {
+			answer = answer[:feedback]
+		}
+		answer = strings.TrimSpace(answer)
+		markerOffset := open + 1 + marker
+
+		if strings.Contains(answer, "->") {
+			return fail(markerOffset, "matching questions are not supported")
+		}
+
+		correct := answerBlock[marker] == '='
+		hasIncorrectMarker = hasIncorrectMarker || !correct
+		if strings.HasPrefix(answer, "%") {
+			weightEnd := strings.Index(answer[1:], "%")
+			if weightEnd == -1 {
+				return fail(markerOffset, "unterminated answer weight")
+			}
+			weight, err := strconv.ParseFloat(answer[1:weightEnd+1], 64)
+			if err != nil {
+				return fail(markerOffset, "invalid answer weight '%s'", answer[1:weightEnd+1])
+			}
+			correct = weight > 0
+			answer = strings.TrimSpace(answer[weightEnd+2:])
+		}
+
+		if correct {
+			qAndA.Answers = append(qAndA.Answers, len(qAndA.Options))
+		}
+		qAndA.Options = append(qAndA.Options, unescapeGift(answer))
+	}
+
+	if !hasIncorrectMarker {
+		return fail(answerOffset, "short answer questions are not supported")
+	}
+
+	return qAndA, nil
+}
+
+// Pulls the category out of the $CATEGORY header. Moodle categories are paths
+// e.g. "$course$/top/Science" so only the last part is used
+func giftCategory(header string) string {
+	parts := strings.Split(strings.TrimSpace(header), "/")
+	category := strings.TrimSpace(parts[len(parts)-1])
+	if category == "" {
+		return DefaultCategory
+	}
+	return category
+}
+
+// Returns the index of the first character of s at or after from that is one
+// of chars and isn't escaped with a backslash, else -1
+func indexUnescaped(s string, chars string, from int) int {
+	for i := from; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++ // Skip the escaped character
+			continue
+		}
+		if strings.IndexByte(chars, s[i]) != -1 {
+			return i
+		}
+	}
+	return -1
+}
+
+// Removes the GIFT escapes from s
+func unescapeGift(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			if next := s[i+1]; strings.IndexByte(giftEscapableChars, next) != -1 {
+				b.WriteByte(next)
+				i++
+				continue
+			} else if next == 'n' {
+				b.WriteByte('\n')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// Sets the line of each validation error from the line each question starts on.
+// Returns nil when there are no errors
+func annotateLines(errs ValidationErrors, lines []int) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	for i := range errs {
+		if 0 <= errs[i].Index && errs[i].Index < len(lines) {
+			errs[i].Line = lines[errs[i].Index]
+		}
+	}
+	return errs
+}
@@ -0,0 +1,106 @@
+package quiz
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseQuizFile_gift(t *testing.T) {
+	fileBytes := []byte(`// Exported from Moodle
+::Q1:: Which of these databases are relational? {
+	~DynamoDB
+	~Athena
+	=Redshift#Correct!
+	~Redis
+}
+
+$CATEGORY: $course$/top/Finance
+
+Which of these are fiat currencies? {
+	~%50%USD
+	~%-100%Bitcoin
+	~%50%AUD
+}
+
+[markdown]The sun rises in the east.{T}
+
+Grant is {~buried =entombed ~living} in Grant's tomb.
+
+Escaped \{braces\} and \= signs {=yes\~no ~no}
+`)
+
+	got, err := ParseQuizFile(&fileBytes, FormatGift)
+	if err != nil {
+		t.Fatalf("Failed to parse gift quiz file: %v", err)
+	}
+
+	want := QuestionAndAnswers{
+		{Question: "Which of these databases are relational?", Category: DefaultCategory, Options: []string{"DynamoDB", "Athena", "Redshift", "Redis"}, Answers: []int{2}},
+		{Question: "Which of these are fiat currencies?", Category: "Finance", Options: []string{"USD", "Bitcoin", "AUD"}, Answers: []int{0, 2}},
+		{Question: "The sun rises in the east.", Category: "Finance", Type: TypeTrueFalse, Options: []string{"True", "False"}, Answers: []int{0}},
+		{Question: "Grant is _____ in Grant's tomb.", Category: "Finance", Options: []string{"buried", "entombed", "living"}, Answers: []int{1}},
+		{Question: "Escaped {braces} and = signs", Category: "Finance", Options: []string{"yes~no", "no"}, Answers: []int{0}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Parsed gift doesn't match: %s", diff)
+	}
+}
+
+func TestParseQuizFile_gift_unsupported(t *testing.T) {
+	fileBytes := []byte(`Write an essay about Moodle. {}
+
+What is 2 + 2? {#4}
+
+Match these. {
+	=cat -> meow
+	=dog -> woof
+}
+
+Who's buried in Grant's tomb? {=Grant =Ulysses Grant}
+
+This is a description.
+
+What is 1 + 1? {~1 =2 ~3}
+`)
+
+	_, err := ParseQuizFile(&fileBytes, FormatGift)
+	validationErrors, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("Expected ValidationErrors, got: %v", err)
+	}
+
+	want := ValidationErrors{
+		{Index: 0, Reason: "essay questions are not supported", Line: 1},
+		{Index: 1, Reason: "numeric questions are not supported", Line: 3},
+		{Index: 2, Reason: "matching questions are not supported", Line: 6},
+		{Index: 3, Reason: "short answer questions are not supported", Line: 10},
+		{Index: 4, Reason: "description items without an answer block are not supported", Line: 12},
+	}
+	if diff := cmp.Diff(want, validationErrors); diff != "" {
+		t.Fatalf("Wrong validation errors: %s", diff)
+	}
+}
+
+func TestParseQuizFile_gift_validated_with_lines(t *testing.T) {
+	fileBytes := []byte(`What is 1 + 1? {~1 =2 ~3}
+
+What is 2 + 2? {~1 ~2 ~3}
+`)
+
+	got, err := ParseQuizFile(&fileBytes, FormatGift)
+	validationErrors, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("Expected ValidationErrors, got: %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("Expected both questions to be returned with the errors but got %d", len(got))
+	}
+
+	want := ValidationErrors{
+		{Index: 1, Field: "answers", Reason: "must have at least 1 answer", Line: 3},
+	}
+	if diff := cmp.Diff(want, validationErrors); diff != "" {
+		t.Fatalf("Wrong validation errors: %s", diff)
+	}
+}
@@ -0,0 +1,148 @@
+package quiz
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Returned when there's no library entry with the requested id
+var ErrLibraryEntryNotFound = errors.New("library entry not found")
+
+// Library ids are the file names without their extension. Restricting them to
+// these characters means they can't be used to reach outside the library directory
+var libraryIdPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Summary of a question set in the library
+type LibraryEntry struct {
+	Id				string		`json:"id"`
+	Name			string		`json:"name"`
+	Categories		[]string	`json:"categories"`
+	QuestionCount	int			`json:"questionCount"`
+}
+
+// Curated question sets that hosts can use instead of uploading their own. Each file
+// in Directory in a recognised format is an entry, identified by its file name
+// without the extension e.g. "example-1.json" is "example-1". An empty Directory
+// is an empty library
+type QuizLibrary struct {
+	Directory string
+}
+
+// Returns every valid entry sorted by id. Files that aren't valid question sets, or
+// have the same id as another file, are returned in skipped so they can be reported
+// without hiding the rest of the library
+func (l QuizLibrary) List() (entries []LibraryEntry, skipped map[string]error, err error) {
+	entries = []LibraryEntry{}
+	skipped = map[string]error{}
+	if l.Directory == "" {
+		return entries, skipped, nil
+	}
+
+	files, err := l.files()
+	if err != nil {
+		return nil, nil, err
+	}
+	for id, names := range files {
+		if err := collision(id, names); err != nil {
+			for _, name := range names {
+				skipped[name] = err
+			}
+			continue
+		}
+		qAndA, err := l.readFile(names[0])
+		if err != nil {
+			skipped[names[0]] = err
+			continue
+		}
+		entries = append(entries, LibraryEntry{
+			Id: id,
+			Name: libraryName(id),
+			Categories: qAndA.Categories(),
+			QuestionCount: len(qAndA),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Id < entries[j].Id })
+	return entries, skipped, nil
+}
+
+// Returns the question set of the entry with id, or ErrLibraryEntryNotFound
+func (l QuizLibrary) Get(id string) (QuestionAndAnswers, error) {
+	if l.Directory == "" || !libraryIdPattern.MatchString(id) {
+		return nil, ErrLibraryEntryNotFound
+	}
+
+	files, err := l.files()
+	if err != nil {
+		return nil, err
+	}
+	names, ok := files[id]
+	if !ok {
+		return nil, ErrLibraryEntryNotFound
+	}
+	if err := collision(id, names); err != nil {
+		return nil, err
+	}
+	return l.readFile(names[0])
+}
+
+// Returns the names of the question set files in the library by their id. More than
+// one file has the id when only their extensions differ e.g. "general.json" and
+// "general.yaml"
+func (l QuizLibrary) files() (map[string][]string, error) {
+	entries, err := os.ReadDir(l.Directory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read library directory '%s'. Error: %w", l.Directory, err)
+	}
+	files := map[string][]string{}
+	for _, entry := range entries {
+		if id, ok := libraryId(entry); ok {
+			files[id] = append(files[id], entry.Name())
+		}
+	}
+	return files, nil
+}
+
+// Rather than picking one of the files with the same id, none of them are used
+func collision(id string, names []string) error {
+	if len(names) < 2 {
+		return nil
+	}
+	return fmt.Errorf("the files %s all have the id '%s'. Rename all but one of them", strings.Join(names, ", "), id)
+}
+
+func (l QuizLibrary) readFile(name string) (QuestionAndAnswers, error) {
+	file, err := os.Open(filepath.Join(l.Directory, name))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseQuizReader(file, DetectFormat("", name))
+}
+
+// Returns the id of the library file, or false if it isn't a question set file
+func libraryId(file os.DirEntry) (string, bool) {
+	if file.IsDir() {
+		return "", false
+	}
+	extension := filepath.Ext(file.Name())
+	if _, ok := extensionFormats[strings.ToLower(extension)]; !ok {
+		return "", false
+	}
+	id := strings.TrimSuffix(file.Name(), extension)
+	return id, libraryIdPattern.MatchString(id)
+}
+
+// Makes a readable name from the id e.g. "world-geography" is "World geography"
+func libraryName(id string) string {
+	name := strings.TrimSpace(strings.NewReplacer("-", " ", "_", " ").Replace(id))
+	if name == "" {
+		return id
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
@@ -0,0 +1,119 @@
+package quiz
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeLibraryFile(t *testing.T, directory string, name string, content string) {
+	if err := os.WriteFile(filepath.Join(directory, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQuizLibrary(t *testing.T) {
+	directory := t.TempDir()
+	writeLibraryFile(t, directory, "world-geography.json", `[
+		{"question": "Capital of Australia?", "category": "geography", "options": ["Sydney", "Canberra"], "answers": [1]},
+		{"question": "Longest river?", "category": "geography", "options": ["Nile", "Thames"], "answers": [0]},
+		{"question": "Currency of Japan?", "category": "finance", "options": ["Yen", "Won"], "answers": [0]}
+	]`)
+	writeLibraryFile(t, directory, "databases.yaml", `
+- question: Which of these databases are relational?
+  category: technology
+  options: [DynamoDB, Redshift]
+  answers: [1]
+`)
+	writeLibraryFile(t, directory, "broken.json", `[{"question": ""}]`)
+	writeLibraryFile(t, directory, "README.md", "Not a question set")
+	if err := os.Mkdir(filepath.Join(directory, "drafts.json"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	library := QuizLibrary{Directory: directory}
+
+	entries, skipped, err := library.List()
+	if err != nil {
+		t.Fatalf("Failed to list library: %v", err)
+	}
+	want := []LibraryEntry{
+		{Id: "databases", Name: "Databases", Categories: []string{"technology"}, QuestionCount: 1},
+		{Id: "world-geography", Name: "World geography", Categories: []string{"finance", "geography"}, QuestionCount: 3},
+	}
+	if diff := cmp.Diff(want, entries); diff != "" {
+		t.Errorf("Wrong entries: %s", diff)
+	}
+	if _, ok := skipped["broken.json"]; !ok || len(skipped) != 1 {
+		t.Errorf("Expected only 'broken.json' to be skipped but got %v", skipped)
+	}
+
+	qAndA, err := library.Get("databases")
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if diff := cmp.Diff(QuestionAndAnswers{{
+		Question: "Which of these databases are relational?",
+		Category: "technology",
+		Options: []string{"DynamoDB", "Redshift"},
+		Answers: []int{1},
+	}}, qAndA); diff != "" {
+		t.Errorf("Wrong question set: %s", diff)
+	}
+
+	for _, id := range []string{"missing", "README", "../databases", ""} {
+		if _, err := library.Get(id); err != ErrLibraryEntryNotFound {
+			t.Errorf("Expected ErrLibraryEntryNotFound for '%s' but got %v", id, err)
+		}
+	}
+	if _, err := library.Get("broken"); err == nil {
+		t.Errorf("Expected invalid entry to fail")
+	}
+}
+
+// Files with the same name in different formats would have the same id
+func TestQuizLibrary_id_collision(t *testing.T) {
+	directory := t.TempDir()
+	writeLibraryFile(t, directory, "general.json", `[{"question": "q1", "category": "c", "options": ["a", "b"], "answers": [0]}]`)
+	writeLibraryFile(t, directory, "general.yaml", `
+- question: q2
+  category: c
+  options: [a, b]
+  answers: [1]
+`)
+	writeLibraryFile(t, directory, "databases.json", `[{"question": "q3", "category": "c", "options": ["a", "b"], "answers": [0]}]`)
+	library := QuizLibrary{Directory: directory}
+
+	entries, skipped, err := library.List()
+	if err != nil {
+		t.Fatalf("Failed to list library: %v", err)
+	}
+	want := []LibraryEntry{{Id: "databases", Name: "Databases", Categories: []string{"c"}, QuestionCount: 1}}
+	if diff := cmp.Diff(want, entries); diff != "" {
+		t.Errorf("Wrong entries: %s", diff)
+	}
+	wantErr := "the files general.json, general.yaml all have the id 'general'. Rename all but one of them"
+	for _, name := range []string{"general.json", "general.yaml"} {
+		if err, ok := skipped[name]; !ok || err.Error() != wantErr {
+			t.Errorf("Expected '%s' to be skipped with '%s' but got %v", name, wantErr, err)
+		}
+	}
+	if len(skipped) != 2 {
+		t.Errorf("Expected only the colliding files to be skipped but got %v", skipped)
+	}
+
+	if _, err := library.Get("general"); err == nil || err.Error() != wantErr {
+		t.Errorf("Expected the collision to be reported but got %v", err)
+	}
+}
+
+func TestQuizLibrary_empty_directory(t *testing.T) {
+	entries, _, err := QuizLibrary{}.List()
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Expected no entries but got %v, %v", entries, err)
+	}
+	if _, err := (QuizLibrary{}).Get("example-1"); err != ErrLibraryEntryNotFound {
+		t.Errorf("Expected ErrLibraryEntryNotFound but got %v", err)
+	}
+}
@@ -1,23 +1,243 @@
 package quiz
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Kinds of question. A question without a type is single-select when it has one
+// answer and multi-select otherwise
+const (
+	TypeSingleSelect	= "single-select"
+	TypeMultiSelect		= "multi-select"
+	TypeTrueFalse		= "true-false"
+)
+
+// Points scored for a correct answer to a question that doesn't set them
+const DefaultPoints = 1
+
+// Upper bounds on a question's points and time limit
+const (
+	MaxPoints			= 1000
+	MaxTimeLimitSeconds	= 3600
 )
 
-type QuestionAndAnswers []struct {
-	Question	string		`json:"question"`
-	Category	string		`json:"category"`
-	Options		[]string	`json:"options"`
-	Answers		[]int		`json:"answers"`
+type QuestionAndAnswer struct {
+	Question			string		`json:"question" yaml:"question"`
+	Category			string		`json:"category" yaml:"category"`
+	Options				[]string	`json:"options" yaml:"options"`
+	Answers				[]int		`json:"answers" yaml:"answers"`
+	// The fields below are optional so that existing question sets are still valid
+	Type				string		`json:"type,omitempty" yaml:"type,omitempty"`
+	Points				int			`json:"points,omitempty" yaml:"points,omitempty"`						// DefaultPoints when 0
+	TimeLimitSeconds	int			`json:"timeLimitSeconds,omitempty" yaml:"timeLimitSeconds,omitempty"`	// The quiz's question duration when 0
+	Explanation			string		`json:"explanation,omitempty" yaml:"explanation,omitempty"`				// Shown after the question is answered
+}
+
+// Returns the type of the question, working it out from the answers when it isn't set
+func (q QuestionAndAnswer) QuestionType() string {
+	switch {
+	case q.Type != "":
+		return q.Type
+	case len(q.Answers) > 1:
+		return TypeMultiSelect
+	default:
+		return TypeSingleSelect
+	}
+}
+
+// Returns the points scored for a correct answer
+func (q QuestionAndAnswer) QuestionPoints() int {
+	if q.Points == 0 {
+		return DefaultPoints
+	}
+	return q.Points
 }
 
+type QuestionAndAnswers []QuestionAndAnswer
+
+// Deserializes and validates the JSON quiz file. If the file deserializes but fails
+// validation then the returned error is a ValidationErrors listing every problem
 func QuizFileFromBytes(fileBytes *[]byte) (qAndA QuestionAndAnswers, err error) {
+	return ParseQuizFile(fileBytes, FormatJson)
+}
+
+// Deserializes the quiz file in the provided format and validates it. If the file
+// deserializes but fails validation then the returned error is a ValidationErrors
+// listing every problem
+func ParseQuizFile(fileBytes *[]byte, format Format) (qAndA QuestionAndAnswers, err error) {
+	return ParseQuizReader(bytes.NewReader(*fileBytes), format)
+}
+
+// Deserializes the quiz file read from r in the provided format and validates it.
+// JSON is decoded a question at a time as it's read, the other formats are read
+// in full before being parsed. Errors returned by r are wrapped so they can be
+// checked with errors.Is. Categories are normalised before validation. When the
+// file deserializes but fails validation, or a GIFT, Aiken or CSV file has questions
+// that can't be read, the questions that could be are returned along with the
+// ValidationErrors
+func ParseQuizReader(r io.Reader, format Format) (qAndA QuestionAndAnswers, err error) {
+
+	if format == FormatJson {
+		qAndA, err = quizFileFromJsonStream(r)
+	} else {
+		var fileBytes []byte
+		if fileBytes, err = ioutil.ReadAll(r); err != nil {
+			return nil, fmt.Errorf("failed to read %s quiz file: Error: %w", format, err)
+		}
+
+		switch format {
+		case FormatYaml:
+			err = yaml.Unmarshal(fileBytes, &qAndA)
+		case FormatCsv:
+			qAndA, err = quizFileFromCsv(fileBytes)
+		case FormatGift:
+			qAndA, err = quizFileFromGift(fileBytes)
+		case FormatAiken:
+			qAndA, err = quizFileFromAiken(fileBytes)
+		case FormatText:
+			if looksLikeAiken(fileBytes) {
+				qAndA, err = quizFileFromAiken(fileBytes)
+			} else {
+				qAndA, err = quizFileFromGift(fileBytes)
+			}
+		default:
+			err = fmt.Errorf("unsupported format '%s'", format)
+		}
+	}
+	if err != nil {
+		if _, ok := err.(ValidationErrors); ok {
+			// The question set is returned too so that it can still be summarised
+			qAndA.normaliseCategories()
+			return qAndA, err
+		}
+		return nil, fmt.Errorf("failed to deserialize %s quiz file: Error: %w", format, err)
+	}
+
+	qAndA.normaliseCategories()
+	if validationErrors := qAndA.Validate(); len(validationErrors) > 0 {
+		// The question set is returned too so that it can still be summarised
+		return qAndA, validationErrors
+	}
 
-	if err := json.Unmarshal(*fileBytes, &qAndA); err != nil {
-		return nil, fmt.Errorf("failed to deserialize quiz file: Error: %s", err.Error())
+	// Successfully deserialized and validated the file - this means its valid
+	return qAndA, nil
+}
+
+// Decodes a JSON quiz file one question at a time so that the raw file is never
+// held in memory. The file is either a QuizFile or, if it was written before the
+// schema was versioned, a bare list of questions. Questions are upgraded to
+// SchemaVersion as they're read
+func quizFileFromJsonStream(r io.Reader) (qAndA QuestionAndAnswers, err error) {
+	decoder := json.NewDecoder(r)
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch delim, _ := token.(json.Delim); {
+	case token == nil:
+		return nil, nil // null, same as json.Unmarshal
+	case delim == '[':
+		qAndA, err = decodeQuestionList(decoder, 1)
+	case delim == '{':
+		qAndA, err = decodeQuizFileObject(decoder)
+	default:
+		return nil, fmt.Errorf("expected a quiz file or a list of questions but got '%v'", token)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	// Successfully deserialized the file - this means its valid
+	if _, err := decoder.Token(); err != io.EOF {
+		if err == nil {
+			err = fmt.Errorf("unexpected data after the quiz file")
+		}
+		return nil, err
+	}
+
+	return qAndA, nil
+}
+
+// Decodes the rest of a QuizFile after its opening '{'
+func decodeQuizFileObject(decoder *json.Decoder) (qAndA QuestionAndAnswers, err error) {
+	version := 0
+	// Questions that came before the schemaVersion so couldn't be decoded yet
+	var pending []json.RawMessage
+
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "schemaVersion":
+			if err := decoder.Decode(&version); err != nil {
+				return nil, fmt.Errorf("schemaVersion: %w", err)
+			}
+			if err := checkSchemaVersion(version); err != nil {
+				return nil, err
+			}
+		case "questions":
+			if version == 0 {
+				if err := decoder.Decode(&pending); err != nil {
+					return nil, fmt.Errorf("questions: %w", err)
+				}
+				continue
+			}
+			if token, err := decoder.Token(); err != nil {
+				return nil, err
+			} else if delim, ok := token.(json.Delim); !ok || delim != '[' {
+				return nil, fmt.Errorf("expected a list of questions but got '%v'", token)
+			}
+			if qAndA, err = decodeQuestionList(decoder, version); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unexpected key '%v' in quiz file", key)
+		}
+	}
+	// Closing '}'
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	if version == 0 {
+		return nil, fmt.Errorf("missing schemaVersion")
+	}
+	for _, raw := range pending {
+		question, err := decodeQuestion(raw, version)
+		if err != nil {
+			return nil, fmt.Errorf("question %d: %w", len(qAndA), err)
+		}
+		qAndA = append(qAndA, question)
+	}
+	return qAndA, nil
+}
+
+// Decodes the rest of a list of questions written with the schema version after its opening '['
+func decodeQuestionList(decoder *json.Decoder, version int) (QuestionAndAnswers, error) {
+	qAndA := QuestionAndAnswers{}
+	for decoder.More() {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("question %d: %w", len(qAndA), err)
+		}
+		question, err := decodeQuestion(raw, version)
+		if err != nil {
+			return nil, fmt.Errorf("question %d: %w", len(qAndA), err)
+		}
+		qAndA = append(qAndA, question)
+	}
+
+	// Closing ']'
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
 	return qAndA, nil
 }
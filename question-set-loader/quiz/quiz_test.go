@@ -2,7 +2,10 @@ package quiz
 
 import (
 	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
+	"testing/iotest"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -36,3 +39,133 @@ func TestQuizFileFromBytes(t *testing.T) {
 		t.Fatalf("Deserialized bytes don't match: %s", diff)
 	}
 }
+
+func TestQuizFileFromBytes_invalid_json(t *testing.T) {
+	fileBytes := []byte("not json")
+	if _, err := QuizFileFromBytes(&fileBytes); err == nil {
+		t.Fatalf("Failed to detect error")
+	}
+}
+
+func TestQuizFileFromBytes_validation_errors(t *testing.T) {
+	qAndA := QuestionAndAnswers{
+		{
+			Question: " ",
+			Category: "food",
+			Options: []string {"a", "b", "a", ""},
+			Answers: []int{1, 4, 1},
+		},
+		{
+			Question: "question 2",
+			Category: "",
+			Options: []string {"i"},
+			Answers: []int{},
+		},
+	}
+
+	qAndABytes, err := json.Marshal(qAndA)
+	if err != nil {
+		t.Fatalf("Failed to serialize QuestionAndAnswer: %v", err)
+	}
+
+	_, err = QuizFileFromBytes(&qAndABytes)
+	validationErrors, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("Expected ValidationErrors, got: %v", err)
+	}
+
+	want := ValidationErrors{
+		{Index: 0, Field: "question", Reason: "must be non-empty"},
+		{Index: 0, Field: "options[2]", Reason: "duplicates options[0] 'a'"},
+		{Index: 0, Field: "options[3]", Reason: "must be non-empty"},
+		{Index: 0, Field: "answers[1]", Reason: "option index 4 is out of range for 4 options"},
+		{Index: 0, Field: "answers[2]", Reason: "duplicates answers[0] option index 1"},
+		{Index: 1, Field: "category", Reason: "must be non-empty"},
+		{Index: 1, Field: "options", Reason: "must have at least 2 options, got 1"},
+		{Index: 1, Field: "answers", Reason: "must have at least 1 answer"},
+	}
+	if diff := cmp.Diff(want, validationErrors); diff != "" {
+		t.Fatalf("Wrong validation errors: %s", diff)
+	}
+}
+
+func TestValidate_question_types_points_and_time_limits(t *testing.T) {
+	qAndA := QuestionAndAnswers{
+		{Question: "q1", Category: "c", Type: TypeSingleSelect, Options: []string{"a", "b"}, Answers: []int{0, 1}},
+		{Question: "q2", Category: "c", Type: TypeTrueFalse, Options: []string{"True", "False", "Maybe"}, Answers: []int{0}},
+		{Question: "q3", Category: "c", Type: "essay", Options: []string{"a", "b"}, Answers: []int{0}},
+		{Question: "q4", Category: "c", Options: []string{"a", "b"}, Answers: []int{0}, Points: -1, TimeLimitSeconds: MaxTimeLimitSeconds + 1},
+		{Question: "q5", Category: "c", Type: TypeMultiSelect, Options: []string{"a", "b"}, Answers: []int{0, 1}, Points: MaxPoints, TimeLimitSeconds: 30, Explanation: "Both"},
+	}
+
+	want := ValidationErrors{
+		{Index: 0, Field: "answers", Reason: "must have exactly 1 answer for a single-select question, got 2"},
+		{Index: 1, Field: "options", Reason: "must have exactly 2 options for a true-false question, got 3"},
+		{Index: 2, Field: "type", Reason: "must be one of 'single-select', 'multi-select' or 'true-false', got 'essay'"},
+		{Index: 3, Field: "points", Reason: "must be between 0 and 1000, got -1"},
+		{Index: 3, Field: "timeLimitSeconds", Reason: "must be between 0 and 3600, got 3601"},
+	}
+	if diff := cmp.Diff(want, qAndA.Validate()); diff != "" {
+		t.Fatalf("Wrong validation errors: %s", diff)
+	}
+}
+
+func TestQuestionAndAnswer_defaults(t *testing.T) {
+	tests := map[string]struct {
+		question	QuestionAndAnswer
+		wantType	string
+		wantPoints	int
+	}{
+		"one answer": {QuestionAndAnswer{Answers: []int{0}}, TypeSingleSelect, DefaultPoints},
+		"many answers": {QuestionAndAnswer{Answers: []int{0, 1}}, TypeMultiSelect, DefaultPoints},
+		"explicit": {QuestionAndAnswer{Type: TypeTrueFalse, Answers: []int{0}, Points: 5}, TypeTrueFalse, 5},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := test.question.QuestionType(); got != test.wantType {
+				t.Errorf("Expected type '%s' but got '%s'", test.wantType, got)
+			}
+			if got := test.question.QuestionPoints(); got != test.wantPoints {
+				t.Errorf("Expected %d points but got %d", test.wantPoints, got)
+			}
+		})
+	}
+}
+
+func TestValidate_empty_question_set(t *testing.T) {
+	got := QuestionAndAnswers{}.Validate()
+	want := ValidationErrors{{Index: QuestionSetIndex, Field: "", Reason: "must contain at least 1 question"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Wrong validation errors: %s", diff)
+	}
+}
+
+func TestParseQuizReader_json_stream_errors(t *testing.T) {
+	testCases := []struct {
+		name string
+		file string
+	}{
+		{"not_a_list", `{"question": "q"}`},
+		{"trailing_data", `[] []`},
+		{"unterminated_list", `[{"question": "q", "category": "c", "options": ["a", "b"], "answers": [0]}`},
+		{"invalid_question", `[{"question": 1}]`},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, err := ParseQuizReader(strings.NewReader(testCase.file), FormatJson); err == nil {
+				t.Fatalf("Failed to detect error")
+			}
+		})
+	}
+}
+
+func TestParseQuizReader_read_error(t *testing.T) {
+	readErr := errors.New("read failed")
+	for _, format := range []Format{FormatJson, FormatCsv} {
+		_, err := ParseQuizReader(iotest.ErrReader(readErr), format)
+		if !errors.Is(err, readErr) {
+			t.Errorf("Expected %s parse to wrap the read error, got: %v", format, err)
+		}
+	}
+}
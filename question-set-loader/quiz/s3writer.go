@@ -0,0 +1,255 @@
+package quiz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// Interface over the S3 client for injecting mocks
+type s3ObjectApi interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+}
+
+// How long a call to S3 can take before it's abandoned, so that a slow or unreachable
+// store fails the request rather than holding it open
+const requestTimeout = 10 * time.Second
+
+// How long the readiness check waits for S3 before reporting it as unreachable
+const readinessTimeout = 5 * time.Second
+
+type S3Options struct {
+	Endpoint			string	// Empty for AWS, else the URL of an S3 compatible store e.g. MinIO
+	Region				string
+	Bucket				string
+	Prefix				string	// Key prefix the question sets are written under
+	AccessKeyID			string	// Empty to use the default credential chain e.g. the lambda execution role
+	SecretAccessKey		string
+}
+
+// Stores question sets as JSON objects in an S3 compatible object store
+type QuizS3Writer struct {
+	client s3ObjectApi
+	bucket string
+	prefix string
+}
+
+// Builds an S3 client from the options. When an endpoint is provided path style
+// addressing is used since that's what most S3 compatible stores expect
+func NewS3Client(ctx context.Context, o S3Options) (*s3.Client, error) {
+	loadOptions := []func(*awsConfig.LoadOptions) error{}
+	if o.Region != "" {
+		loadOptions = append(loadOptions, awsConfig.WithRegion(o.Region))
+	}
+	if o.AccessKeyID != "" {
+		loadOptions = append(loadOptions, awsConfig.WithCredentialsProvider(credentials.StaticCredentialsProvider{
+			Value: aws.Credentials{
+				AccessKeyID: o.AccessKeyID,
+				SecretAccessKey: o.SecretAccessKey,
+			},
+		}))
+	}
+	if o.Endpoint != "" {
+		loadOptions = append(loadOptions, awsConfig.WithEndpointResolverWithOptions(aws.EndpointResolverWithOptionsFunc(
+			func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+				return aws.Endpoint{URL: o.Endpoint, HostnameImmutable: true}, nil
+			},
+		)))
+	}
+
+	cfg, err := awsConfig.LoadDefaultConfig(ctx, loadOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config. Error: %w", err)
+	}
+
+	return s3.NewFromConfig(cfg, func(options *s3.Options) {
+		options.UsePathStyle = o.Endpoint != ""
+	}), nil
+}
+
+func NewQuizS3Writer(ctx context.Context, o S3Options) (QuizS3Writer, error) {
+	if o.Bucket == "" {
+		return QuizS3Writer{}, fmt.Errorf("bucket must be non-empty")
+	}
+	client, err := NewS3Client(ctx, o)
+	if err != nil {
+		return QuizS3Writer{}, err
+	}
+	return QuizS3Writer{client: client, bucket: o.Bucket, prefix: o.Prefix}, nil
+}
+
+// Returns the key of the object that the question set for quizId is written to
+func (qw QuizS3Writer) key(quizId string) string {
+	return path.Join(qw.prefix, quizId + ".json")
+}
+
+func (qw QuizS3Writer) Write(quizId string, qAndA *QuestionAndAnswers) error {
+	return qw.put(quizId, qAndA)
+}
+
+// Puts the question set with the options e.g. a condition
+func (qw QuizS3Writer) put(quizId string, qAndA *QuestionAndAnswers, optFns ...func(*s3.Options)) error {
+	data, err := encodeQuestionSet(qAndA)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	_, err = qw.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(qw.bucket),
+		Key: aws.String(qw.key(quizId)),
+		Body: bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	}, optFns...)
+	if len(optFns) > 0 && isConditionFailure(err) {
+		return ErrVersionMismatch
+	}
+	if err != nil {
+		return fmt.Errorf("failed to put object '%s' in bucket '%s'. Error: %w", qw.key(quizId), qw.bucket, err)
+	}
+
+	return nil
+}
+
+func (qw QuizS3Writer) Read(quizId string) (QuestionAndAnswers, error) {
+	qAndA, _, err := qw.ReadVersion(quizId)
+	return qAndA, err
+}
+
+// The version of an object is its ETag
+func (qw QuizS3Writer) ReadVersion(quizId string) (QuestionAndAnswers, string, error) {
+	// The body is read within the timeout too
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	output, err := qw.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(qw.bucket),
+		Key: aws.String(qw.key(quizId)),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, "", ErrQuizNotFound
+		}
+		return nil, "", fmt.Errorf("failed to get object '%s' from bucket '%s'. Error: %w", qw.key(quizId), qw.bucket, err)
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	qAndA, err := decodeQuestionSet(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return qAndA, aws.ToString(output.ETag), nil
+}
+
+// Puts the object with 'If-Match', or 'If-None-Match: *' to only create it, so that S3
+// checks the version as it writes
+func (qw QuizS3Writer) WriteIfVersion(quizId string, qAndA *QuestionAndAnswers, version string) error {
+	if version == "" {
+		return qw.put(quizId, qAndA, withHeader("If-None-Match", "*"))
+	}
+	return qw.put(quizId, qAndA, withHeader("If-Match", version))
+}
+
+// Deletes the object with 'If-Match' so that S3 checks the version as it deletes
+func (qw QuizS3Writer) DeleteIfVersion(quizId string, version string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	_, err := qw.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(qw.bucket),
+		Key: aws.String(qw.key(quizId)),
+	}, withHeader("If-Match", version))
+	if isConditionFailure(err) {
+		return ErrVersionMismatch
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete object '%s' from bucket '%s'. Error: %w", qw.key(quizId), qw.bucket, err)
+	}
+	return nil
+}
+
+// Sets a header on the request. Used for the conditional request headers, which this
+// version of the SDK doesn't have fields for
+func withHeader(name string, value string) func(*s3.Options) {
+	return s3.WithAPIOptions(smithyhttp.SetHeaderValue(name, value))
+}
+
+// Whether S3 rejected a conditional request. It's '412 Precondition Failed' when the
+// condition doesn't hold, '409 Conflict' when another conditional request for the
+// object is in progress, and '404 Not Found' when there's no object to match
+func isConditionFailure(err error) bool {
+	var apiError smithy.APIError
+	if errors.As(err, &apiError) && apiError.ErrorCode() == "NoSuchBucket" {
+		return false
+	}
+	var responseError interface{ HTTPStatusCode() int }
+	if !errors.As(err, &responseError) {
+		return false
+	}
+	switch responseError.HTTPStatusCode() {
+	case http.StatusPreconditionFailed, http.StatusConflict, http.StatusNotFound:
+		return true
+	}
+	return false
+}
+
+func (qw QuizS3Writer) Delete(quizId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	// Deleting an absent object succeeds in S3 so check that it's there first
+	_, err := qw.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(qw.bucket),
+		Key: aws.String(qw.key(quizId)),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return ErrQuizNotFound
+		}
+		return fmt.Errorf("failed to head object '%s' in bucket '%s'. Error: %w", qw.key(quizId), qw.bucket, err)
+	}
+
+	_, err = qw.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(qw.bucket),
+		Key: aws.String(qw.key(quizId)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete object '%s' from bucket '%s'. Error: %w", qw.key(quizId), qw.bucket, err)
+	}
+	return nil
+}
+
+// Heads the bucket. Checks that it's reachable and the credentials are accepted for it
+// without writing on every probe
+func (qw QuizS3Writer) CheckWritable() error {
+	ctx, cancel := context.WithTimeout(context.Background(), readinessTimeout)
+	defer cancel()
+	_, err := qw.client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(qw.bucket),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to head bucket '%s'. Error: %w", qw.bucket, err)
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package quiz
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+)
+
+// In memory S3 keyed by "<bucket>/<key>"
+type mockS3Client struct {
+	objects map[string][]byte
+	err     error
+}
+
+func newMockS3Client() *mockS3Client {
+	return &mockS3Client{objects: make(map[string][]byte)}
+}
+
+// ETag S3 gives an object put in one part
+func mockETag(object []byte) string {
+	return fmt.Sprintf(`"%x"`, md5.Sum(object))
+}
+
+// Returns the headers the options add to the request
+func requestHeaders(t *testing.T, optFns []func(*s3.Options)) http.Header {
+	options := s3.Options{}
+	for _, fn := range optFns {
+		fn(&options)
+	}
+	stack := middleware.NewStack("mock", smithyhttp.NewStackRequest)
+	for _, fn := range options.APIOptions {
+		if err := fn(stack); err != nil {
+			t.Fatalf("Failed to apply API option: %v", err)
+		}
+	}
+	var headers http.Header
+	handler := middleware.HandlerFunc(func(ctx context.Context, input interface{}) (interface{}, middleware.Metadata, error) {
+		headers = input.(*smithyhttp.Request).Header
+		return nil, middleware.Metadata{}, nil
+	})
+	if _, _, err := middleware.DecorateHandler(handler, stack).Handle(context.Background(), nil); err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+	return headers
+}
+
+// Error S3 responds with when a conditional request's condition doesn't hold
+func preconditionFailed() error {
+	return &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusPreconditionFailed}},
+		Err: errors.New("PreconditionFailed"),
+	}
+}
+
+// Checks the conditional request headers against the object, which is nil when there isn't one
+func checkCondition(headers http.Header, object []byte) error {
+	if ifMatch := headers.Get("If-Match"); ifMatch != "" && (object == nil || ifMatch != mockETag(object)) {
+		return preconditionFailed()
+	}
+	if headers.Get("If-None-Match") == "*" && object != nil {
+		return preconditionFailed()
+	}
+	return nil
+}
+
+// Conditional requests only work with the middleware stack, which tests build with requestHeaders
+type conditionalMockS3Client struct {
+	*mockS3Client
+	t *testing.T
+}
+
+func (m conditionalMockS3Client) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	object := m.objects[*params.Bucket+"/"+*params.Key]
+	if err := checkCondition(requestHeaders(m.t, optFns), object); err != nil {
+		return nil, err
+	}
+	return m.mockS3Client.PutObject(ctx, params)
+}
+
+func (m conditionalMockS3Client) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	object, ok := m.objects[*params.Bucket+"/"+*params.Key]
+	if !ok {
+		return nil, &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusNotFound}},
+			Err: errors.New("NoSuchKey"),
+		}
+	}
+	if err := checkCondition(requestHeaders(m.t, optFns), object); err != nil {
+		return nil, err
+	}
+	return m.mockS3Client.DeleteObject(ctx, params)
+}
+
+// Every call to S3 must be abandoned if it takes too long
+func checkDeadline(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok {
+		return errors.New("call to S3 without a deadline")
+	}
+	return nil
+}
+
+func (m *mockS3Client) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if err := checkDeadline(ctx); err != nil {
+		return nil, err
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.objects[*params.Bucket+"/"+*params.Key], _ = io.ReadAll(params.Body)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (m *mockS3Client) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if err := checkDeadline(ctx); err != nil {
+		return nil, err
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	object, ok := m.objects[*params.Bucket+"/"+*params.Key]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(object)), ETag: aws.String(mockETag(object))}, nil
+}
+
+func (m *mockS3Client) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	if err := checkDeadline(ctx); err != nil {
+		return nil, err
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	if _, ok := m.objects[*params.Bucket+"/"+*params.Key]; !ok {
+		return nil, &types.NotFound{}
+	}
+	return &s3.HeadObjectOutput{}, nil
+}
+
+func (m *mockS3Client) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	if err := checkDeadline(ctx); err != nil {
+		return nil, err
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	delete(m.objects, *params.Bucket+"/"+*params.Key)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (m *mockS3Client) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	if err := checkDeadline(ctx); err != nil {
+		return nil, err
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func TestQuizS3Writer_Write(t *testing.T) {
+	qAndA := QuestionAndAnswers{
+		{
+			Question: "question 1",
+			Category: "food",
+			Options:  []string{"a", "b", "c", "d"},
+			Answers:  []int{1, 2},
+		},
+	}
+
+	client := newMockS3Client()
+	writer := QuizS3Writer{client: client, bucket: "question-sets", prefix: "uploads/"}
+
+	if err := writer.Write("quizid1", &qAndA); err != nil {
+		t.Fatalf("Failed to write question set: %v", err)
+	}
+
+	object, ok := client.objects["question-sets/uploads/quizid1.json"]
+	if !ok {
+		t.Fatalf("Question set wasn't written to the expected key. Objects: %v", client.objects)
+	}
+	var written QuizFile
+	if err := json.Unmarshal(object, &written); err != nil {
+		t.Fatalf("Failed to deserialize written object: %v", err)
+	}
+	if diff := cmp.Diff(QuizFile{SchemaVersion: SchemaVersion, Questions: qAndA}, written); diff != "" {
+		t.Errorf("Wrong question set written: %s", diff)
+	}
+}
+
+func TestQuizS3Writer_Write_error(t *testing.T) {
+	client := newMockS3Client()
+	client.err = fmt.Errorf("access denied")
+	writer := QuizS3Writer{client: client, bucket: "question-sets"}
+
+	if err := writer.Write("quizid1", &QuestionAndAnswers{}); err == nil {
+		t.Fatalf("Failed to detect error")
+	}
+}
+
+func TestQuizS3Writer_Read_and_Delete(t *testing.T) {
+	qAndA := QuestionAndAnswers{
+		{
+			Question: "question 1",
+			Category: "food",
+			Options:  []string{"a", "b"},
+			Answers:  []int{1},
+		},
+	}
+
+	writer := QuizS3Writer{client: newMockS3Client(), bucket: "question-sets"}
+
+	if _, err := writer.Read("quizid1"); err != ErrQuizNotFound {
+		t.Fatalf("Expected ErrQuizNotFound reading absent question set, got: %v", err)
+	}
+	if err := writer.Delete("quizid1"); err != ErrQuizNotFound {
+		t.Fatalf("Expected ErrQuizNotFound deleting absent question set, got: %v", err)
+	}
+
+	if err := writer.Write("quizid1", &qAndA); err != nil {
+		t.Fatalf("Failed to write question set: %v", err)
+	}
+	got, err := writer.Read("quizid1")
+	if err != nil {
+		t.Fatalf("Failed to read question set: %v", err)
+	}
+	if diff := cmp.Diff(qAndA, got); diff != "" {
+		t.Errorf("Wrong question set read: %s", diff)
+	}
+
+	if err := writer.Delete("quizid1"); err != nil {
+		t.Fatalf("Failed to delete question set: %v", err)
+	}
+	if _, err := writer.Read("quizid1"); err != ErrQuizNotFound {
+		t.Fatalf("Expected ErrQuizNotFound after delete, got: %v", err)
+	}
+}
+
+func TestQuizS3Writer_CheckWritable(t *testing.T) {
+	client := newMockS3Client()
+	writer := QuizS3Writer{client: client, bucket: "question-sets", prefix: "uploads/"}
+
+	if err := writer.CheckWritable(); err != nil {
+		t.Fatalf("Expected the bucket to be writable, got: %v", err)
+	}
+	if len(client.objects) != 0 {
+		t.Errorf("Expected the check not to write but got objects: %v", client.objects)
+	}
+
+	client.err = fmt.Errorf("access denied")
+	if err := writer.CheckWritable(); err == nil {
+		t.Errorf("Failed to detect error")
+	}
+}
+
+func TestQuizS3Writer_conditional(t *testing.T) {
+	original := QuestionAndAnswers{{Question: "question 1", Category: "food", Options: []string{"a", "b"}, Answers: []int{1}}}
+	replacement := QuestionAndAnswers{{Question: "question 2", Category: "tech", Options: []string{"i", "ii"}, Answers: []int{0}}}
+
+	client := newMockS3Client()
+	writer := QuizS3Writer{client: conditionalMockS3Client{client, t}, bucket: "question-sets"}
+
+	if err := writer.WriteIfVersion("quizid1", &original, ""); err != nil {
+		t.Fatalf("Failed to create question set: %v", err)
+	}
+	if err := writer.WriteIfVersion("quizid1", &original, ""); err != ErrVersionMismatch {
+		t.Fatalf("Expected ErrVersionMismatch creating a question set that exists, got: %v", err)
+	}
+
+	_, version, err := writer.ReadVersion("quizid1")
+	if err != nil {
+		t.Fatalf("Failed to read question set: %v", err)
+	}
+	if err := writer.WriteIfVersion("quizid1", &replacement, version); err != nil {
+		t.Fatalf("Failed to replace question set: %v", err)
+	}
+	// The version read before the replacement is stale
+	if err := writer.WriteIfVersion("quizid1", &original, version); err != ErrVersionMismatch {
+		t.Fatalf("Expected ErrVersionMismatch replacing a changed question set, got: %v", err)
+	}
+	if err := writer.DeleteIfVersion("quizid1", version); err != ErrVersionMismatch {
+		t.Fatalf("Expected ErrVersionMismatch deleting a changed question set, got: %v", err)
+	}
+
+	got, version, err := writer.ReadVersion("quizid1")
+	if err != nil {
+		t.Fatalf("Failed to read question set: %v", err)
+	}
+	if diff := cmp.Diff(replacement, got); diff != "" {
+		t.Errorf("Wrong question set read: %s", diff)
+	}
+	if err := writer.DeleteIfVersion("quizid1", version); err != nil {
+		t.Fatalf("Failed to delete question set: %v", err)
+	}
+	if err := writer.DeleteIfVersion("quizid1", version); err != ErrVersionMismatch {
+		t.Fatalf("Expected ErrVersionMismatch deleting an absent question set, got: %v", err)
+	}
+}
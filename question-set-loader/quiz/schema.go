@@ -0,0 +1,87 @@
+package quiz
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Version of the quiz file schema written by this service. Files written before the
+// schema was versioned are a bare list of questions and are read as version 1
+const SchemaVersion = 1
+
+// Top level of a versioned quiz file
+type QuizFile struct {
+	SchemaVersion	int					`json:"schemaVersion"`
+	Questions		QuestionAndAnswers	`json:"questions"`
+}
+
+// Upgrades a question, in place, from the schema version it's keyed by to the next one.
+// Every version before SchemaVersion must have one. There are none yet as optional
+// fields can be added without a new version
+var migrations = map[int]func(question map[string]interface{}) error{}
+
+// Checks that files with this schema version can be read
+func checkSchemaVersion(version int) error {
+	if version < 1 || version > SchemaVersion {
+		return fmt.Errorf("unsupported schemaVersion %d, must be between 1 and %d", version, SchemaVersion)
+	}
+	return nil
+}
+
+// Deserializes a question written with the schema version, upgrading it to SchemaVersion
+func decodeQuestion(raw json.RawMessage, version int) (question QuestionAndAnswer, err error) {
+	return upgradeQuestion(raw, version, SchemaVersion)
+}
+
+// Deserializes a question written with the schema version, upgrading it to target
+func upgradeQuestion(raw json.RawMessage, version int, target int) (question QuestionAndAnswer, err error) {
+	if version < target {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return question, err
+		}
+		for v := version; v < target; v++ {
+			migrate, ok := migrations[v]
+			if !ok {
+				return question, fmt.Errorf("no migration from schemaVersion %d", v)
+			}
+			if err := migrate(fields); err != nil {
+				return question, fmt.Errorf("failed to migrate from schemaVersion %d: %w", v, err)
+			}
+		}
+		if raw, err = json.Marshal(fields); err != nil {
+			return question, err
+		}
+	}
+	err = json.Unmarshal(raw, &question)
+	return question, err
+}
+
+// Returns a copy of the question set with the defaults filled in and the answers in
+// order, so that question sets that mean the same thing are identical
+func (qAndA QuestionAndAnswers) normalised() QuestionAndAnswers {
+	normalised := make(QuestionAndAnswers, len(qAndA))
+	for i, question := range qAndA {
+		question.Type = question.QuestionType()
+		question.Points = question.QuestionPoints()
+		question.Answers = append([]int{}, question.Answers...)
+		sort.Ints(question.Answers)
+		normalised[i] = question
+	}
+	return normalised
+}
+
+// Returns the SHA-256 of the normalised question set in the current schema e.g.
+// "sha256:9f86d0...". Question sets with the same content have the same hash whatever
+// format they were uploaded in and whether or not the defaults were spelt out
+func (qAndA QuestionAndAnswers) ContentHash() (string, error) {
+	questions, err := json.Marshal(qAndA.normalised())
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(questions)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
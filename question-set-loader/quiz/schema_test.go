@@ -0,0 +1,202 @@
+package quiz
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseQuizReader_schema_versions(t *testing.T) {
+	want := QuestionAndAnswers{
+		{Question: "q1", Category: "c", Options: []string{"a", "b"}, Answers: []int{0}},
+		{Question: "q2", Category: "c", Options: []string{"a", "b"}, Answers: []int{0, 1}, Type: TypeMultiSelect},
+	}
+	questions := `[
+		{"question": "q1", "category": "c", "options": ["a", "b"], "answers": [0]},
+		{"question": "q2", "category": "c", "options": ["a", "b"], "answers": [0, 1], "type": "multi-select"}
+	]`
+
+	testCases := map[string]string{
+		"unversioned":              questions,
+		"v1":                       `{"schemaVersion": 1, "questions": ` + questions + `}`,
+		"questions before version": `{"questions": ` + questions + `, "schemaVersion": 1}`,
+	}
+	for name, file := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseQuizReader(strings.NewReader(file), FormatJson)
+			if err != nil {
+				t.Fatalf("Failed to parse quiz file: %v", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Fatalf("Parsed quiz file doesn't match: %s", diff)
+			}
+		})
+	}
+}
+
+func TestParseQuizReader_schema_errors(t *testing.T) {
+	testCases := map[string]string{
+		"missing version":    `{"questions": []}`,
+		"newer version":      `{"schemaVersion": 2, "questions": []}`,
+		"zero version":       `{"schemaVersion": 0, "questions": []}`,
+		"unexpected key":     `{"schemaVersion": 1, "questions": [], "title": "t"}`,
+		"questions not list": `{"schemaVersion": 1, "questions": {}}`,
+	}
+	for name, file := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseQuizReader(strings.NewReader(file), FormatJson); err == nil {
+				t.Fatalf("Failed to detect error")
+			}
+		})
+	}
+}
+
+// Every version before the current one can be upgraded
+func TestMigrations(t *testing.T) {
+	for version := 1; version < SchemaVersion; version++ {
+		if _, ok := migrations[version]; !ok {
+			t.Errorf("Missing migration from schemaVersion %d", version)
+		}
+	}
+}
+
+// Questions older than the target version pass through every migration after theirs
+func TestUpgradeQuestion_migration_chain(t *testing.T) {
+	defer func() { migrations = map[int]func(question map[string]interface{}) error{} }()
+	for _, version := range []int{1, 2} {
+		version := version
+		migrations[version] = func(question map[string]interface{}) error {
+			question["question"] = fmt.Sprintf("%s (v%d)", question["question"], version + 1)
+			return nil
+		}
+	}
+	raw := []byte(`{"question": "q1", "category": "c", "options": ["a", "b"], "answers": [0]}`)
+
+	got, err := upgradeQuestion(raw, 1, 3)
+	if err != nil {
+		t.Fatalf("Failed to decode question: %v", err)
+	}
+	if got.Question != "q1 (v2) (v3)" {
+		t.Errorf("Expected the question to be migrated twice but got '%s'", got.Question)
+	}
+
+	got, err = upgradeQuestion(raw, 3, 3)
+	if err != nil {
+		t.Fatalf("Failed to decode question: %v", err)
+	}
+	if got.Question != "q1" {
+		t.Errorf("Expected a current question not to be migrated but got '%s'", got.Question)
+	}
+
+	migrationErr := errors.New("can't migrate")
+	migrations[2] = func(question map[string]interface{}) error { return migrationErr }
+	if _, err := upgradeQuestion([]byte(`{}`), 1, 3); !errors.Is(err, migrationErr) {
+		t.Errorf("Expected the migration error but got: %v", err)
+	}
+
+	delete(migrations, 2)
+	_, err = upgradeQuestion(raw, 1, 3)
+	if err == nil || err.Error() != "no migration from schemaVersion 2" {
+		t.Errorf("Expected the missing migration to be reported but got: %v", err)
+	}
+}
+
+// Source of the schema version and its migrations in the schema.go at path, by name
+func schemaDefinitions(t *testing.T, path string) map[string]string {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse '%s': %v", path, err)
+	}
+	print := func(node interface{}) string {
+		var buf bytes.Buffer
+		if err := printer.Fprint(&buf, fset, node); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	definitions := map[string]string{}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				if spec, ok := spec.(*ast.ValueSpec); ok && (spec.Names[0].Name == "SchemaVersion" || spec.Names[0].Name == "migrations") {
+					definitions[spec.Names[0].Name] = print(spec)
+				}
+			}
+		case *ast.FuncDecl:
+			// The migrations and the function applying them
+			if strings.HasPrefix(decl.Name.Name, "migrate") || decl.Name.Name == "upgradeQuestion" {
+				definitions[decl.Name.Name] = print(decl)
+			}
+		}
+	}
+	return definitions
+}
+
+// The quiz-result-loader reads the files written here so must have the same schema
+// version and migrations
+func TestSchema_matches_quiz_result_loader(t *testing.T) {
+	const readerSchema = "../../quiz-result-loader/quiz/schema.go"
+	if _, err := os.Stat(readerSchema); err != nil {
+		t.Skipf("The quiz-result-loader isn't checked out alongside: %v", err)
+	}
+
+	want := schemaDefinitions(t, "schema.go")
+	if _, ok := want["SchemaVersion"]; !ok {
+		t.Fatalf("Found no SchemaVersion in schema.go")
+	}
+	if diff := cmp.Diff(want, schemaDefinitions(t, readerSchema)); diff != "" {
+		t.Errorf("The quiz-result-loader's schema has drifted. Change both together: %s", diff)
+	}
+}
+
+// The hash depends on the questions and not the format they were uploaded in
+func TestContentHash(t *testing.T) {
+	json := []byte(`[{"question": "q1", "category": "c", "options": ["a", "b"], "answers": [1]}]`)
+	csv := []byte("question,category,option,option,answers\nq1,c,a,b,1\n")
+	fromJson, _ := ParseQuizFile(&json, FormatJson)
+	fromCsv, _ := ParseQuizFile(&csv, FormatCsv)
+
+	jsonHash, err := fromJson.ContentHash()
+	if err != nil {
+		t.Fatalf("Failed to hash question set: %v", err)
+	}
+	csvHash, _ := fromCsv.ContentHash()
+	if jsonHash != csvHash {
+		t.Errorf("Expected the same hash for the same questions but got '%s' and '%s'", jsonHash, csvHash)
+	}
+	if !strings.HasPrefix(jsonHash, "sha256:") || len(jsonHash) != len("sha256:") + 64 {
+		t.Errorf("Expected a sha256 hash but got '%s'", jsonHash)
+	}
+
+	explicit := QuestionAndAnswers{{Question: "q1", Category: "c", Options: []string{"a", "b"}, Answers: []int{1},
+		Type: TypeSingleSelect, Points: DefaultPoints}}
+	if explicitHash, _ := explicit.ContentHash(); explicitHash != jsonHash {
+		t.Errorf("Expected the same hash when the defaults are spelt out but got '%s' and '%s'", explicitHash, jsonHash)
+	}
+	reordered := QuestionAndAnswers{{Question: "q", Options: []string{"a", "b", "c"}, Answers: []int{2, 0}}}
+	ordered := QuestionAndAnswers{{Question: "q", Options: []string{"a", "b", "c"}, Answers: []int{0, 2}}}
+	reorderedHash, _ := reordered.ContentHash()
+	if orderedHash, _ := ordered.ContentHash(); reorderedHash != orderedHash {
+		t.Errorf("Expected the same hash whatever order the answers are in")
+	}
+	if diff := cmp.Diff([]int{2, 0}, reordered[0].Answers); diff != "" {
+		t.Errorf("Hashing changed the question set: %s", diff)
+	}
+
+	fromCsv[0].Answers = []int{0}
+	if changedHash, _ := fromCsv.ContentHash(); changedHash == jsonHash {
+		t.Errorf("Expected a different hash when the questions change")
+	}
+}
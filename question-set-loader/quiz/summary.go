@@ -0,0 +1,49 @@
+package quiz
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Overview of a question set, for hosts checking a file before uploading it
+type Summary struct {
+	QuestionCount		int					`json:"questionCount"`
+	CategoryCounts		map[string]int		`json:"categoryCounts"`	// Number of questions in each category
+	MultiAnswerCount	int					`json:"multiAnswerCount"`	// Questions with more than one answer
+	// Things that are allowed but probably aren't intended, such as asking the same
+	// question twice. They're in the same shape as validation errors
+	Warnings			ValidationErrors	`json:"warnings"`
+}
+
+// Returns the summary of the question set. It doesn't need to be valid
+func (qAndA QuestionAndAnswers) Summarise() Summary {
+	summary := Summary{
+		QuestionCount: len(qAndA),
+		CategoryCounts: map[string]int{},
+		Warnings: ValidationErrors{},
+	}
+
+	firstAsked := map[string]int{}
+	for i, q := range qAndA {
+		summary.CategoryCounts[q.Category]++
+		if len(q.Answers) > 1 {
+			summary.MultiAnswerCount++
+		}
+
+		// Questions that only differ by case or spacing are the same question
+		question := strings.ToLower(strings.Join(strings.Fields(q.Question), " "))
+		if question == "" {
+			continue
+		}
+		if first, ok := firstAsked[question]; ok {
+			summary.Warnings = append(summary.Warnings, ValidationError{
+				Index: i,
+				Field: "question",
+				Reason: fmt.Sprintf("duplicates question %d '%s'", first, qAndA[first].Question),
+			})
+			continue
+		}
+		firstAsked[question] = i
+	}
+	return summary
+}
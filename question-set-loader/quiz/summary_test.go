@@ -0,0 +1,53 @@
+package quiz
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSummarise(t *testing.T) {
+	tests := map[string]struct {
+		qAndA	QuestionAndAnswers
+		want	Summary
+	}{
+		"empty": {
+			QuestionAndAnswers{},
+			Summary{QuestionCount: 0, CategoryCounts: map[string]int{}, Warnings: ValidationErrors{}},
+		},
+		"counts": {
+			QuestionAndAnswers{
+				{Question: "q1", Category: "tech", Options: []string{"a", "b"}, Answers: []int{0}},
+				{Question: "q2", Category: "food", Options: []string{"a", "b"}, Answers: []int{0, 1}},
+				{Question: "q3", Category: "tech", Options: []string{"a", "b", "c"}, Answers: []int{1, 2}},
+			},
+			Summary{QuestionCount: 3, CategoryCounts: map[string]int{"tech": 2, "food": 1}, MultiAnswerCount: 2, Warnings: ValidationErrors{}},
+		},
+		"duplicates": {
+			QuestionAndAnswers{
+				{Question: "What is 1 + 1?", Category: "maths", Options: []string{"1", "2"}, Answers: []int{1}},
+				{Question: "what is  1 + 1? ", Category: "maths", Options: []string{"2", "3"}, Answers: []int{0}},
+				{Question: "What is 2 + 2?", Category: "maths", Options: []string{"4", "5"}, Answers: []int{0}},
+				{Question: "WHAT IS 1 + 1?", Category: "maths", Options: []string{"1", "2"}, Answers: []int{1}},
+				{Question: "", Category: "maths", Options: []string{"1", "2"}, Answers: []int{1}},
+				{Question: "", Category: "maths", Options: []string{"1", "2"}, Answers: []int{1}},
+			},
+			Summary{
+				QuestionCount: 6,
+				CategoryCounts: map[string]int{"maths": 6},
+				Warnings: ValidationErrors{
+					{Index: 1, Field: "question", Reason: "duplicates question 0 'What is 1 + 1?'"},
+					{Index: 3, Field: "question", Reason: "duplicates question 0 'What is 1 + 1?'"},
+				},
+			},
+		},
+	}
+
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			if diff := cmp.Diff(test.want, test.qAndA.Summarise()); diff != "" {
+				t.Errorf("Wrong summary: %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package quiz
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Index used in a ValidationError for problems with the question set as a whole
+// rather than a particular question
+const QuestionSetIndex = -1
+
+// A single problem found while validating a question set
+type ValidationError struct {
+	Index  int    `json:"index"`          // Index of the question, or QuestionSetIndex
+	Field  string `json:"field"`          // Field of the question the problem is in e.g. "answers[1]"
+	Reason string `json:"reason"`         // Human readable description of the problem
+	Line   int    `json:"line,omitempty"` // Line in the uploaded file, for line based formats
+}
+
+func (e ValidationError) Error() string {
+	location := ""
+	if e.Line > 0 {
+		location = fmt.Sprintf("line %d: ", e.Line)
+	}
+	if e.Index == QuestionSetIndex {
+		return location + e.Reason
+	}
+	if e.Field == "" {
+		return fmt.Sprintf("%squestion %d: %s", location, e.Index, e.Reason)
+	}
+	return fmt.Sprintf("%squestion %d: %s %s", location, e.Index, e.Field, e.Reason)
+}
+
+// Every problem found while validating a question set
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	reasons := make([]string, 0, len(e))
+	for _, validationError := range e {
+		reasons = append(reasons, validationError.Error())
+	}
+	return fmt.Sprintf("question set has %d problem(s): %s", len(e), strings.Join(reasons, "; "))
+}
+
+// Checks the question set is usable by the rest of the system. All problems
+// are collected and returned rather than stopping at the first. Returns an empty
+// slice when the question set is valid
+func (qAndA QuestionAndAnswers) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	if len(qAndA) == 0 {
+		errs = append(errs, ValidationError{Index: QuestionSetIndex, Reason: "must contain at least 1 question"})
+	}
+
+	for i, q := range qAndA {
+		errs = append(errs, q.validate(i)...)
+	}
+
+	return errs
+}
+
+// Validates a single question that's at index in the question set
+func (q QuestionAndAnswer) validate(index int) ValidationErrors {
+	errs := ValidationErrors{}
+	add := func(field string, format string, a ...interface{}) {
+		errs = append(errs, ValidationError{Index: index, Field: field, Reason: fmt.Sprintf(format, a...)})
+	}
+
+	if strings.TrimSpace(q.Question) == "" {
+		add("question", "must be non-empty")
+	}
+	if strings.TrimSpace(q.Category) == "" {
+		add("category", "must be non-empty")
+	}
+
+	if len(q.Options) < 2 {
+		add("options", "must have at least 2 options, got %d", len(q.Options))
+	}
+	seenOptions := make(map[string]int)
+	for i, option := range q.Options {
+		field := fmt.Sprintf("options[%d]", i)
+		if strings.TrimSpace(option) == "" {
+			add(field, "must be non-empty")
+			continue
+		}
+		if first, ok := seenOptions[option]; ok {
+			add(field, "duplicates options[%d] '%s'", first, option)
+			continue
+		}
+		seenOptions[option] = i
+	}
+
+	if len(q.Answers) == 0 {
+		add("answers", "must have at least 1 answer")
+	}
+	seenAnswers := make(map[int]int)
+	for i, answer := range q.Answers {
+		field := fmt.Sprintf("answers[%d]", i)
+		if answer < 0 || answer >= len(q.Options) {
+			add(field, "option index %d is out of range for %d options", answer, len(q.Options))
+			continue
+		}
+		if first, ok := seenAnswers[answer]; ok {
+			add(field, "duplicates answers[%d] option index %d", first, answer)
+			continue
+		}
+		seenAnswers[answer] = i
+	}
+
+	switch q.Type {
+	case "", TypeMultiSelect:
+	case TypeSingleSelect:
+		if len(q.Answers) > 1 {
+			add("answers", "must have exactly 1 answer for a %s question, got %d", q.Type, len(q.Answers))
+		}
+	case TypeTrueFalse:
+		if len(q.Options) != 2 {
+			add("options", "must have exactly 2 options for a %s question, got %d", q.Type, len(q.Options))
+		}
+		if len(q.Answers) > 1 {
+			add("answers", "must have exactly 1 answer for a %s question, got %d", q.Type, len(q.Answers))
+		}
+	default:
+		add("type", "must be one of '%s', '%s' or '%s', got '%s'", TypeSingleSelect, TypeMultiSelect, TypeTrueFalse, q.Type)
+	}
+
+	if q.Points < 0 || q.Points > MaxPoints {
+		add("points", "must be between 0 and %d, got %d", MaxPoints, q.Points)
+	}
+	if q.TimeLimitSeconds < 0 || q.TimeLimitSeconds > MaxTimeLimitSeconds {
+		add("timeLimitSeconds", "must be between 0 and %d, got %d", MaxTimeLimitSeconds, q.TimeLimitSeconds)
+	}
+
+	return errs
+}
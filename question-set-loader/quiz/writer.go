@@ -2,40 +2,274 @@ package quiz
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 )
 
+// The backends that question sets can be written to
+const (
+	StoreFile = "file"
+	StoreS3 = "s3"
+)
+
+// Returned by a QuizStore when there's no question set stored for the quiz
+var ErrQuizNotFound = errors.New("question set not found")
+
+// Returned by a QuizStore's conditional changes when the stored question set isn't the
+// version that was read, because another request has changed it since
+var ErrVersionMismatch = errors.New("question set has changed")
+
 type QuizWriter interface {
 	Write(quizId string, qAndA *QuestionAndAnswers) error
 }
 
+// Stores question sets so that they can be read back, replaced and deleted.
+// Write atomically replaces any existing question set for the quiz. The conditional
+// changes check the version and make the change as one step, so that a change made
+// by another request after the question set was read isn't lost
+type QuizStore interface {
+	QuizWriter
+	Read(quizId string) (QuestionAndAnswers, error)
+	Delete(quizId string) error
+	// Returns the question set along with the store's version of it
+	ReadVersion(quizId string) (qAndA QuestionAndAnswers, version string, err error)
+	// Writes the question set if the stored one is still version, or if there isn't
+	// one stored when version is empty. Otherwise returns ErrVersionMismatch
+	WriteIfVersion(quizId string, qAndA *QuestionAndAnswers, version string) error
+	// Deletes the question set if it's still version. Otherwise returns ErrVersionMismatch
+	DeleteIfVersion(quizId string, version string) error
+}
+
+// Implemented by stores that can check whether question sets can be written to
+// them without writing one
+type WritableChecker interface {
+	CheckWritable() error
+}
+
 type QuizJsonFileWriter struct {
 	SaveDirectory string // Location to write files to
 }
 
+// Held while a file is changed so that conditional changes to it within this process
+// happen one at a time. Keyed by the file's path so that every QuizJsonFileWriter
+// for the same directory shares them
+var fileLocks = struct {
+	sync.Mutex
+	locks map[string]*fileLock
+}{locks: make(map[string]*fileLock)}
+
+type fileLock struct {
+	sync.Mutex
+	holders int // Waiting for or holding the lock. It's removed when there are none
+}
+
+// Locks the file at path and returns the function that unlocks it
+func lockFile(path string) (unlock func()) {
+	fileLocks.Lock()
+	lock, ok := fileLocks.locks[path]
+	if !ok {
+		lock = &fileLock{}
+		fileLocks.locks[path] = lock
+	}
+	lock.holders++
+	fileLocks.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		fileLocks.Lock()
+		if lock.holders--; lock.holders == 0 {
+			delete(fileLocks.locks, path)
+		}
+		fileLocks.Unlock()
+	}
+}
+
+// Returns the path of the file that the question set for quizId is written to
+func (qw QuizJsonFileWriter) path(quizId string) string {
+	return filepath.Join(qw.SaveDirectory, quizId + ".json")
+}
+
 func (qw QuizJsonFileWriter) Write(quizId string, qAndA *QuestionAndAnswers) error {
-	writePath := filepath.Join(qw.SaveDirectory, quizId + ".json")
+	writePath := qw.path(quizId)
+	defer lockFile(writePath)()
+
+	tmpPath, err := qw.writeTemp(quizId, qAndA)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath) // No-op once renamed
+
+	// Rename it over the top of any existing file so that readers never see a
+	// partially written question set
+	return os.Rename(tmpPath, writePath)
+}
+
+// Writes the question set to a temporary file next to where it's stored, with only
+// read permission for all users, and returns the temporary file's path
+func (qw QuizJsonFileWriter) writeTemp(quizId string, qAndA *QuestionAndAnswers) (string, error) {
+	writePath := qw.path(quizId)
 	// Create the parent directories if needed
 	if err := os.MkdirAll(filepath.Dir(writePath), os.ModePerm); err != nil {
-		return fmt.Errorf("failed to create quiz file parent directories. Error: %v", err)
+		return "", fmt.Errorf("failed to create quiz file parent directories. Error: %v", err)
+	}
+
+	data, err := encodeQuestionSet(qAndA)
+	if err != nil {
+		return "", err
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(writePath), "." + quizId + ".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary quiz file. Error: %v", err)
+	}
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	if err := os.Chmod(tmpFile.Name(), os.FileMode(int(0444))); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	return tmpFile.Name(), nil
+}
+
+func (qw QuizJsonFileWriter) Read(quizId string) (QuestionAndAnswers, error) {
+	qAndA, _, err := qw.ReadVersion(quizId)
+	return qAndA, err
+}
+
+func (qw QuizJsonFileWriter) Delete(quizId string) error {
+	deletePath := qw.path(quizId)
+	defer lockFile(deletePath)()
+
+	err := os.Remove(deletePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrQuizNotFound
 	}
+	return err
+}
+
+// The version of a file is the SHA-256 of its content
+func (qw QuizJsonFileWriter) ReadVersion(quizId string) (QuestionAndAnswers, string, error) {
+	data, version, err := qw.readFile(quizId)
+	if err != nil {
+		return nil, "", err
+	}
+	qAndA, err := decodeQuestionSet(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return qAndA, version, nil
+}
+
+// Returns the content of the stored file and its version
+func (qw QuizJsonFileWriter) readFile(quizId string) ([]byte, string, error) {
+	data, err := os.ReadFile(qw.path(quizId))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", ErrQuizNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(data)
+	return data, hex.EncodeToString(sum[:]), nil
+}
+
+// The file is locked while the version is checked and the file written so that other
+// changes in this process can't come in between. A new file is linked into place,
+// which fails if another process created one first
+func (qw QuizJsonFileWriter) WriteIfVersion(quizId string, qAndA *QuestionAndAnswers, version string) error {
+	writePath := qw.path(quizId)
+	defer lockFile(writePath)()
 
+	_, currentVersion, err := qw.readFile(quizId)
+	if err != nil && !errors.Is(err, ErrQuizNotFound) {
+		return err
+	}
+	if currentVersion != version {
+		return ErrVersionMismatch
+	}
+
+	tmpPath, err := qw.writeTemp(quizId, qAndA)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath) // Only the temporary name is removed once linked
+
+	if version != "" {
+		return os.Rename(tmpPath, writePath)
+	}
+	err = os.Link(tmpPath, writePath)
+	if errors.Is(err, os.ErrExist) {
+		return ErrVersionMismatch
+	}
+	return err
+}
+
+func (qw QuizJsonFileWriter) DeleteIfVersion(quizId string, version string) error {
+	deletePath := qw.path(quizId)
+	defer lockFile(deletePath)()
+
+	_, currentVersion, err := qw.readFile(quizId)
+	if errors.Is(err, ErrQuizNotFound) {
+		return ErrVersionMismatch
+	}
+	if err != nil {
+		return err
+	}
+	if currentVersion != version {
+		return ErrVersionMismatch
+	}
+	err = os.Remove(deletePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrVersionMismatch
+	}
+	return err
+}
+
+// Creates and removes a temporary file in the save directory
+func (qw QuizJsonFileWriter) CheckWritable() error {
+	if err := os.MkdirAll(qw.SaveDirectory, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create save directory '%s'. Error: %w", qw.SaveDirectory, err)
+	}
+	probe, err := os.CreateTemp(qw.SaveDirectory, ".writable.*.tmp")
+	if err != nil {
+		return fmt.Errorf("save directory '%s' isn't writable. Error: %w", qw.SaveDirectory, err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// Serializes the question set to the JSON QuizFile written by every QuizWriter
+func encodeQuestionSet(qAndA *QuestionAndAnswers) ([]byte, error) {
 	data := bytes.Buffer{}
 	enc := json.NewEncoder(&data)
 	enc.SetEscapeHTML(false)
 	enc.SetIndent("", "  ")
-	if err := enc.Encode(&qAndA); err != nil {
-		return err
+	if err := enc.Encode(QuizFile{SchemaVersion: SchemaVersion, Questions: *qAndA}); err != nil {
+		return nil, err
 	}
+	return data.Bytes(), nil
+}
 
-	// Write the file with only read permission for all users
-	if err := os.WriteFile(writePath, data.Bytes(), os.FileMode(int(0444))); err != nil {
-		return err
+// Deserializes a question set previously written by encodeQuestionSet, or by an older
+// version of it, upgrading it to the current schema
+func decodeQuestionSet(data []byte) (QuestionAndAnswers, error) {
+	qAndA, err := quizFileFromJsonStream(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize stored question set: Error: %s", err.Error())
 	}
-
-	// Success
-	return nil
+	return qAndA, nil
 }
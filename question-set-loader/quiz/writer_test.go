@@ -0,0 +1,183 @@
+package quiz
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestQuizJsonFileWriter(t *testing.T) {
+	original := QuestionAndAnswers{
+		{
+			Question: "question 1",
+			Category: "food",
+			Options:  []string{"a", "b", "c", "d"},
+			Answers:  []int{1, 2},
+		},
+	}
+	replacement := QuestionAndAnswers{
+		{
+			Question: "question 2",
+			Category: "tech",
+			Options:  []string{"i", "ii"},
+			Answers:  []int{0},
+		},
+	}
+
+	saveDirectory := t.TempDir()
+	writer := QuizJsonFileWriter{SaveDirectory: saveDirectory}
+
+	if _, err := writer.Read("quizid1"); err != ErrQuizNotFound {
+		t.Fatalf("Expected ErrQuizNotFound reading absent question set, got: %v", err)
+	}
+
+	// Write then replace the read-only file
+	for _, qAndA := range []QuestionAndAnswers{original, replacement} {
+		if err := writer.Write("quizid1", &qAndA); err != nil {
+			t.Fatalf("Failed to write question set: %v", err)
+		}
+		got, err := writer.Read("quizid1")
+		if err != nil {
+			t.Fatalf("Failed to read question set: %v", err)
+		}
+		if diff := cmp.Diff(qAndA, got); diff != "" {
+			t.Errorf("Wrong question set read: %s", diff)
+		}
+	}
+
+	info, err := os.Stat(filepath.Join(saveDirectory, "quizid1.json"))
+	if err != nil {
+		t.Fatalf("Failed to stat question set file: %v", err)
+	}
+	if diff := cmp.Diff(os.FileMode(0444), info.Mode().Perm()); diff != "" {
+		t.Errorf("Wrong file permissions: %s", diff)
+	}
+	entries, _ := os.ReadDir(saveDirectory)
+	if len(entries) != 1 {
+		t.Errorf("Expected only the question set file, got %d entries", len(entries))
+	}
+
+	if err := writer.Delete("quizid1"); err != nil {
+		t.Fatalf("Failed to delete question set: %v", err)
+	}
+	if err := writer.Delete("quizid1"); err != ErrQuizNotFound {
+		t.Fatalf("Expected ErrQuizNotFound deleting absent question set, got: %v", err)
+	}
+}
+
+func TestQuizJsonFileWriter_conditional(t *testing.T) {
+	original := QuestionAndAnswers{{Question: "question 1", Category: "food", Options: []string{"a", "b"}, Answers: []int{1}}}
+	replacement := QuestionAndAnswers{{Question: "question 2", Category: "tech", Options: []string{"i", "ii"}, Answers: []int{0}}}
+	writer := QuizJsonFileWriter{SaveDirectory: t.TempDir()}
+
+	if err := writer.WriteIfVersion("quizid1", &original, ""); err != nil {
+		t.Fatalf("Failed to create question set: %v", err)
+	}
+	if err := writer.WriteIfVersion("quizid1", &original, ""); err != ErrVersionMismatch {
+		t.Fatalf("Expected ErrVersionMismatch creating a question set that exists, got: %v", err)
+	}
+
+	_, version, err := writer.ReadVersion("quizid1")
+	if err != nil {
+		t.Fatalf("Failed to read question set: %v", err)
+	}
+	if err := writer.WriteIfVersion("quizid1", &replacement, version); err != nil {
+		t.Fatalf("Failed to replace question set: %v", err)
+	}
+	// The version read before the replacement is stale
+	if err := writer.WriteIfVersion("quizid1", &original, version); err != ErrVersionMismatch {
+		t.Fatalf("Expected ErrVersionMismatch replacing a changed question set, got: %v", err)
+	}
+	if err := writer.DeleteIfVersion("quizid1", version); err != ErrVersionMismatch {
+		t.Fatalf("Expected ErrVersionMismatch deleting a changed question set, got: %v", err)
+	}
+
+	got, version, err := writer.ReadVersion("quizid1")
+	if err != nil {
+		t.Fatalf("Failed to read question set: %v", err)
+	}
+	if diff := cmp.Diff(replacement, got); diff != "" {
+		t.Errorf("Wrong question set read: %s", diff)
+	}
+	if err := writer.DeleteIfVersion("quizid1", version); err != nil {
+		t.Fatalf("Failed to delete question set: %v", err)
+	}
+	if err := writer.DeleteIfVersion("quizid1", version); err != ErrVersionMismatch {
+		t.Fatalf("Expected ErrVersionMismatch deleting an absent question set, got: %v", err)
+	}
+	entries, _ := os.ReadDir(writer.SaveDirectory)
+	if len(entries) != 0 {
+		t.Errorf("Expected no files to be left, got %d entries", len(entries))
+	}
+}
+
+// Only one of the concurrent changes made from the same version succeeds
+func TestQuizJsonFileWriter_concurrent_conditional(t *testing.T) {
+	writer := QuizJsonFileWriter{SaveDirectory: t.TempDir()}
+	original := QuestionAndAnswers{{Question: "question 1", Category: "food", Options: []string{"a", "b"}, Answers: []int{1}}}
+	if err := writer.Write("quizid1", &original); err != nil {
+		t.Fatalf("Failed to write question set: %v", err)
+	}
+	_, version, err := writer.ReadVersion("quizid1")
+	if err != nil {
+		t.Fatalf("Failed to read question set: %v", err)
+	}
+
+	const writers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			qAndA := QuestionAndAnswers{{Question: fmt.Sprintf("question %d", i), Category: "tech", Options: []string{"i", "ii"}, Answers: []int{0}}}
+			errs <- writer.WriteIfVersion("quizid1", &qAndA, version)
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch err {
+		case nil:
+			succeeded++
+		case ErrVersionMismatch:
+		default:
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("Expected 1 write to succeed but %d did", succeeded)
+	}
+}
+
+func TestQuizJsonFileWriter_CheckWritable(t *testing.T) {
+	saveDirectory := filepath.Join(t.TempDir(), "questions")
+	writer := QuizJsonFileWriter{SaveDirectory: saveDirectory}
+
+	if err := writer.CheckWritable(); err != nil {
+		t.Fatalf("Expected the save directory to be writable, got: %v", err)
+	}
+	entries, err := os.ReadDir(saveDirectory)
+	if err != nil {
+		t.Fatalf("Save directory wasn't created: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Probe file was left in the save directory: %v", entries)
+	}
+
+	// A file in the way of the save directory
+	blocked := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(blocked, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	writer = QuizJsonFileWriter{SaveDirectory: blocked}
+	if err := writer.CheckWritable(); err == nil {
+		t.Errorf("Failed to detect unwritable save directory")
+	}
+}
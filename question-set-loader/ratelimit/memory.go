@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// How often full buckets are removed from a MemoryLimiter
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens		float64
+	updated		time.Time
+	limit		Limit
+}
+
+// Limiter holding the buckets in memory so limits only apply within this instance
+type MemoryLimiter struct {
+	now			func() time.Time
+
+	mu			sync.Mutex
+	buckets		map[string]*bucket
+	lastSweep	time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+func (m *MemoryLimiter) Allow(key string, limit Limit) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		m.buckets[key] = b
+	}
+	tokens, allowed, retryAfter := take(b.tokens, now.Sub(b.updated), limit)
+	b.tokens, b.updated, b.limit = tokens, now, limit
+	return allowed, retryAfter, nil
+}
+
+// Removes the buckets that have refilled since they were last used. They're
+// recreated full so forgetting them changes nothing
+func (m *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	for key, b := range m.buckets {
+		if now.Sub(b.updated) >= refillTime(b.limit) {
+			delete(m.buckets, key)
+		}
+	}
+	m.lastSweep = now
+}
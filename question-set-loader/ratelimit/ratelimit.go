@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+const StoreMemory = "memory"
+const StoreRedis = "redis"
+
+// Token bucket that holds up to Burst tokens and is refilled at Rate tokens per
+// second. Each request takes a token and is throttled when there are none left
+type Limit struct {
+	Rate	float64
+	Burst	int
+}
+
+// Returns the limit allowing requests per minute on average with bursts of up to burst.
+// A burst less than 1 is raised to 1 so that requests can be made at all
+func PerMinute(requests int, burst int) Limit {
+	if burst < 1 {
+		burst = 1
+	}
+	return Limit{Rate: float64(requests) / 60, Burst: burst}
+}
+
+// Whether the limit is applied. Limits with no rate aren't
+func (l Limit) Enabled() bool {
+	return l.Rate > 0
+}
+
+// Tracks a token bucket per key
+type Limiter interface {
+	// Takes a token from the bucket for key. When the bucket is empty allowed is false
+	// and retryAfter is how long until the next token is added
+	Allow(key string, limit Limit) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// Refills the bucket for the time elapsed since it was last updated then takes a token
+// if there is one
+func take(tokens float64, elapsed time.Duration, limit Limit) (remaining float64, allowed bool, retryAfter time.Duration) {
+	if elapsed > 0 {
+		tokens = math.Min(float64(limit.Burst), tokens + elapsed.Seconds() * limit.Rate)
+	}
+	if tokens >= 1 {
+		return tokens - 1, true, 0
+	}
+	wait := time.Duration(math.Ceil((1 - tokens) / limit.Rate * float64(time.Second)))
+	return tokens, false, wait
+}
+
+// How long an untouched bucket takes to refill completely, after which it can be forgotten
+func refillTime(limit Limit) time.Duration {
+	return time.Duration(math.Ceil(float64(limit.Burst) / limit.Rate * float64(time.Second)))
+}
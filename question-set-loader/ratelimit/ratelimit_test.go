@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// Runs the same requests against each Limiter
+func testLimiter(t *testing.T, limiter Limiter, clock *fakeClock) {
+	limit := PerMinute(60, 2) // 1 per second, bursts of 2
+
+	type step struct {
+		advance time.Duration
+		key string
+		wantAllowed bool
+		wantRetryAfter time.Duration
+	}
+	steps := []step{
+		{0, "quiz1", true, 0},
+		{0, "quiz1", true, 0},
+		{0, "quiz1", false, time.Second},
+		{0, "quiz2", true, 0}, // Other keys have their own bucket
+		{250 * time.Millisecond, "quiz1", false, 750 * time.Millisecond},
+		{750 * time.Millisecond, "quiz1", true, 0},
+		{0, "quiz1", false, time.Second},
+		{time.Hour, "quiz1", true, 0}, // Refills up to the burst only
+		{0, "quiz1", true, 0},
+		{0, "quiz1", false, time.Second},
+	}
+
+	for i, s := range steps {
+		clock.now = clock.now.Add(s.advance)
+		allowed, retryAfter, err := limiter.Allow(s.key, limit)
+		if err != nil {
+			t.Fatalf("Step %d: unexpected error: %v", i, err)
+		}
+		if allowed != s.wantAllowed || retryAfter != s.wantRetryAfter {
+			t.Errorf("Step %d: expected allowed %t retry after %s but got %t %s", i, s.wantAllowed, s.wantRetryAfter, allowed, retryAfter)
+		}
+	}
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	clock := &fakeClock{time.Unix(1643273706, 0)}
+	limiter := NewMemoryLimiter()
+	limiter.now = clock.Now
+
+	testLimiter(t, limiter, clock)
+}
+
+func TestMemoryLimiter_forgets_full_buckets(t *testing.T) {
+	clock := &fakeClock{time.Unix(1643273706, 0)}
+	limiter := NewMemoryLimiter()
+	limiter.now = clock.Now
+
+	limiter.Allow("quiz1", PerMinute(60, 2))
+	clock.now = clock.now.Add(sweepInterval)
+	limiter.Allow("quiz2", PerMinute(60, 2))
+
+	if _, ok := limiter.buckets["quiz1"]; ok {
+		t.Errorf("Expected the refilled bucket to be removed")
+	}
+	if _, ok := limiter.buckets["quiz2"]; !ok {
+		t.Errorf("Expected the bucket in use to be kept")
+	}
+}
+
+func TestRedisLimiter(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	clock := &fakeClock{time.Unix(1643273706, 0)}
+	limiter := NewRedisLimiter(RedisOptions{Addr: mr.Addr()})
+	limiter.now = clock.Now
+
+	testLimiter(t, limiter, clock)
+
+	if ttl := mr.TTL("ratelimit:quiz1"); ttl != 2 * time.Second {
+		t.Errorf("Expected the bucket to expire once refilled but TTL is %s", ttl)
+	}
+
+	mr.Close()
+	if _, _, err := limiter.Allow("quiz1", PerMinute(60, 2)); err == nil {
+		t.Errorf("Failed to detect error")
+	}
+}
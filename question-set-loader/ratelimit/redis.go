@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type RedisOptions struct {
+	Addr     string
+	Password string
+}
+
+// Refills and takes a token from the bucket in KEYS[1] atomically. ARGV is the rate
+// in tokens per second, the burst, the current time in milliseconds and the time
+// in milliseconds the bucket takes to refill. Returns {allowed, retry after ms}
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(bucket[1]) or burst
+local updated = tonumber(bucket[2]) or now
+if now > updated then
+	tokens = math.min(burst, tokens + (now - updated) * rate / 1000)
+end
+local allowed = 0
+local retryAfter = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retryAfter = math.ceil((1 - tokens) * 1000 / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', tostring(math.max(now, updated)))
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return {allowed, retryAfter}
+`)
+
+// Limiter holding the buckets in Redis so limits apply across every instance. Buckets
+// are stored under 'ratelimit:<key>' and expire once they would have refilled
+type RedisLimiter struct {
+	rdb *redis.Client
+	now func() time.Time
+}
+
+func NewRedisLimiter(o RedisOptions) *RedisLimiter {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     o.Addr,
+		Password: o.Password,
+	})
+
+	return &RedisLimiter{
+		rdb: rdb,
+		now: time.Now,
+	}
+}
+
+func (r *RedisLimiter) Allow(key string, limit Limit) (bool, time.Duration, error) {
+
+	// Give the check 1 second to complete
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	result, err := takeScript.Run(ctx, r.rdb, []string{"ratelimit:" + key},
+		limit.Rate, limit.Burst, r.now().UnixMilli(), refillTime(limit).Milliseconds(),
+	).Int64Slice()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to check rate limit for '%s': %w", key, err)
+	}
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond, nil
+}
@@ -0,0 +1,181 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/google/go-cmp/cmp"
+)
+
+const testArn = "arn:aws:secretsmanager:ap-southeast-2:123456789012:secret:mc-speedrun-AbCdEf"
+
+// Counts the lookups so caching can be checked
+type countingSource struct {
+	MapSource
+	lookups int
+}
+
+func (c *countingSource) Lookup(ctx context.Context, ref string) (string, error) {
+	c.lookups++
+	return c.MapSource.Lookup(ctx, ref)
+}
+
+func TestResolve(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "jwt_secret")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_SECRET", "from-env")
+	t.Setenv("TEST_EMPTY_SECRET", "")
+	resolver := NewResolver(MapSource{
+		testArn: `{"username": "admin", "password": "from-secrets-manager", "port": 5672}`,
+	})
+
+	tests := []struct {
+		name	string
+		value	string
+		want	string
+	}{
+		{"plain value", "plaintext", "plaintext"},
+		{"empty value", "", ""},
+		{"file", "file://" + secretFile, "from-file"},
+		{"env", "env://TEST_SECRET", "from-env"},
+		{"env set but empty", "env://TEST_EMPTY_SECRET", ""},
+		{"secrets manager", testArn, `{"username": "admin", "password": "from-secrets-manager", "port": 5672}`},
+		{"secrets manager key", testArn + "#password", "from-secrets-manager"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := resolver.Resolve(context.Background(), test.value)
+			if err != nil {
+				t.Fatalf("Failed to resolve: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Wrong secret: %s", diff)
+			}
+		})
+	}
+}
+
+func TestResolve_errors(t *testing.T) {
+	resolver := NewResolver(MapSource{testArn: `{"port": 5672}`})
+
+	tests := []struct {
+		name	string
+		value	string
+	}{
+		{"missing file", "file://" + filepath.Join(t.TempDir(), "missing")},
+		{"unset env var", "env://TEST_UNSET_SECRET"},
+		{"unknown arn", "arn:aws:secretsmanager:ap-southeast-2:123456789012:secret:other-AbCdEf"},
+		{"missing key", testArn + "#password"},
+		{"non string key", testArn + "#port"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := resolver.Resolve(context.Background(), test.value); err == nil {
+				t.Errorf("Expected an error resolving '%s'", test.value)
+			}
+		})
+	}
+}
+
+func TestResolve_without_secrets_manager(t *testing.T) {
+	if _, err := NewResolver(nil).Resolve(context.Background(), testArn); err == nil {
+		t.Errorf("Expected an error resolving an ARN without a secrets manager")
+	}
+}
+
+func TestResolve_caches(t *testing.T) {
+	source := &countingSource{MapSource: MapSource{testArn: `{"username": "admin", "password": "passwd"}`}}
+	resolver := NewResolver(source)
+
+	username, password := testArn + "#username", testArn + "#password"
+	if err := resolver.ResolveAll(context.Background(), &username, &password); err != nil {
+		t.Fatalf("Failed to resolve: %v", err)
+	}
+	if _, err := resolver.Resolve(context.Background(), testArn); err != nil {
+		t.Fatalf("Failed to resolve: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"admin", "passwd"}, []string{username, password}); diff != "" {
+		t.Errorf("Wrong secrets: %s", diff)
+	}
+	if source.lookups != 1 {
+		t.Errorf("Expected the secret to be looked up once but was looked up %d times", source.lookups)
+	}
+}
+
+type mockSecretsManagerClient struct {
+	secrets	map[string]string
+	region	string // Of the last request
+}
+
+func (m *mockSecretsManagerClient) GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	options := secretsmanager.Options{}
+	for _, optFn := range optFns {
+		optFn(&options)
+	}
+	m.region = options.Region
+	secret, ok := m.secrets[*params.SecretId]
+	if !ok {
+		return nil, errors.New("ResourceNotFoundException")
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: &secret}, nil
+}
+
+func TestSecretsManagerSource(t *testing.T) {
+	client := &mockSecretsManagerClient{secrets: map[string]string{testArn: "passwd"}}
+	source := &SecretsManagerSource{client: client}
+
+	got, err := source.Lookup(context.Background(), testArn)
+	if err != nil {
+		t.Fatalf("Failed to look up secret: %v", err)
+	}
+	if diff := cmp.Diff("passwd", got); diff != "" {
+		t.Errorf("Wrong secret: %s", diff)
+	}
+	if diff := cmp.Diff("ap-southeast-2", client.region); diff != "" {
+		t.Errorf("Wrong region: %s", diff)
+	}
+
+	if _, err := source.Lookup(context.Background(), testArn + "-missing"); err == nil {
+		t.Errorf("Expected an error looking up a missing secret")
+	}
+}
+
+// The quiz-result-loader keeps a copy of this package since each service is built
+// with only its own module. The copy is tested here so it must stay the same
+func TestSecret_matches_quiz_result_loader(t *testing.T) {
+	const readerSecret = "../../quiz-result-loader/secret"
+	if _, err := os.Stat(readerSecret); err != nil {
+		t.Skipf("The quiz-result-loader isn't checked out alongside: %v", err)
+	}
+
+	for _, name := range []string{"secret.go", "secretsmanager.go"} {
+		want, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join(readerSecret, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(want, got) {
+			t.Errorf("The quiz-result-loader's %s has drifted. Copy it from here", name)
+		}
+	}
+	copies, err := filepath.Glob(filepath.Join(readerSecret, "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(copies) != 2 {
+		t.Errorf("Expected only secret.go and secretsmanager.go in the quiz-result-loader's copy but got %v", copies)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
@@ -12,6 +13,7 @@ import (
 	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/load"
 	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/logfmt"
 	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/quiz"
+	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/secret"
 	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/subscribe"
 	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/worker"
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -47,12 +49,20 @@ func main() {
 	logger.SetOutput(os.Stdout)
 	logger.SetFormatter(logfmt.NewUtcLogFormatter())
 
-	config, err := config.Load("./config.ini")
+	configPath := flag.String("config", "", "Path to an .ini, .yaml or .toml config file. Without one, the config comes from environment variables alone")
+	flag.Parse()
+
+	config, err := config.Load(*configPath)
 	if err != nil {
 		logger.Panicf("Failed to load config: %s", err.Error())
 	}
 
-	logger.Infof("Loaded config: %+v\n", config)
+	logger.Infof("Loaded config: %s", config)
+
+	// Resolved after logging so that the references to secrets are shown rather than redacted
+	if err := config.ResolveSecrets(context.TODO(), secret.NewResolver(secret.NewSecretsManagerSource())); err != nil {
+		logger.Panicf("Failed to resolve secrets: %s", err.Error())
+	}
 
 	// Assemble the extractor and loader for the workers below
 	extractor := extract.NewRedisExtractor(extract.RedisExtractorOptions{
@@ -73,6 +83,23 @@ func main() {
 		logger.Panic("Failed to initialize DynamoDB loader. Error: " + err.Error())
 	}
 
+	// Question sets are either read from the filesystem or object storage
+	var quizUtil quiz.IQuiz = &quiz.QuizUtil{}
+	questionSetBasePath := config.QuestionSet.Path
+	if config.QuestionSet.Store == quiz.StoreS3 {
+		quizUtil, err = quiz.NewS3QuizUtil(context.TODO(), quiz.S3Options{
+			Endpoint: config.S3.Endpoint,
+			Region: config.S3.Region,
+			Bucket: config.S3.Bucket,
+			AccessKeyID: config.S3.AccessKeyID,
+			SecretAccessKey: config.S3.SecretAccessKey,
+		})
+		if err != nil {
+			logger.Panic("Failed to initialize S3 question set reader. Error: " + err.Error())
+		}
+		questionSetBasePath = config.S3.Prefix
+	}
+
 	// Stop the context (workers and everything) when ctrl-c is pressed
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
@@ -134,7 +161,7 @@ func main() {
 	}()
 
 	// Start the workers and block waiting for them to finish (when the ctx is cancelled)
-	worker.WorkerPool(ctx, logger, &quiz.QuizUtil{}, extractor, loader, config.QuestionSet.Path, quizCh, completeJobCh, 10)
+	worker.WorkerPool(ctx, logger, quizUtil, extractor, loader, questionSetBasePath, quizCh, completeJobCh, 10)
 
 	fmt.Println("Done")
 }
\ No newline at end of file
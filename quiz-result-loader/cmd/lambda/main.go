@@ -8,10 +8,12 @@ import (
 	"strconv"
 	"time"
 
+	appconfig "github.com/Ryangwaite/mc-speedrun/quiz-result-loader/config"
 	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/extract"
 	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/load"
 	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/logfmt"
 	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/quiz"
+	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/secret"
 	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/subscribe"
 	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/worker"
 	"github.com/aws/aws-lambda-go/events"
@@ -26,10 +28,25 @@ type lambdaConfig struct {
 		port int
 	}
 	questionSet struct {
+		store string
 		path string
 	}
+	s3 quiz.S3Options
 }
 
+// Formats the config with the S3 credentials redacted so that it can be logged.
+// References to secrets are left as they're safe to show
+func (c lambdaConfig) String() string {
+	type plain lambdaConfig // Without this method so that formatting doesn't recurse
+	c.s3.AccessKeyID = appconfig.Redact(c.s3.AccessKeyID)
+	c.s3.SecretAccessKey = appconfig.Redact(c.s3.SecretAccessKey)
+	return fmt.Sprintf("%+v", plain(c))
+}
+
+// Secrets resolved by a previous invocation are cached by the resolver so secrets
+// manager is only called on a cold start
+var secrets = secret.NewResolver(secret.NewSecretsManagerSource())
+
 // Loads the lambda config from the environment variables
 func loadLambdaConfig() (lambdaConfig, error) {
 	config := lambdaConfig{}
@@ -46,9 +63,30 @@ func loadLambdaConfig() (lambdaConfig, error) {
 		return config, fmt.Errorf("required env var '%s' was missing or invalid. Error: %w", redisHostKey, err)
 	}
 
-	questionSetPathKey := "QUESTION_SET_PATH"
-	if config.questionSet.path = os.Getenv(questionSetPathKey); config.questionSet.path == "" {
-		return config, fmt.Errorf("required env var '%s' was missing", questionSetPathKey)
+	questionSetStoreKey := "QUESTION_SET_STORE"
+	if config.questionSet.store = os.Getenv(questionSetStoreKey); config.questionSet.store == "" {
+		config.questionSet.store = quiz.StoreFile
+	}
+
+	switch config.questionSet.store {
+	case quiz.StoreFile:
+		questionSetPathKey := "QUESTION_SET_PATH"
+		if config.questionSet.path = os.Getenv(questionSetPathKey); config.questionSet.path == "" {
+			return config, fmt.Errorf("required env var '%s' was missing", questionSetPathKey)
+		}
+	case quiz.StoreS3:
+		s3BucketKey := "S3_BUCKET"
+		if config.s3.Bucket = os.Getenv(s3BucketKey); config.s3.Bucket == "" {
+			return config, fmt.Errorf("required env var '%s' was missing", s3BucketKey)
+		}
+		// Optional - the question set path is the key prefix and credentials come from the execution role when not set
+		config.questionSet.path = os.Getenv("S3_PREFIX")
+		config.s3.Endpoint = os.Getenv("S3_ENDPOINT")
+		config.s3.Region = os.Getenv("S3_REGION")
+		config.s3.AccessKeyID = os.Getenv("S3_ACCESS_KEY_ID")
+		config.s3.SecretAccessKey = os.Getenv("S3_SECRET_ACCESS_KEY")
+	default:
+		return config, fmt.Errorf("env var '%s' has unsupported value '%s'", questionSetStoreKey, config.questionSet.store)
 	}
 
 	return config, nil
@@ -89,7 +127,15 @@ func HandleRequest(ctx context.Context, event events.SQSEvent) ([]events.SQSBatc
 		return failEvents, err
 	}
 
-	logger.Infof("Loaded config: %+v", config)
+	logger.Infof("Loaded config: %s", config)
+
+	// The S3 credentials can be ARNs of secrets in secrets manager. Requires
+	// secretsmanager:GetSecretValue on the execution role when they are
+	if err := secrets.ResolveAll(ctx, &config.s3.AccessKeyID, &config.s3.SecretAccessKey); err != nil {
+		err := fmt.Errorf("failed to resolve secrets. Reason: %w", err)
+		logger.Error(err)
+		return failEvents, err
+	}
 	
 	// Build redis extractor
 	extractor := extract.NewRedisExtractor(extract.RedisExtractorOptions{
@@ -114,6 +160,16 @@ func HandleRequest(ctx context.Context, event events.SQSEvent) ([]events.SQSBatc
 		return failEvents, err
 	}
 
+	// Build question set reader
+	var quizUtil quiz.IQuiz = &quiz.QuizUtil{}
+	if config.questionSet.store == quiz.StoreS3 {
+		if quizUtil, err = quiz.NewS3QuizUtil(ctx, config.s3); err != nil {
+			err := fmt.Errorf("failed to initialize S3 question set reader. Reason: %w", err)
+			logger.Error(err)
+			return failEvents, err
+		}
+	}
+
 	newJobCh := make(chan string, 10)
 	quizIdMsgIdMapper := make(map[string]string)
 
@@ -178,7 +234,7 @@ func HandleRequest(ctx context.Context, event events.SQSEvent) ([]events.SQSBatc
 
 	// Start the workers and block waiting for them to finish (when the ctx is cancelled)
 	numWorkers := totalRecordsToProcess
-	worker.WorkerPool(workCompleteCtx, logger, &quiz.QuizUtil{}, extractor, loader, config.questionSet.path,
+	worker.WorkerPool(workCompleteCtx, logger, quizUtil, extractor, loader, config.questionSet.path,
 			newJobCh, completeJobCh, numWorkers)
 
 	logger.Info("Workers exited")
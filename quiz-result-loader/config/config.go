@@ -1,10 +1,15 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/quiz"
+	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/secret"
 	"github.com/spf13/viper"
 )
 
@@ -21,6 +26,7 @@ type Config struct {
 		Port int
 	}
 	QuestionSet struct {
+		Store string
 		Path string
 	}
 	DynamoDB struct {
@@ -29,96 +35,158 @@ type Config struct {
 		AccessKeyID			string
 		SecretAccessKey		string
 	}
+	S3 struct {
+		Endpoint			string
+		Region				string
+		Bucket				string
+		Prefix				string
+		AccessKeyID			string
+		SecretAccessKey		string
+	}
 }
 
-type missing string
-
-type missingConfigError struct {
-	key string
-}
-
-func (e *missingConfigError) Error() string {
-	return fmt.Sprintf("config item '%s' was not set", e.key)
+// Config file formats by the file's extension
+var formats = map[string]string{
+	".ini": "ini",
+	".yaml": "yaml",
+	".yml": "yaml",
+	".toml": "toml",
 }
 
-// Loads the config from the path specified and applys any overrides
-// from environment variables. Missing settings return an error
+// Loads the config from the file at path, if there is one, and applys any overrides
+// from environment variables. When path is empty the config comes from environment
+// variables alone. Every missing or invalid setting is returned in ValidationErrors
 func Load(path string) (Config, error) {
+	if path == "" {
+		return loadFromReader(nil, "")
+	}
+	format, ok := formats[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return Config{}, fmt.Errorf("config file '%s' must be .ini, .yaml, .yml or .toml", path)
+	}
 	file, err := os.Open(path)
 	if err != nil {
 		return Config{}, fmt.Errorf("failed to open config file '%s': %v", path, err)
 	}
-	return loadFromReader(file)
+	defer file.Close()
+	return loadFromReader(file, format)
 }
 
-func loadFromReader(reader io.Reader) (Config, error) {
-	viper.SetConfigType("ini")
+// Loads the config from reader in format, or from environment variables alone when
+// reader is nil
+func loadFromReader(reader io.Reader, format string) (Config, error) {
+	v := viper.New()
 
 	// Pair up env vars with the fields in config
-	viper.BindEnv("rabbit-mq.host", "RABBITMQ_HOST")
-	viper.BindEnv("rabbit-mq.port", "RABBITMQ_PORT")
-	viper.BindEnv("rabbit-mq.username", "RABBITMQ_USERNAME")
-	viper.BindEnv("rabbit-mq.password", "RABBITMQ_PASSWORD")
-	viper.BindEnv("rabbit-mq.queue_name", "RABBITMQ_QUEUE_NAME")
-	viper.BindEnv("redis.host", "REDIS_HOST")
-	viper.BindEnv("redis.port", "REDIS_PORT")
-	viper.BindEnv("question-set.path", "QUESTION_SET_PATH")
-	viper.BindEnv("dynamodb.region", "DYNAMODB_REGION")
-	viper.BindEnv("dynamodb.endpoint_url", "DYNAMODB_ENDPOINT_URL")
-	viper.BindEnv("dynamodb.access_key_id", "DYNAMODB_ACCESS_KEY_ID")
-	viper.BindEnv("dynamodb.secret_access_key", "DYNAMODB_SECRET_ACCESS_KEY")
+	v.BindEnv("rabbit-mq.host", "RABBITMQ_HOST")
+	v.BindEnv("rabbit-mq.port", "RABBITMQ_PORT")
+	v.BindEnv("rabbit-mq.username", "RABBITMQ_USERNAME")
+	v.BindEnv("rabbit-mq.password", "RABBITMQ_PASSWORD")
+	v.BindEnv("rabbit-mq.queue_name", "RABBITMQ_QUEUE_NAME")
+	v.BindEnv("redis.host", "REDIS_HOST")
+	v.BindEnv("redis.port", "REDIS_PORT")
+	v.BindEnv("question-set.store", "QUESTION_SET_STORE")
+	v.BindEnv("question-set.path", "QUESTION_SET_PATH")
+	v.BindEnv("dynamodb.region", "DYNAMODB_REGION")
+	v.BindEnv("dynamodb.endpoint_url", "DYNAMODB_ENDPOINT_URL")
+	v.BindEnv("dynamodb.access_key_id", "DYNAMODB_ACCESS_KEY_ID")
+	v.BindEnv("dynamodb.secret_access_key", "DYNAMODB_SECRET_ACCESS_KEY")
+	v.BindEnv("s3.endpoint", "S3_ENDPOINT")
+	v.BindEnv("s3.region", "S3_REGION")
+	v.BindEnv("s3.bucket", "S3_BUCKET")
+	v.BindEnv("s3.prefix", "S3_PREFIX")
+	v.BindEnv("s3.access_key_id", "S3_ACCESS_KEY_ID")
+	v.BindEnv("s3.secret_access_key", "S3_SECRET_ACCESS_KEY")
 
 	// Set all fields to be required
 	var missingFlag missing
-	viper.SetDefault("rabbit-mq.host", missingFlag)
-	viper.SetDefault("rabbit-mq.port", missingFlag)
-	viper.SetDefault("rabbit-mq.username", missingFlag)
-	viper.SetDefault("rabbit-mq.password", missingFlag)
-	viper.SetDefault("rabbit-mq.queue_name", missingFlag)
-	viper.SetDefault("redis.host", missingFlag)
-	viper.SetDefault("redis.port", missingFlag)
-	viper.SetDefault("question-set.path", missingFlag)
-	viper.SetDefault("dynamodb.region", missingFlag)
-	viper.SetDefault("dynamodb.endpoint_url", missingFlag)
-	viper.SetDefault("dynamodb.access_key_id", missingFlag)
-	viper.SetDefault("dynamodb.secret_access_key", missingFlag)
+	v.SetDefault("rabbit-mq.host", missingFlag)
+	v.SetDefault("rabbit-mq.port", missingFlag)
+	v.SetDefault("rabbit-mq.username", missingFlag)
+	v.SetDefault("rabbit-mq.password", missingFlag)
+	v.SetDefault("rabbit-mq.queue_name", missingFlag)
+	v.SetDefault("redis.host", missingFlag)
+	v.SetDefault("redis.port", missingFlag)
+	v.SetDefault("question-set.path", missingFlag)
+	v.SetDefault("dynamodb.region", missingFlag)
+	v.SetDefault("dynamodb.endpoint_url", missingFlag)
+	v.SetDefault("dynamodb.access_key_id", missingFlag)
+	v.SetDefault("dynamodb.secret_access_key", missingFlag)
+
+	// Optional fields
+	v.SetDefault("question-set.store", quiz.StoreFile)
+	v.SetDefault("s3.endpoint", "")
+	v.SetDefault("s3.region", "")
+	v.SetDefault("s3.bucket", "")
+	v.SetDefault("s3.prefix", "")
+	v.SetDefault("s3.access_key_id", "")
+	v.SetDefault("s3.secret_access_key", "")
 
 	loadedConfig := Config{}
-	
-	if err := viper.ReadConfig(reader); err != nil {
-		return loadedConfig, err
+
+	if reader != nil {
+		v.SetConfigType(format)
+		if err := v.ReadConfig(reader); err != nil {
+			return loadedConfig, fmt.Errorf("failed to read config: %w", err)
+		}
 	}
 
-	// Validate that there were no missing required keys
-	keys := []string {
-		"rabbit-mq.host","rabbit-mq.port","rabbit-mq.username",
-		"rabbit-mq.password","rabbit-mq.queue_name",
-		"redis.host", "redis.port",
-		"question-set.path",
-		"dynamodb.region", "dynamodb.endpoint_url",
-		"dynamodb.access_key_id", "dynamodb.secret_access_key",
+	// Pull out every setting, checking its type and range as it goes
+	r := &valueReader{v: v}
+	loadedConfig.RabbitMQ.Host = r.nonEmptyString("rabbit-mq.host")
+	loadedConfig.RabbitMQ.Port = r.port("rabbit-mq.port")
+	loadedConfig.RabbitMQ.Username = r.string("rabbit-mq.username")
+	loadedConfig.RabbitMQ.Password = r.string("rabbit-mq.password")
+	loadedConfig.RabbitMQ.QueueName = r.nonEmptyString("rabbit-mq.queue_name")
+	loadedConfig.Redis.Host = r.nonEmptyString("redis.host")
+	loadedConfig.Redis.Port = r.port("redis.port")
+	loadedConfig.QuestionSet.Store = r.oneOf("question-set.store", quiz.StoreFile, quiz.StoreS3)
+	// The question set path is only needed when reading from the filesystem
+	if loadedConfig.QuestionSet.Store == quiz.StoreFile {
+		loadedConfig.QuestionSet.Path = r.nonEmptyString("question-set.path")
+	} else if path, ok := v.Get("question-set.path").(string); ok {
+		loadedConfig.QuestionSet.Path = path
 	}
-	for _, key := range keys {
-		if _, ok := viper.Get(key).(missing); ok {
-			return loadedConfig, &missingConfigError{key}
-		}
+	loadedConfig.DynamoDB.Region = r.nonEmptyString("dynamodb.region")
+	loadedConfig.DynamoDB.EndpointUrl = r.url("dynamodb.endpoint_url")
+	loadedConfig.DynamoDB.AccessKeyID = r.string("dynamodb.access_key_id")
+	loadedConfig.DynamoDB.SecretAccessKey = r.string("dynamodb.secret_access_key")
+	loadedConfig.S3.Endpoint = r.string("s3.endpoint")
+	loadedConfig.S3.Region = r.string("s3.region")
+	if loadedConfig.QuestionSet.Store == quiz.StoreS3 {
+		loadedConfig.S3.Bucket = r.nonEmptyString("s3.bucket")
 	}
+	loadedConfig.S3.Prefix = r.string("s3.prefix")
+	loadedConfig.S3.AccessKeyID = r.string("s3.access_key_id")
+	loadedConfig.S3.SecretAccessKey = r.string("s3.secret_access_key")
 
-	// Everythings present and been validated - pull it out
-	loadedConfig.RabbitMQ.Host = viper.GetString("rabbit-mq.host")
-	loadedConfig.RabbitMQ.Port = viper.GetInt("rabbit-mq.port")
-	loadedConfig.RabbitMQ.Username = viper.GetString("rabbit-mq.username")
-	loadedConfig.RabbitMQ.Password = viper.GetString("rabbit-mq.password")
-	loadedConfig.RabbitMQ.QueueName = viper.GetString("rabbit-mq.queue_name")
-	loadedConfig.Redis.Host = viper.GetString("redis.host")
-	loadedConfig.Redis.Port = viper.GetInt("redis.port")
-	loadedConfig.QuestionSet.Path = viper.GetString("question-set.path")
-	loadedConfig.DynamoDB.Region = viper.GetString("dynamodb.region")
-	loadedConfig.DynamoDB.EndpointUrl = viper.GetString("dynamodb.endpoint_url")
-	loadedConfig.DynamoDB.AccessKeyID = viper.GetString("dynamodb.access_key_id")
-	loadedConfig.DynamoDB.SecretAccessKey = viper.GetString("dynamodb.secret_access_key")
-
-	// TODO: Validate these settings
-	
+	if len(r.errs) > 0 {
+		return Config{}, r.errs
+	}
 	return loadedConfig, nil
 }
+
+// Returns a copy of the config with the secrets hidden. References to secrets, such as
+// "file:///run/secrets/rabbitmq_password", are left as they're safe to show
+func (c Config) Redacted() Config {
+	c.RabbitMQ.Password = Redact(c.RabbitMQ.Password)
+	c.DynamoDB.SecretAccessKey = Redact(c.DynamoDB.SecretAccessKey)
+	c.S3.SecretAccessKey = Redact(c.S3.SecretAccessKey)
+	return c
+}
+
+// Formats the effective config with the secrets redacted so that it can be logged
+func (c Config) String() string {
+	type plain Config // Without this method so that formatting doesn't recurse
+	return fmt.Sprintf("%+v", plain(c.Redacted()))
+}
+
+// Replaces the credentials that reference a secret e.g. "file:///run/secrets/rabbitmq_password"
+// with the secret
+func (c *Config) ResolveSecrets(ctx context.Context, resolver *secret.Resolver) error {
+	return resolver.ResolveAll(ctx,
+		&c.RabbitMQ.Username, &c.RabbitMQ.Password,
+		&c.DynamoDB.AccessKeyID, &c.DynamoDB.SecretAccessKey,
+		&c.S3.AccessKeyID, &c.S3.SecretAccessKey,
+	)
+}
@@ -2,11 +2,17 @@ package config
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
+
+	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/quiz"
+	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/secret"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -247,7 +253,7 @@ func TestLoadFromReader_env_vars_override(t *testing.T) {
 	})
 
 	// Act
-	got, err := loadFromReader(reader)
+	got, err := loadFromReader(reader, "ini")
 	if err != nil {
 		t.Fatalf("Failed to load config with: %v", err)
 	}
@@ -265,7 +271,8 @@ func TestLoadFromReader_env_vars_override(t *testing.T) {
 			Host: redis_host,
 			Port: redis_port,
 		},
-		QuestionSet: struct{Path string}{
+		QuestionSet: struct{Store string; Path string}{
+			Store: quiz.StoreFile,
 			Path: qs_path,
 		},
 		DynamoDB: struct{Region string; EndpointUrl string; AccessKeyID string; SecretAccessKey string}{
@@ -321,7 +328,7 @@ func TestLoadFromReader_no_env_vars(t *testing.T) {
 	})
 
 	// Act
-	got, err := loadFromReader(reader)
+	got, err := loadFromReader(reader, "ini")
 	if err != nil {
 		t.Fatalf("Failed to load config with: %v", err)
 	}
@@ -339,7 +346,8 @@ func TestLoadFromReader_no_env_vars(t *testing.T) {
 			Host: redis_host,
 			Port: redis_port,
 		},
-		QuestionSet: struct{Path string}{
+		QuestionSet: struct{Store string; Path string}{
+			Store: quiz.StoreFile,
 			Path: qs_path,
 		},
 		DynamoDB: struct{Region string; EndpointUrl string; AccessKeyID string; SecretAccessKey string}{
@@ -397,7 +405,7 @@ func TestLoadFromReader_absent_required_keys(t *testing.T) {
 			reader := buildConfigReader(config)
 			
 			// Act
-			_, got := loadFromReader(reader)
+			_, got := loadFromReader(reader, "ini")
 			
 			// Assert
 			if got == nil {
@@ -411,3 +419,208 @@ func TestLoadFromReader_absent_required_keys(t *testing.T) {
 		})
 	}
 }
+
+// Tests loading with the S3 store where the question set path isn't needed
+func TestLoadFromReader_s3_store(t *testing.T) {
+	reader := strings.NewReader(`
+[rabbit-mq]
+host = localhost
+port = 5672
+username = admin
+password = passwd
+queue_name = quiz-complete
+
+[redis]
+host = localhost
+port = 6379
+
+[question-set]
+store = s3
+
+[dynamodb]
+region = us-east-2
+endpoint_url = http://localhost:8000
+access_key_id = keyid
+secret_access_key = secretkey
+
+[s3]
+endpoint = http://localhost:9000
+region = us-east-2
+bucket = question-sets
+prefix = uploads
+access_key_id = minio
+secret_access_key = minio123
+`)
+
+	got, err := loadFromReader(reader, "ini")
+	if err != nil {
+		t.Fatalf("Failed to load config with: %v", err)
+	}
+
+	if diff := cmp.Diff(quiz.StoreS3, got.QuestionSet.Store); diff != "" {
+		t.Error("Wrong question set store: ", diff)
+	}
+	want := struct{Endpoint string; Region string; Bucket string; Prefix string; AccessKeyID string; SecretAccessKey string}{
+		Endpoint: "http://localhost:9000",
+		Region: "us-east-2",
+		Bucket: "question-sets",
+		Prefix: "uploads",
+		AccessKeyID: "minio",
+		SecretAccessKey: "minio123",
+	}
+	if diff := cmp.Diff(want, got.S3); diff != "" {
+		t.Fatal("Wrong S3 config loaded: ", diff)
+	}
+}
+
+// Tests that every invalid and absent setting is reported at once
+func TestLoadFromReader_invalid_values(t *testing.T) {
+	reader := strings.NewReader(`
+[rabbit-mq]
+host =
+port = amqp
+username = admin
+password = passwd
+queue_name = quiz-complete
+
+[redis]
+host = localhost
+port = 0
+
+[question-set]
+store = ftp
+
+[dynamodb]
+region = us-east-2
+endpoint_url = localhost:8000
+access_key_id = keyid
+secret_access_key = secretkey
+`)
+
+	_, got := loadFromReader(reader, "ini")
+	if got == nil {
+		t.Fatalf("Failed to detect error")
+	}
+
+	want := ValidationErrors{
+		&missingConfigError{"rabbit-mq.host"},
+		&invalidConfigError{"rabbit-mq.port", "must be a whole number, not 'amqp'"},
+		&invalidConfigError{"redis.port", "must be between 1 and 65535, not 0"},
+		&invalidConfigError{"question-set.store", "has unsupported value 'ftp'"},
+		&invalidConfigError{"dynamodb.endpoint_url", "must be an absolute URL such as 'http://localhost:8000', not 'localhost:8000'"},
+	}
+	if diff := cmp.Diff(want.Error(), got.Error()); diff != "" {
+		t.Error("Wrong error received", diff)
+	}
+}
+
+// Tests loading a YAML file with overrides from env vars, and from env vars alone
+func TestLoad_yaml_and_env_vars_only(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(`
+rabbit-mq:
+  host: localhost
+  port: 5672
+  username: admin
+  password: passwd
+  queue_name: quiz-complete
+redis:
+  host: localhost
+  port: 6379
+question-set:
+  path: /tmp/question-sets/
+dynamodb:
+  region: us-east-2
+  endpoint_url: http://localhost:8000
+  access_key_id: keyid
+  secret_access_key: secretkey
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("REDIS_HOST", "redis.envvar.localhost")
+
+	want := Config{}
+	want.RabbitMQ.Host = "localhost"
+	want.RabbitMQ.Port = 5672
+	want.RabbitMQ.Username = "admin"
+	want.RabbitMQ.Password = "passwd"
+	want.RabbitMQ.QueueName = "quiz-complete"
+	want.Redis.Host = "redis.envvar.localhost"
+	want.Redis.Port = 6379
+	want.QuestionSet.Store = quiz.StoreFile
+	want.QuestionSet.Path = "/tmp/question-sets/"
+	want.DynamoDB.Region = "us-east-2"
+	want.DynamoDB.EndpointUrl = "http://localhost:8000"
+	want.DynamoDB.AccessKeyID = "keyid"
+	want.DynamoDB.SecretAccessKey = "secretkey"
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config with: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal("Wrong config loaded from YAML: ", diff)
+	}
+
+	cleanupEnvVars := setEnvVarsFromConfig(testConfig{
+		rabbitMq: &testConfigRabbitMq{&want.RabbitMQ.Host, &want.RabbitMQ.Port, &want.RabbitMQ.Username, &want.RabbitMQ.Password, &want.RabbitMQ.QueueName},
+		redis: &testConfigRedis{&want.Redis.Host, &want.Redis.Port},
+		questionSet: &testConfigQuestionSet{&want.QuestionSet.Path},
+		dynamodb: &testConfigDynamodb{&want.DynamoDB.Region, &want.DynamoDB.EndpointUrl, &want.DynamoDB.AccessKeyID, &want.DynamoDB.SecretAccessKey},
+	})
+	defer cleanupEnvVars()
+
+	got, err = Load("")
+	if err != nil {
+		t.Fatalf("Failed to load config with: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal("Wrong config loaded from env vars: ", diff)
+	}
+}
+
+func TestString_redacts_secrets(t *testing.T) {
+	config := Config{}
+	config.RabbitMQ.Username = "admin"
+	config.RabbitMQ.Password = "hunter2"
+	config.DynamoDB.SecretAccessKey = "file:///run/secrets/dynamodb_secret_access_key"
+
+	got := config.String()
+	for _, want := range []string{"Username:admin", "Password:REDACTED", "SecretAccessKey:file:///run/secrets/dynamodb_secret_access_key"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected '%s' in %s", want, got)
+		}
+	}
+	if strings.Contains(got, "hunter2") {
+		t.Errorf("Secret was not redacted in %s", got)
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	arn := "arn:aws:secretsmanager:us-east-2:123456789012:secret:rabbitmq-AbCdEf"
+	passwordFile := filepath.Join(t.TempDir(), "dynamodb_secret_access_key")
+	if err := os.WriteFile(passwordFile, []byte("access-key\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	resolver := secret.NewResolver(secret.MapSource{arn: `{"username": "admin", "password": "passwd"}`})
+
+	config := Config{}
+	config.RabbitMQ.Host = "localhost"
+	config.RabbitMQ.Username = arn + "#username"
+	config.RabbitMQ.Password = arn + "#password"
+	config.DynamoDB.AccessKeyID = "key-id"
+	config.DynamoDB.SecretAccessKey = "file://" + passwordFile
+	if err := config.ResolveSecrets(context.Background(), resolver); err != nil {
+		t.Fatalf("Failed to resolve secrets: %v", err)
+	}
+
+	want := Config{}
+	want.RabbitMQ.Host = "localhost"
+	want.RabbitMQ.Username = "admin"
+	want.RabbitMQ.Password = "passwd"
+	want.DynamoDB.AccessKeyID = "key-id"
+	want.DynamoDB.SecretAccessKey = "access-key"
+	if diff := cmp.Diff(want, config); diff != "" {
+		t.Fatal("Wrong secrets resolved: ", diff)
+	}
+}
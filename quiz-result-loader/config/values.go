@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/secret"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+type missing string
+
+type missingConfigError struct {
+	key string
+}
+
+func (e *missingConfigError) Error() string {
+	return fmt.Sprintf("config item '%s' was not set", e.key)
+}
+
+type invalidConfigError struct {
+	key		string
+	reason	string
+}
+
+func (e *invalidConfigError) Error() string {
+	return fmt.Sprintf("config item '%s' %s", e.key, e.reason)
+}
+
+// Every problem found with the config so that they can all be fixed at once
+type ValidationErrors []error
+
+func (e ValidationErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	problems := make([]string, len(e))
+	for i, err := range e {
+		problems[i] = err.Error()
+	}
+	return fmt.Sprintf("%d problems with the config: %s", len(e), strings.Join(problems, "; "))
+}
+
+// Reads typed values out of the config, recording a problem for each value that's
+// missing or invalid rather than stopping at the first
+type valueReader struct {
+	v		*viper.Viper
+	errs	ValidationErrors
+}
+
+func (r *valueReader) isMissing(key string) bool {
+	if _, ok := r.v.Get(key).(missing); ok {
+		r.errs = append(r.errs, &missingConfigError{key})
+		return true
+	}
+	return false
+}
+
+func (r *valueReader) invalid(key string, reason string, args ...interface{}) {
+	r.errs = append(r.errs, &invalidConfigError{key, fmt.Sprintf(reason, args...)})
+}
+
+func (r *valueReader) string(key string) string {
+	if r.isMissing(key) {
+		return ""
+	}
+	return r.v.GetString(key)
+}
+
+// Strings that are required can't be empty either
+func (r *valueReader) nonEmptyString(key string) string {
+	if r.isMissing(key) {
+		return ""
+	}
+	value := r.v.GetString(key)
+	if value == "" {
+		r.errs = append(r.errs, &missingConfigError{key})
+	}
+	return value
+}
+
+// Returns the value when it's one of allowed
+func (r *valueReader) oneOf(key string, allowed ...string) string {
+	value := r.string(key)
+	for _, a := range allowed {
+		if value == a {
+			return value
+		}
+	}
+	r.invalid(key, "has unsupported value '%s'", value)
+	return value
+}
+
+// URLs must be absolute e.g. "http://localhost:8000"
+func (r *valueReader) url(key string) string {
+	value := r.nonEmptyString(key)
+	if value == "" {
+		return value
+	}
+	if parsed, err := url.Parse(value); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		r.invalid(key, "must be an absolute URL such as 'http://localhost:8000', not '%s'", value)
+	}
+	return value
+}
+
+func (r *valueReader) int(key string, min int, max int) int {
+	if r.isMissing(key) {
+		return 0
+	}
+	value, err := cast.ToIntE(r.v.Get(key))
+	if err != nil {
+		r.invalid(key, "must be a whole number, not '%v'", r.v.Get(key))
+		return 0
+	}
+	if value < min || value > max {
+		r.invalid(key, "must be between %d and %d, not %d", min, max, value)
+	}
+	return value
+}
+
+func (r *valueReader) port(key string) int {
+	return r.int(key, 1, 65535)
+}
+
+const redacted = "REDACTED"
+
+// Hides a secret unless it's empty or a reference to where the secret is kept
+func Redact(value string) string {
+	if value == "" || secret.IsReference(value) {
+		return value
+	}
+	return redacted
+}
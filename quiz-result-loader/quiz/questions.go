@@ -1,7 +1,6 @@
 package quiz
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -9,13 +8,51 @@ import (
 
 // TODO: Investigate consolidating these structs with those used in the question-set-loader
 
-type QuestionAndAnswers []struct {
-	Question	string		`json:"question"`
-	Category	string		`json:"category"`
-	Options		[]string	`json:"options"`
-	Answers		[]int		`json:"answers"`
+// Kinds of question. A question without a type is single-select when it has one
+// answer and multi-select otherwise
+const (
+	TypeSingleSelect	= "single-select"
+	TypeMultiSelect		= "multi-select"
+	TypeTrueFalse		= "true-false"
+)
+
+// Points scored for a correct answer to a question that doesn't set them
+const DefaultPoints = 1
+
+type QuestionAndAnswer struct {
+	Question			string		`json:"question"`
+	Category			string		`json:"category"`
+	Options				[]string	`json:"options"`
+	Answers				[]int		`json:"answers"`
+	// Optional so that question sets written before these were added still load
+	Type				string		`json:"type,omitempty"`
+	Points				int			`json:"points,omitempty"`
+	TimeLimitSeconds	int			`json:"timeLimitSeconds,omitempty"`
+	Explanation			string		`json:"explanation,omitempty"`
+}
+
+// Returns the type of the question, working it out from the answers when it isn't set
+func (q QuestionAndAnswer) QuestionType() string {
+	switch {
+	case q.Type != "":
+		return q.Type
+	case len(q.Answers) > 1:
+		return TypeMultiSelect
+	default:
+		return TypeSingleSelect
+	}
 }
 
+// Returns the points scored for a correct answer
+func (q QuestionAndAnswer) QuestionPoints() int {
+	if q.Points == 0 {
+		return DefaultPoints
+	}
+	return q.Points
+}
+
+type QuestionAndAnswers []QuestionAndAnswer
+
 type IQuiz interface {
 	QuizFileFromBytes(fileBytes *[]byte) (QuestionAndAnswers, error)
 	LoadQuestionsFromFile(path string) (QuestionAndAnswers, error)
@@ -24,10 +61,11 @@ type IQuiz interface {
 
 type QuizUtil struct {}
 
-// Extracts a QuestionAndAnswers object from the fileBytes, returns non-nil error on failure
+// Extracts a QuestionAndAnswers object from the fileBytes, upgrading it from older schema
+// versions, returns non-nil error on failure
 func (q *QuizUtil) QuizFileFromBytes(fileBytes *[]byte) (qAndA QuestionAndAnswers, err error) {
 
-	if err := json.Unmarshal(*fileBytes, &qAndA); err != nil {
+	if qAndA, err = decodeQuizFile(*fileBytes); err != nil {
 		return nil, fmt.Errorf("failed to deserialize quiz file: Error: %s", err.Error())
 	}
 
@@ -13,6 +13,10 @@ type QuestionSummary struct {
 	Options[]				string
 	CorrectOptions			[]int
 	ParticipantAnswers		[]Answerer
+	Type					string
+	Points					int
+	TimeLimit				time.Duration	// The quiz's question duration unless the question set its own
+	Explanation				string
 }
 
 type Participant struct {
@@ -0,0 +1,113 @@
+package quiz
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// The backends that question sets can be loaded from
+const (
+	StoreFile = "file"
+	StoreS3   = "s3"
+)
+
+// Interface over the S3 client for injecting mocks
+type s3ObjectApi interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+type S3Options struct {
+	Endpoint        string // Empty for AWS, else the URL of an S3 compatible store e.g. MinIO
+	Region          string
+	Bucket          string
+	AccessKeyID     string // Empty to use the default credential chain e.g. the lambda execution role
+	SecretAccessKey string
+}
+
+// Loads question sets written by the question-set-loader from an S3 compatible
+// object store. The paths passed to LoadQuestionsFromFile and DeleteQuestionsFile
+// are the object keys
+type S3QuizUtil struct {
+	QuizUtil
+	client s3ObjectApi
+	bucket string
+}
+
+// Builds an S3 client from the options. When an endpoint is provided path style
+// addressing is used since that's what most S3 compatible stores expect
+func NewS3Client(ctx context.Context, o S3Options) (*s3.Client, error) {
+	loadOptions := []func(*awsConfig.LoadOptions) error{}
+	if o.Region != "" {
+		loadOptions = append(loadOptions, awsConfig.WithRegion(o.Region))
+	}
+	if o.AccessKeyID != "" {
+		loadOptions = append(loadOptions, awsConfig.WithCredentialsProvider(credentials.StaticCredentialsProvider{
+			Value: aws.Credentials{
+				AccessKeyID:     o.AccessKeyID,
+				SecretAccessKey: o.SecretAccessKey,
+			},
+		}))
+	}
+	if o.Endpoint != "" {
+		loadOptions = append(loadOptions, awsConfig.WithEndpointResolverWithOptions(aws.EndpointResolverWithOptionsFunc(
+			func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+				return aws.Endpoint{URL: o.Endpoint, HostnameImmutable: true}, nil
+			},
+		)))
+	}
+
+	cfg, err := awsConfig.LoadDefaultConfig(ctx, loadOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config. Error: %w", err)
+	}
+
+	return s3.NewFromConfig(cfg, func(options *s3.Options) {
+		options.UsePathStyle = o.Endpoint != ""
+	}), nil
+}
+
+func NewS3QuizUtil(ctx context.Context, o S3Options) (*S3QuizUtil, error) {
+	if o.Bucket == "" {
+		return nil, fmt.Errorf("bucket must be non-empty")
+	}
+	client, err := NewS3Client(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+	return &S3QuizUtil{client: client, bucket: o.Bucket}, nil
+}
+
+// Extracts a QuestionAndAnswers object from the object with key path, returns non-nil error on failure
+func (q *S3QuizUtil) LoadQuestionsFromFile(path string) (QuestionAndAnswers, error) {
+	output, err := q.client.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(q.bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object '%s' from bucket '%s'. Error: %s", path, q.bucket, err.Error())
+	}
+	defer output.Body.Close()
+
+	bytes, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object '%s'. Error: %s", path, err.Error())
+	}
+
+	return q.QuizFileFromBytes(&bytes)
+}
+
+// Deletes the object with key path
+func (q *S3QuizUtil) DeleteQuestionsFile(path string) error {
+	_, err := q.client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+		Bucket: aws.String(q.bucket),
+		Key:    aws.String(path),
+	})
+	return err
+}
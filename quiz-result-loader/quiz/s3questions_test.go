@@ -0,0 +1,59 @@
+package quiz
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/go-cmp/cmp"
+)
+
+type mockS3Client struct {
+	objects map[string][]byte
+}
+
+func (m *mockS3Client) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	object, ok := m.objects[*params.Bucket+"/"+*params.Key]
+	if !ok {
+		return nil, fmt.Errorf("NoSuchKey")
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(object))}, nil
+}
+
+func (m *mockS3Client) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	delete(m.objects, *params.Bucket+"/"+*params.Key)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func TestS3QuizUtil(t *testing.T) {
+	client := &mockS3Client{objects: map[string][]byte{
+		"question-sets/uploads/quizid1.json": []byte(`[{"question": "question 1", "category": "food", "options": ["a", "b"], "answers": [1]}]`),
+	}}
+	quizUtil := S3QuizUtil{client: client, bucket: "question-sets"}
+
+	got, err := quizUtil.LoadQuestionsFromFile("uploads/quizid1.json")
+	if err != nil {
+		t.Fatalf("Failed to load questions: %v", err)
+	}
+	want := QuestionAndAnswers{
+		{
+			Question: "question 1",
+			Category: "food",
+			Options:  []string{"a", "b"},
+			Answers:  []int{1},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Loaded questions don't match: %s", diff)
+	}
+
+	if err := quizUtil.DeleteQuestionsFile("uploads/quizid1.json"); err != nil {
+		t.Fatalf("Failed to delete questions: %v", err)
+	}
+	if _, err := quizUtil.LoadQuestionsFromFile("uploads/quizid1.json"); err == nil {
+		t.Fatalf("Questions weren't deleted")
+	}
+}
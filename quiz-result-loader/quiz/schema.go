@@ -0,0 +1,81 @@
+package quiz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Newest version of the quiz file schema that can be read. Files written before the
+// schema was versioned are a bare list of questions and are read as version 1
+const SchemaVersion = 1
+
+// Top level of a versioned quiz file
+type QuizFile struct {
+	SchemaVersion	int					`json:"schemaVersion"`
+	Questions		[]json.RawMessage	`json:"questions"`
+}
+
+// Upgrades a question, in place, from the schema version it's keyed by to the next one.
+// Every version before SchemaVersion must have one. There are none yet as optional
+// fields can be added without a new version
+var migrations = map[int]func(question map[string]interface{}) error{}
+
+// Deserializes a quiz file of any schema version, upgrading its questions to SchemaVersion
+func decodeQuizFile(fileBytes []byte) (QuestionAndAnswers, error) {
+	var file QuizFile
+	if trimmed := bytes.TrimSpace(fileBytes); len(trimmed) > 0 && trimmed[0] == '[' {
+		file.SchemaVersion = 1
+		if err := json.Unmarshal(fileBytes, &file.Questions); err != nil {
+			return nil, err
+		}
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(fileBytes))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&file); err != nil {
+			return nil, err
+		}
+	}
+	if file.SchemaVersion < 1 || file.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("unsupported schemaVersion %d, must be between 1 and %d", file.SchemaVersion, SchemaVersion)
+	}
+
+	qAndA := QuestionAndAnswers{}
+	for i, raw := range file.Questions {
+		question, err := decodeQuestion(raw, file.SchemaVersion)
+		if err != nil {
+			return nil, fmt.Errorf("question %d: %w", i, err)
+		}
+		qAndA = append(qAndA, question)
+	}
+	return qAndA, nil
+}
+
+// Deserializes a question written with the schema version, upgrading it to SchemaVersion
+func decodeQuestion(raw json.RawMessage, version int) (question QuestionAndAnswer, err error) {
+	return upgradeQuestion(raw, version, SchemaVersion)
+}
+
+// Deserializes a question written with the schema version, upgrading it to target
+func upgradeQuestion(raw json.RawMessage, version int, target int) (question QuestionAndAnswer, err error) {
+	if version < target {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return question, err
+		}
+		for v := version; v < target; v++ {
+			migrate, ok := migrations[v]
+			if !ok {
+				return question, fmt.Errorf("no migration from schemaVersion %d", v)
+			}
+			if err := migrate(fields); err != nil {
+				return question, fmt.Errorf("failed to migrate from schemaVersion %d: %w", v, err)
+			}
+		}
+		if raw, err = json.Marshal(fields); err != nil {
+			return question, err
+		}
+	}
+	err = json.Unmarshal(raw, &question)
+	return question, err
+}
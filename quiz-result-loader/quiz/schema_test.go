@@ -0,0 +1,124 @@
+package quiz
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestQuizFileFromBytes_schema_versions(t *testing.T) {
+	want := QuestionAndAnswers{
+		{Question: "q1", Category: "c", Options: []string{"a", "b"}, Answers: []int{0}},
+		{Question: "q2", Category: "c", Options: []string{"a", "b"}, Answers: []int{0, 1}, Type: TypeMultiSelect, Points: 5},
+	}
+	questions := `[
+		{"question": "q1", "category": "c", "options": ["a", "b"], "answers": [0]},
+		{"question": "q2", "category": "c", "options": ["a", "b"], "answers": [0, 1], "type": "multi-select", "points": 5}
+	]`
+
+	testCases := map[string]string{
+		"unversioned":              questions,
+		"v1":                       `{"schemaVersion": 1, "questions": ` + questions + `}`,
+		"questions before version": `{"questions": ` + questions + `, "schemaVersion": 1}`,
+	}
+	for name, file := range testCases {
+		t.Run(name, func(t *testing.T) {
+			fileBytes := []byte(file)
+			got, err := (&QuizUtil{}).QuizFileFromBytes(&fileBytes)
+			if err != nil {
+				t.Fatalf("Failed to deserialize quiz file: %v", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Fatalf("Deserialized quiz file doesn't match: %s", diff)
+			}
+		})
+	}
+}
+
+func TestQuizFileFromBytes_schema_errors(t *testing.T) {
+	testCases := map[string]string{
+		"missing version": `{"questions": []}`,
+		"newer version":   `{"schemaVersion": 2, "questions": []}`,
+		"unexpected key":  `{"schemaVersion": 1, "questions": [], "title": "t"}`,
+		"not a question":  `[1]`,
+	}
+	for name, file := range testCases {
+		t.Run(name, func(t *testing.T) {
+			fileBytes := []byte(file)
+			if _, err := (&QuizUtil{}).QuizFileFromBytes(&fileBytes); err == nil {
+				t.Fatalf("Failed to detect error")
+			}
+		})
+	}
+}
+
+func TestLoadQuestionsFromFile_versioned(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quiz.json")
+	file := `{"schemaVersion": 1, "questions": [{"question": "q1", "category": "c", "options": ["a", "b"], "answers": [1]}]}`
+	if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+		t.Fatalf("Failed to write quiz file: %v", err)
+	}
+
+	got, err := (&QuizUtil{}).LoadQuestionsFromFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load quiz file: %v", err)
+	}
+	want := QuestionAndAnswers{{Question: "q1", Category: "c", Options: []string{"a", "b"}, Answers: []int{1}}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Loaded quiz file doesn't match: %s", diff)
+	}
+}
+
+// Every version before the current one can be upgraded
+func TestMigrations(t *testing.T) {
+	for version := 1; version < SchemaVersion; version++ {
+		if _, ok := migrations[version]; !ok {
+			t.Errorf("Missing migration from schemaVersion %d", version)
+		}
+	}
+}
+
+// Questions older than the target version pass through every migration after theirs
+func TestUpgradeQuestion_migration_chain(t *testing.T) {
+	defer func() { migrations = map[int]func(question map[string]interface{}) error{} }()
+	for _, version := range []int{1, 2} {
+		version := version
+		migrations[version] = func(question map[string]interface{}) error {
+			question["question"] = fmt.Sprintf("%s (v%d)", question["question"], version + 1)
+			return nil
+		}
+	}
+	raw := []byte(`{"question": "q1", "category": "c", "options": ["a", "b"], "answers": [0]}`)
+
+	got, err := upgradeQuestion(raw, 1, 3)
+	if err != nil {
+		t.Fatalf("Failed to decode question: %v", err)
+	}
+	if got.Question != "q1 (v2) (v3)" {
+		t.Errorf("Expected the question to be migrated twice but got '%s'", got.Question)
+	}
+
+	got, err = upgradeQuestion(raw, 3, 3)
+	if err != nil {
+		t.Fatalf("Failed to decode question: %v", err)
+	}
+	if got.Question != "q1" {
+		t.Errorf("Expected a current question not to be migrated but got '%s'", got.Question)
+	}
+
+	migrationErr := errors.New("can't migrate")
+	migrations[2] = func(question map[string]interface{}) error { return migrationErr }
+	if _, err := upgradeQuestion([]byte(`{}`), 1, 3); !errors.Is(err, migrationErr) {
+		t.Errorf("Expected the migration error but got: %v", err)
+	}
+
+	delete(migrations, 2)
+	_, err = upgradeQuestion(raw, 1, 3)
+	if err == nil || err.Error() != "no migration from schemaVersion 2" {
+		t.Errorf("Expected the missing migration to be reported but got: %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Prefixes of config values that reference a secret. Values without one of these
+// are used as they are
+const (
+	FilePrefix				= "file://"					// e.g. file:///run/secrets/jwt_secret for Docker secrets
+	EnvPrefix				= "env://"					// e.g. env://JWT_SECRET
+	SecretsManagerPrefix	= "arn:aws:secretsmanager:"	// A secret's ARN, optionally followed by #<key> to pick a key from a JSON secret
+)
+
+// Looks up the value of a secret by its reference
+type Source interface {
+	Lookup(ctx context.Context, ref string) (string, error)
+}
+
+// Resolves config values that reference secrets. Each secret is looked up once and
+// cached for the life of the resolver
+type Resolver struct {
+	sources	map[string]Source // By reference prefix
+	mu		sync.Mutex
+	cache	map[string]string
+}
+
+// Builds a resolver for file:// and env:// references, and for secrets manager ARNs
+// looked up with secretsManager. ARNs are rejected when secretsManager is nil
+func NewResolver(secretsManager Source) *Resolver {
+	sources := map[string]Source{
+		FilePrefix: fileSource{},
+		EnvPrefix: envSource{},
+	}
+	if secretsManager != nil {
+		sources[SecretsManagerPrefix] = secretsManager
+	}
+	return &Resolver{sources: sources, cache: make(map[string]string)}
+}
+
+// Returns the secret referenced by value, or value itself if it isn't a reference
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	if strings.HasPrefix(value, SecretsManagerPrefix) {
+		if r.sources[SecretsManagerPrefix] == nil {
+			return "", fmt.Errorf("secret '%s' can't be resolved as there's no secrets manager", value)
+		}
+		// ARNs can't contain '#' so it always separates the key
+		arn, key, hasKey := cut(value, "#")
+		secret, err := r.lookup(ctx, SecretsManagerPrefix, arn)
+		if err != nil || !hasKey {
+			return secret, err
+		}
+		return jsonKey(arn, secret, key)
+	}
+	for _, prefix := range []string{FilePrefix, EnvPrefix} {
+		if strings.HasPrefix(value, prefix) {
+			return r.lookup(ctx, prefix, strings.TrimPrefix(value, prefix))
+		}
+	}
+	return value, nil
+}
+
+// Whether value references a secret rather than being one
+func IsReference(value string) bool {
+	for _, prefix := range []string{FilePrefix, EnvPrefix, SecretsManagerPrefix} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolves each of the values in place. Stops at the first that can't be resolved
+func (r *Resolver) ResolveAll(ctx context.Context, values ...*string) error {
+	for _, value := range values {
+		resolved, err := r.Resolve(ctx, *value)
+		if err != nil {
+			return err
+		}
+		*value = resolved
+	}
+	return nil
+}
+
+func (r *Resolver) lookup(ctx context.Context, prefix string, ref string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if secret, ok := r.cache[prefix + ref]; ok {
+		return secret, nil
+	}
+	secret, err := r.sources[prefix].Lookup(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve secret '%s%s'. Error: %w", prefix, ref, err)
+	}
+	r.cache[prefix + ref] = secret
+	return secret, nil
+}
+
+// Returns the string value of key in the JSON object secret
+func jsonKey(arn string, secret string, key string) (string, error) {
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &values); err != nil {
+		return "", fmt.Errorf("secret '%s' must be a JSON object to pick key '%s'", arn, key)
+	}
+	value, ok := values[key].(string)
+	if !ok {
+		return "", fmt.Errorf("secret '%s' has no string key '%s'", arn, key)
+	}
+	return value, nil
+}
+
+// Same as strings.Cut which isn't available in go 1.17
+func cut(s string, sep string) (string, string, bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// Reads secrets from files e.g. Docker secrets mounted under /run/secrets
+type fileSource struct{}
+
+func (fileSource) Lookup(ctx context.Context, path string) (string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	// Files written by editors and echo usually end in a newline that isn't part of the secret
+	return strings.TrimRight(string(contents), "\r\n"), nil
+}
+
+// Reads secrets from other environment variables
+type envSource struct{}
+
+func (envSource) Lookup(ctx context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("env var '%s' is not set", name)
+	}
+	return value, nil
+}
+
+// In memory secrets keyed by reference. Stands in for a secrets manager in tests and
+// local development
+type MapSource map[string]string
+
+func (m MapSource) Lookup(ctx context.Context, ref string) (string, error) {
+	secret, ok := m[ref]
+	if !ok {
+		return "", fmt.Errorf("no such secret")
+	}
+	return secret, nil
+}
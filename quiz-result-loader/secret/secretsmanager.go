@@ -0,0 +1,63 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// Interface over the secrets manager client for injecting mocks
+type secretsManagerApi interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// Looks up secrets in AWS Secrets Manager by their ARN. The client is built from the
+// default credential chain e.g. the lambda execution role, on the first lookup so
+// that nothing is needed from AWS unless an ARN is configured
+type SecretsManagerSource struct {
+	once	sync.Once
+	client	secretsManagerApi
+	err		error
+}
+
+func NewSecretsManagerSource() *SecretsManagerSource {
+	return &SecretsManagerSource{}
+}
+
+// Requires secretsmanager:GetSecretValue on the secret
+func (s *SecretsManagerSource) Lookup(ctx context.Context, arn string) (string, error) {
+	s.once.Do(func() {
+		if s.client != nil {
+			return
+		}
+		cfg, err := awsConfig.LoadDefaultConfig(ctx)
+		if err != nil {
+			s.err = fmt.Errorf("failed to load AWS config. Error: %w", err)
+			return
+		}
+		s.client = secretsmanager.NewFromConfig(cfg)
+	})
+	if s.err != nil {
+		return "", s.err
+	}
+
+	output, err := s.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(arn)},
+		func(o *secretsmanager.Options) {
+			// The secret is in the region named by its ARN e.g. arn:aws:secretsmanager:<region>:...
+			if parts := strings.Split(arn, ":"); len(parts) > 3 && parts[3] != "" {
+				o.Region = parts[3]
+			}
+		})
+	if err != nil {
+		return "", err
+	}
+	if output.SecretString == nil {
+		return "", fmt.Errorf("secret is binary but only string secrets are supported")
+	}
+	return *output.SecretString, nil
+}
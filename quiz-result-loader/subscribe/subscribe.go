@@ -13,13 +13,13 @@ type Subscriber interface {
 type QuizCh chan<- string
 
 type QuizCompleteEvent struct {
-	Type		string						`json:type`
-	Source		string						`json:source`
-	Id			string						`json:id`
-	Time		time.Time					`json:time`
-	Data		QuizCompleteEventDataV1		`json:data`
+	Type		string						`json:"type"`
+	Source		string						`json:"source"`
+	Id			string						`json:"id"`
+	Time		time.Time					`json:"time"`
+	Data		QuizCompleteEventDataV1		`json:"data"`
 }
 
 type QuizCompleteEventDataV1 struct {
-	QuizId		string			`json:quizId`
+	QuizId		string			`json:"quizId"`
 }
\ No newline at end of file
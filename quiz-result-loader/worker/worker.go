@@ -21,12 +21,21 @@ func combineExtractedQuizAndQuestions(extractedQuiz quiz.Quiz, questions quiz.Qu
 		if err != nil {
 			return extractedQuiz, errors.New("expected question from extracted quiz to be an index")
 		}
-		// TODO: Check for qIndex out-of-bounds error
+		if qIndex < 0 || qIndex >= len(questions) {
+			return extractedQuiz, fmt.Errorf("question index %d is out of range for %d questions", qIndex, len(questions))
+		}
 		// Copy the question fields into the quiz
 		qAndA := questions[qIndex]
 		extractedQuiz.Questions[i].Question = qAndA.Question
 		extractedQuiz.Questions[i].Options = qAndA.Options
 		extractedQuiz.Questions[i].CorrectOptions = qAndA.Answers
+		extractedQuiz.Questions[i].Type = qAndA.QuestionType()
+		extractedQuiz.Questions[i].Points = qAndA.QuestionPoints()
+		extractedQuiz.Questions[i].TimeLimit = extractedQuiz.QuestionDuration
+		if qAndA.TimeLimitSeconds > 0 {
+			extractedQuiz.Questions[i].TimeLimit = time.Duration(qAndA.TimeLimitSeconds) * time.Second
+		}
+		extractedQuiz.Questions[i].Explanation = qAndA.Explanation
 	}
 	return extractedQuiz, nil
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/internal/testutils"
 	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/load"
 	"github.com/Ryangwaite/mc-speedrun/quiz-result-loader/quiz"
+	"github.com/google/go-cmp/cmp"
 )
 
 /////// GLOBAL VARIABLES ///////
@@ -516,3 +517,41 @@ func TestWorker_deleteQuestionsFile_error(t *testing.T) {
 		}
 	}
 }
+
+// Carries the question fields into the summaries, defaulting those the question doesn't set
+func TestCombineExtractedQuizAndQuestions(t *testing.T) {
+	questions := quiz.QuestionAndAnswers{
+		{Question: q1_text, Category: q1_category, Options: q1_options, Answers: q1_answers},
+		{Question: q2_text, Category: q2_category, Options: []string{"True", "False"}, Answers: []int{1},
+			Type: quiz.TypeTrueFalse, Points: 5, TimeLimitSeconds: 30, Explanation: "It's false"},
+	}
+	extractedQuiz := quiz.Quiz{
+		QuestionDuration: questionDuration,
+		Questions: []quiz.QuestionSummary{{Question: "0"}, {Question: "1"}},
+	}
+
+	got, err := combineExtractedQuizAndQuestions(extractedQuiz, questions)
+	if err != nil {
+		t.Fatalf("Failed to combine: %v", err)
+	}
+
+	want := []quiz.QuestionSummary{
+		{Question: q1_text, Options: q1_options, CorrectOptions: q1_answers, Type: quiz.TypeMultiSelect,
+			Points: quiz.DefaultPoints, TimeLimit: questionDuration},
+		{Question: q2_text, Options: []string{"True", "False"}, CorrectOptions: []int{1}, Type: quiz.TypeTrueFalse,
+			Points: 5, TimeLimit: 30 * time.Second, Explanation: "It's false"},
+	}
+	if diff := cmp.Diff(want, got.Questions); diff != "" {
+		t.Fatalf("Combined questions don't match: %s", diff)
+	}
+}
+
+// Fails rather than panicking when the extracted quiz has more questions than the question set
+func TestCombineExtractedQuizAndQuestions_index_out_of_range(t *testing.T) {
+	questions := quiz.QuestionAndAnswers{{Question: q1_text, Category: q1_category, Options: q1_options, Answers: q1_answers}}
+	extractedQuiz := quiz.Quiz{Questions: []quiz.QuestionSummary{{Question: "1"}}}
+
+	if _, err := combineExtractedQuizAndQuestions(extractedQuiz, questions); err == nil {
+		t.Fatalf("Failed to detect error")
+	}
+}
@@ -6,17 +6,22 @@ import kotlinx.coroutines.Job
 import kotlinx.coroutines.delay
 import kotlinx.coroutines.launch
 import kotlinx.serialization.Serializable
-import kotlinx.serialization.decodeFromString
 import kotlinx.serialization.json.Json
+import kotlinx.serialization.json.JsonArray
+import kotlinx.serialization.json.decodeFromJsonElement
 import java.io.File
 import java.io.FileNotFoundException
 import java.util.Date
 import java.util.concurrent.ConcurrentHashMap
 
-//@Serializable
-//data class QuizFileFormat(
-//    val questionsAndAnswers: List<QuestionAndAnswers>,
-//)
+/**
+ * Versioned quiz file written by the question-set-loader service
+ */
+@Serializable
+data class QuizFileFormat(
+    val schemaVersion: Int,
+    val questions: List<QuestionAndAnswers>,
+)
 
 data class CacheEntry(
     val questionsAndAnswers: List<QuestionAndAnswers>,
@@ -85,7 +90,12 @@ object QuizLoader : IQuizLoader {
             throw FileNotFoundException("File not found for quiz '$quizId' at path '${quizFile.path}'")
         }
         val contents = quizFile.readText()
-        return Json.decodeFromString(contents)
+        val quizFileJson = Json.parseToJsonElement(contents)
+        if (quizFileJson is JsonArray) {
+            // Written before the schema was versioned
+            return Json.decodeFromJsonElement(quizFileJson)
+        }
+        return Json.decodeFromJsonElement<QuizFileFormat>(quizFileJson).questions
     }
 
     /**
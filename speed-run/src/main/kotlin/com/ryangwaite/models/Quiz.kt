@@ -8,4 +8,9 @@ data class QuestionAndAnswers(
     val category: String,
     val options: List<String>,
     val answers: List<Int>,
+    // Optional so that question sets without them still load
+    val type: String? = null,
+    val points: Int? = null,
+    val timeLimitSeconds: Int? = null,
+    val explanation: String? = null,
 )
\ No newline at end of file
@@ -23,6 +23,27 @@ export async function uploadQuiz(quizFile: File, token: string) {
     })
 
     if (!response.ok) {
-        throw new Error(`Upload failed: ${await response.text()}`)
+        throw new Error(`Upload failed: ${await readProblem(response)}`)
+    }
+}
+
+/**
+ * Reads the message from a failed response. The upload API describes failures with
+ * application/problem+json documents, see https://datatracker.ietf.org/doc/html/rfc7807
+ * @returns the problem's detail, including every validation error, else the raw body
+ */
+async function readProblem(response: Response): Promise<string> {
+    const body = await response.text()
+    if (!response.headers.get("Content-Type")?.startsWith("application/problem+json")) {
+        return body
+    }
+    try {
+        const problem = JSON.parse(body)
+        const errors: string[] = (problem.errors ?? []).map((e: {index: number, field: string, reason: string, line?: number}) =>
+            `${e.line ? `line ${e.line}: ` : ""}${e.index >= 0 ? `question ${e.index + 1}` : "question set"}${e.field ? ` ${e.field}` : ""} ${e.reason}`
+        )
+        return [problem.detail ?? problem.title, ...errors].join("\n")
+    } catch {
+        return body
     }
 }
\ No newline at end of file